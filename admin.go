@@ -0,0 +1,76 @@
+package padding
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// adminStatus 是 AdminHandler 返回的 JSON 结构
+type adminStatus struct {
+	Enabled bool           `json:"enabled"`
+	Options PaddingOptions `json:"options"`
+	Stats   Stats          `json:"stats"`
+	Budget  *BudgetStats   `json:"budget,omitempty"`
+	Audit   *AuditReport   `json:"audit,omitempty"`
+	// ValueCache 是 padding 值缓存的计数器
+	ValueCache *ValueCacheStats `json:"value_cache,omitempty"`
+	// Recommendation 是观察模式结束后给出的推荐结果
+	Recommendation *Recommendation `json:"recommendation,omitempty"`
+}
+
+// AdminHandler 返回一个用于运行时诊断的 http.Handler
+// GET 以 JSON 返回是否启用、当前生效配置与统计信息 (padding 长度直方图、padding 字节数、预算计数等);
+// POST 携带查询参数 enabled=true|false 时在运行时启用或停用 padding, 并返回更新后的状态
+// 该接口会暴露配置细节并允许关闭 padding, 应只挂载在受信任的内部地址上
+func (p *Padder) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPost:
+			enabled, err := strconv.ParseBool(r.URL.Query().Get("enabled"))
+			if err != nil {
+				http.Error(w, "padding: enabled must be true or false", http.StatusBadRequest)
+				return
+			}
+			if enabled {
+				p.Enable()
+			} else {
+				p.Disable()
+			}
+		default:
+			w.Header().Set("Allow", "GET, HEAD, POST")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		status := adminStatus{
+			Enabled: p.Enabled(),
+			Options: p.Options(),
+			Stats:   p.Stats(),
+		}
+		if status.Options.Budget != nil {
+			bs := status.Options.Budget.Stats()
+			status.Budget = &bs
+		}
+		if status.Options.Audit != nil {
+			ar := status.Options.Audit.Check()
+			status.Audit = &ar
+		}
+		if status.Options.ValueCache != nil {
+			vs := status.Options.ValueCache.Stats()
+			status.ValueCache = &vs
+		}
+		if status.Options.Observe != nil {
+			if rec, ok := status.Options.Observe.Recommendation(); ok {
+				status.Recommendation = &rec
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(status)
+	})
+}
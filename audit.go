@@ -0,0 +1,177 @@
+package padding
+
+import (
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// AuditOptions 配置 Auditor
+type AuditOptions struct {
+	// Window 是参与统计的最近响应数, 小于等于 0 时为 512
+	Window int
+	// MinSamples 是窗口内至少需要的样本数, 样本不足时不做判断; 小于等于 0 时为 64
+	MinSamples int
+	// MinDistinct 是窗口内已填充响应至少应有的不同总大小个数, 低于该值视为分布坍缩; 小于等于 0 时为 8
+	MinDistinct int
+	// MaxUnpadded 是窗口内未填充响应所占比例的上限, 取值 (0, 1]; 小于等于 0 时为 0.5
+	// 使用 Probability 抽样时应相应调高
+	MaxUnpadded float64
+	// Interval 是后台检查的间隔, 小于等于 0 时为 1 分钟
+	Interval time.Duration
+	// OnAlert 在检查发现异常时调用, 为 nil 时记录日志
+	OnAlert func(report AuditReport) `json:"-"`
+}
+
+// AuditReport 是 Auditor 对最近一个窗口的检查结果
+type AuditReport struct {
+	Samples  int     `json:"samples"`  // 窗口内的响应数
+	Unpadded int     `json:"unpadded"` // 其中未添加任何 padding 的响应数
+	Distinct int     `json:"distinct"` // 已填充响应中不同总大小的个数
+	Min      int64   `json:"min"`      // 已填充响应总大小的最小值
+	Max      int64   `json:"max"`      // 已填充响应总大小的最大值
+	StdDev   float64 `json:"std_dev"`  // 已填充响应总大小的标准差
+	// Collapsed 表示已填充响应的总大小分布坍缩 (不同取值过少), 如 Profile 被误配置为常数
+	Collapsed bool `json:"collapsed"`
+	// Skipping 表示未填充响应的比例超过 MaxUnpadded, 如 padding 被静默跳过
+	Skipping bool `json:"skipping"`
+}
+
+// Healthy 报告本次检查是否没有发现异常
+func (r AuditReport) Healthy() bool {
+	return !r.Collapsed && !r.Skipping
+}
+
+// auditSample 是一次响应的记录, size 为 -1 表示大小未知 (跳过 padding 时不包装响应)
+type auditSample struct {
+	size   int64
+	padded bool
+}
+
+// Auditor 跟踪服务端响应总大小 (头部 + 响应体, 含 padding) 的分布, 在分布坍缩或 padding 被大量跳过时告警,
+// 帮助运维人员发现重新暴露流量特征的配置漂移; 通过 PaddingOptions.Audit 安装, 可在多个实例之间共享
+type Auditor struct {
+	opts AuditOptions
+
+	mu      sync.Mutex
+	samples []auditSample // 环形缓冲区
+	next    int
+	full    bool
+}
+
+// NewAuditor 创建一个 Auditor, 需调用 Start 启动后台检查或自行调用 Check
+func NewAuditor(opts AuditOptions) *Auditor {
+	if opts.Window <= 0 {
+		opts.Window = 512
+	}
+	if opts.MinSamples <= 0 {
+		opts.MinSamples = 64
+	}
+	opts.MinSamples = min(opts.MinSamples, opts.Window)
+	if opts.MinDistinct <= 0 {
+		opts.MinDistinct = 8
+	}
+	if opts.MaxUnpadded <= 0 {
+		opts.MaxUnpadded = 0.5
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	return &Auditor{opts: opts, samples: make([]auditSample, opts.Window)}
+}
+
+// record 记录一次响应; a 为 nil 时不做任何事
+func (a *Auditor) record(size int64, padded bool) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	a.samples[a.next] = auditSample{size: size, padded: padded}
+	a.next++
+	if a.next == len(a.samples) {
+		a.next, a.full = 0, true
+	}
+	a.mu.Unlock()
+}
+
+// Check 立即对最近一个窗口做一次检查并返回结果, 不会触发 OnAlert
+func (a *Auditor) Check() AuditReport {
+	a.mu.Lock()
+	n := a.next
+	if a.full {
+		n = len(a.samples)
+	}
+	window := make([]auditSample, n)
+	copy(window, a.samples[:n])
+	a.mu.Unlock()
+
+	r := AuditReport{Samples: n}
+	distinct := make(map[int64]struct{})
+	var count int
+	var sum, sumSq float64
+	for _, s := range window {
+		if !s.padded {
+			r.Unpadded++
+			continue
+		}
+		if s.size < 0 {
+			continue
+		}
+		if count == 0 || s.size < r.Min {
+			r.Min = s.size
+		}
+		r.Max = max(r.Max, s.size)
+		distinct[s.size] = struct{}{}
+		count++
+		sum += float64(s.size)
+		sumSq += float64(s.size) * float64(s.size)
+	}
+	r.Distinct = len(distinct)
+	if count > 0 {
+		mean := sum / float64(count)
+		r.StdDev = math.Sqrt(max(sumSq/float64(count)-mean*mean, 0))
+	}
+	if n < a.opts.MinSamples {
+		return r
+	}
+	r.Skipping = float64(r.Unpadded)/float64(n) > a.opts.MaxUnpadded
+	r.Collapsed = count >= a.opts.MinSamples && r.Distinct < a.opts.MinDistinct
+	return r
+}
+
+// Start 在后台每隔 Interval 检查一次, 发现异常时调用 OnAlert (或记录日志)
+// 调用返回的 stop 函数停止后台检查
+func (a *Auditor) Start() (stop func()) {
+	done := make(chan struct{})
+	var once sync.Once
+	go func() {
+		ticker := time.NewTicker(a.opts.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if r := a.Check(); !r.Healthy() {
+					a.alert(r)
+				}
+			}
+		}
+	}()
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// alert 上报一次异常检查结果
+func (a *Auditor) alert(r AuditReport) {
+	if a.opts.OnAlert != nil {
+		a.opts.OnAlert(r)
+		return
+	}
+	if r.Collapsed {
+		log.Printf("padding.Auditor: padded response sizes collapsed to %d distinct values over %d samples (min %d, max %d)", r.Distinct, r.Samples, r.Min, r.Max)
+	}
+	if r.Skipping {
+		log.Printf("padding.Auditor: %d of %d responses were sent without padding", r.Unpadded, r.Samples)
+	}
+}
@@ -0,0 +1,141 @@
+package padding
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// BackoffOptions 配置 Backoff
+type BackoffOptions struct {
+	// Statuses 是表示请求过大的响应状态码, 为空时为 431 (Request Header Fields Too Large) 与 413 (Content Too Large);
+	// 同时启用了 QueryPadding 时可以加入 414 (URI Too Long)
+	Statuses []int
+	// Factor 是每次回退后长度上限相对于触发回退的 padding 长度的比例, 不在 (0, 1) 内时为 0.5
+	Factor float64
+	// MinLength 是长度上限的下限, 上限低于它时停用发往该主机的请求的头部 padding, 小于等于 0 时为 16
+	MinLength int
+	// OnBackoff 在某个主机的长度上限降低时同步调用, 可以为 nil
+	OnBackoff func(BackoffEvent) `json:"-"`
+}
+
+// BackoffEvent 描述一次回退
+type BackoffEvent struct {
+	Host       string // 出站请求的目标主机名 (小写)
+	StatusCode int    // 触发回退的响应状态码
+	Length     int    // 触发回退的请求携带的 padding 长度
+	Limit      int    // 回退后该主机的 padding 长度上限, 0 表示已停用
+}
+
+// BackoffMetrics 是 Metrics 可选实现的扩展接口, 实现了它的 Metrics 会在每次回退时收到 BackoffEvent
+type BackoffMetrics interface {
+	RecordBackoff(e BackoffEvent)
+}
+
+// Backoff 让客户端 padding 自动适应中间设备的头部大小限制: 携带 padding 的请求收到 431、413 等响应时,
+// 将发往该主机的请求的 padding 长度上限降为本次长度的 Factor 倍; 上限在进程的生命周期内保持,
+// 不随 Reload 重置 (同一个 Backoff 可在多份配置与多个 Padder 之间共享)
+// 通过 PaddingOptions.Backoff 安装; 上限作用于请求选出的 Profile, 设置了 Strategy 时以 RequestInfo.Profile 传入
+type Backoff struct {
+	opts BackoffOptions
+
+	mu     sync.Mutex
+	limits map[string]int
+}
+
+// NewBackoff 创建一个尚未对任何主机回退的 Backoff
+func NewBackoff(opts BackoffOptions) *Backoff {
+	if len(opts.Statuses) == 0 {
+		opts.Statuses = []int{http.StatusRequestHeaderFieldsTooLarge, http.StatusRequestEntityTooLarge}
+	} else {
+		opts.Statuses = slices.Clone(opts.Statuses)
+	}
+	if opts.Factor <= 0 || opts.Factor >= 1 {
+		opts.Factor = 0.5
+	}
+	if opts.MinLength <= 0 {
+		opts.MinLength = 16
+	}
+	return &Backoff{opts: opts, limits: make(map[string]int)}
+}
+
+// Limits 返回所有已回退的主机及其当前的 padding 长度上限 (0 表示已停用)
+func (b *Backoff) Limits() map[string]int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return maps.Clone(b.limits)
+}
+
+// Reset 清除主机 host 的回退记录, host 为空时清除全部; 用于确认中间设备的限制已调整之后
+func (b *Backoff) Reset(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if host == "" {
+		clear(b.limits)
+		return
+	}
+	delete(b.limits, strings.ToLower(host))
+}
+
+// limit 返回发往 host 的请求的 padding 长度上限, 未回退过时 ok 为 false; b 为 nil 时总是返回 false
+func (b *Backoff) limit(host string) (n int, ok bool) {
+	if b == nil {
+		return 0, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n, ok = b.limits[host]
+	return n, ok
+}
+
+// report 报告发往 host 且携带 length 字节 padding 的请求收到了状态码 status 的响应
+// 状态码表示请求过大时降低该主机的上限, 并调用 OnBackoff 与 Metrics
+func (b *Backoff) report(host string, status, length int, opts *PaddingOptions) {
+	if b == nil || length <= 0 || !slices.Contains(b.opts.Statuses, status) {
+		return
+	}
+	limit := int(float64(length) * b.opts.Factor)
+	if limit < b.opts.MinLength {
+		limit = 0
+	}
+	b.mu.Lock()
+	if cur, ok := b.limits[host]; ok && cur <= limit {
+		b.mu.Unlock()
+		return
+	}
+	b.limits[host] = limit
+	b.mu.Unlock()
+
+	e := BackoffEvent{Host: host, StatusCode: status, Length: length, Limit: limit}
+	if bm, ok := opts.Metrics.(BackoffMetrics); ok {
+		bm.RecordBackoff(e)
+	}
+	if b.opts.OnBackoff != nil {
+		b.opts.OnBackoff(e)
+	}
+}
+
+// backoffHost 返回出站请求用于回退记录的主机名
+func backoffHost(req *http.Request) string {
+	host := req.Host
+	if req.URL != nil && req.URL.Host != "" {
+		host = req.URL.Hostname()
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// limitProfile 返回长度不超过 limit 的 p 的副本: 各分量的上下限都被限制在 limit 以内, 块长度填充被关闭
+func limitProfile(p *PaddingProfile, limit int) *PaddingProfile {
+	q := *p
+	q.MinLength, q.MaxLength = min(q.MinLength, limit), min(q.MaxLength, limit)
+	q.BlockSize = 0
+	if len(q.Components) > 0 {
+		q.Components = make([]WeightedProfile, len(p.Components))
+		for i, c := range p.Components {
+			q.Components[i] = WeightedProfile{Profile: limitProfile(c.Profile, limit), Weight: c.Weight}
+		}
+	}
+	return &q
+}
@@ -0,0 +1,46 @@
+package padding
+
+import (
+	"strconv"
+	"testing"
+)
+
+func BenchmarkGetPaddingSlice(b *testing.B) {
+	for _, length := range []int{16, 256, maxPaddingSize} {
+		b.Run(strconv.Itoa(length), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				_ = getPaddingSlice(defaultRandSource, length)
+			}
+		})
+	}
+}
+
+func BenchmarkRandInt(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := randInt(defaultRandSource, 0, maxPaddingSize); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPaddingValue(b *testing.B) {
+	for _, c := range []struct {
+		name string
+		opts PaddingOptions
+	}{
+		{"raw", PaddingOptions{}},
+		{"self-describing", PaddingOptions{SelfDescribing: true}},
+		{"wire-size", PaddingOptions{WireSize: true}},
+		{"token", PaddingOptions{StructuredField: StructuredFieldToken}},
+	} {
+		opts := normalizeOptions(c.opts, "bench")
+		b.Run(c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				_ = paddingValue(256, &opts)
+			}
+		})
+	}
+}
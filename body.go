@@ -0,0 +1,169 @@
+package padding
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// bodyAllowed 报告给定的请求方法与状态码组合是否允许携带响应体
+func bodyAllowed(method string, status int) bool {
+	if method == http.MethodHead {
+		return false
+	}
+	switch {
+	case status >= 100 && status < 200:
+		return false
+	case status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
+
+// isRedirect 报告 status 是否为可携带响应体的重定向状态码 (3xx, 304 除外)
+func isRedirect(status int) bool {
+	return status >= 300 && status < 400 && status != http.StatusNotModified
+}
+
+// encoded 报告响应体是否已经过内容编码 (如 gzip)
+// 此时中间件看到的是压缩后的字节, 追加明文 padding 会破坏响应体, 说明压缩中间件位于 padding 中间件之内;
+// 应使用 WithCompression 调整顺序, 让 padding 在压缩之前追加
+func encoded(h http.Header) bool {
+	ce := h.Get("Content-Encoding")
+	return ce != "" && !strings.EqualFold(ce, "identity")
+}
+
+// htmlCommentFiller 生成恰好 n 字节的 HTML 注释 ("<!--" + padding + "-->")
+// n 不足以容纳注释定界符时退化为空白字符, 同样不会影响页面渲染
+func htmlCommentFiller(src RandSource, n int) []byte {
+	const commentOpen, commentClose = "<!--", "-->"
+	if n < len(commentOpen)+len(commentClose)+1 {
+		return whitespaceFiller(n)
+	}
+	buf := make([]byte, 0, n)
+	buf = append(buf, commentOpen...)
+	buf = append(buf, getPaddingSlice(src, n-len(commentOpen)-len(commentClose))...)
+	buf = append(buf, commentClose...)
+	return buf
+}
+
+// whitespaceFiller 生成恰好 n 字节的空白字符
+func whitespaceFiller(n int) []byte {
+	if n <= 0 {
+		return nil
+	}
+	buf := make([]byte, n)
+	for i := range buf {
+		buf[i] = ' '
+	}
+	buf[n-1] = '\n'
+	return buf
+}
+
+// ContentLengthMode 决定启用响应体 padding 时如何处理处理函数已设置的 Content-Length
+type ContentLengthMode int
+
+const (
+	// ContentLengthStrip 移除 Content-Length, 响应改为分块传输 (HTTP/1.1) 或以流结束标记结尾 (默认)
+	ContentLengthStrip ContentLengthMode = iota
+	// ContentLengthRecompute 将 Content-Length 修正为原长度加上 padding 长度, 保留定长响应
+	// JSON 字段注入模式的注入长度要到响应结束才能确定, 此时仍会移除 Content-Length
+	ContentLengthRecompute
+	// ContentLengthSkip 对已设置 Content-Length 的响应不添加响应体 padding
+	ContentLengthSkip
+)
+
+// prepareBodyPadding 在 WriteHeader 中调用, 根据内容类型决定是否为响应体添加 padding
+// 启用时按 ContentLength 的设置移除或修正 Content-Length, 因为追加的数据会使其失效; 仅在随机数生成失败时返回错误
+func (prw *paddingResponseWriter) prepareBodyPadding(statusCode int) error {
+	if !bodyAllowed(prw.req.Method, statusCode) || partialContent(statusCode) || encoded(prw.Header()) {
+		return nil
+	}
+	var profile *PaddingProfile
+	mt := mediaType(prw.Header())
+	setType := false
+	switch {
+	case prw.opts.RedirectBodyPadding != nil && isRedirect(statusCode) && (mt == "" || mt == "text/html"):
+		profile = prw.opts.RedirectBodyPadding
+		if mt == "" {
+			setType, mt = true, "text/html"
+		}
+	case prw.opts.HTMLBodyPadding != nil && mt == "text/html":
+		profile = prw.opts.HTMLBodyPadding
+	case prw.opts.JSONBodyPadding != nil && isJSONMediaType(mt):
+		profile = prw.opts.JSONBodyPadding.Profile
+	default:
+		return nil
+	}
+	cl := responseContentLength(prw.Header())
+	if cl >= 0 && prw.opts.ContentLength == ContentLengthSkip {
+		return nil
+	}
+	var length int
+	if prw.decision != nil {
+		length = min(prw.decision.Body, maxPaddingSize)
+	} else {
+		if p := entityProfile(prw.req, prw.Header(), profile, "body", prw.opts); p != nil {
+			profile = p
+		}
+		var err error
+		if length, err = profile.sample(prw.opts.Rand); err != nil {
+			return err
+		}
+	}
+	if prw.overhead >= 0 {
+		// 响应体 padding 使用头部 padding 之后剩余的 MaxOverheadPercent 额度
+		length = min(length, prw.overhead)
+	}
+	length = prw.opts.Budget.take(prw.opts.Load.scaleLength(length))
+	if length <= 0 {
+		return nil
+	}
+	prw.stats.recordBody(length)
+	if prw.opts.Metrics != nil {
+		prw.opts.Metrics.RecordBodyPadding(length)
+	}
+	prw.bodyLength = length
+	if prw.opts.DryRun {
+		// 演练模式只记录决定, 不修改响应
+		return nil
+	}
+	if setType {
+		prw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	if mt == "text/html" {
+		prw.bodyPadding = htmlCommentFiller(prw.opts.Rand, length)
+	} else {
+		prw.prepareJSONPadding(length)
+	}
+	if cl >= 0 && prw.opts.ContentLength == ContentLengthRecompute && prw.json == nil {
+		// 追加式 padding 的长度在此时已经确定, 可以直接修正 Content-Length
+		prw.Header().Set("Content-Length", strconv.FormatInt(cl+int64(len(prw.bodyPadding)), 10))
+	} else {
+		prw.Header().Del("Content-Length")
+	}
+	return nil
+}
+
+// writeBodyPadding 在处理链执行完毕后写出缓冲的响应体并追加响应体 padding, 并按 UniformErrors 与 MinTotalSize 补齐响应体
+func (prw *paddingResponseWriter) writeBodyPadding() {
+	if prw.failed || prw.ResponseWriter.IsHijacked() {
+		return
+	}
+	prw.writeMu.Lock()
+	defer prw.writeMu.Unlock()
+	if prw.json != nil {
+		if err := prw.flushJSON(); err != nil {
+			log.Printf("toukaPadding: failed to write buffered JSON body: %v", err)
+			return
+		}
+	}
+	if prw.bodyPadding != nil {
+		if _, err := prw.writeBody(prw.bodyPadding); err != nil {
+			log.Printf("toukaPadding: failed to write body padding: %v", err)
+			return
+		}
+	}
+	prw.writeFill()
+}
@@ -0,0 +1,130 @@
+package padding
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBreakerOpen 表示 Breaker 已打开 (padding 已停用), 仅在 FailClosed 模式下返回
+var ErrBreakerOpen = errors.New("padding: circuit breaker is open")
+
+// BreakerState 是 Breaker 的降级级别
+type BreakerState int
+
+const (
+	// BreakerClosed 正常工作, 使用配置的 Profile
+	BreakerClosed BreakerState = iota
+	// BreakerDegraded 已降级, 改用 BreakerOptions.Fallback 这一更小的 Profile
+	BreakerDegraded
+	// BreakerOpen 已停用 padding, 只在冷却期结束后放行探测请求
+	BreakerOpen
+)
+
+// String 返回状态的名称
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerDegraded:
+		return "degraded"
+	case BreakerOpen:
+		return "open"
+	}
+	return "closed"
+}
+
+// BreakerOptions 配置 Breaker
+type BreakerOptions struct {
+	// Threshold 是触发降一级所需的连续失败次数, 小于等于 0 时为 5
+	Threshold int
+	// Fallback 是降级状态使用的 Profile, 为 nil 时使用 ProfileShort
+	Fallback *PaddingProfile
+	// Cooldown 是降级或停用后尝试恢复前的等待时间, 小于等于 0 时为 30 秒
+	// 冷却期结束后的下一条消息作为探测按上一级的设置处理, 成功则恢复一级, 失败则重新开始冷却
+	Cooldown time.Duration
+	// OnStateChange 在状态变化时同步调用, 可以为 nil
+	OnStateChange func(from, to BreakerState) `json:"-"`
+}
+
+// Breaker 是 padding 生成的断路器: 连续失败 (如随机数源出错) 时先降级到更小的 Profile, 再停用 padding,
+// 冷却后逐级探测恢复, 而不是在每个请求上重复记录同一个错误; 通过 PaddingOptions.Breaker 安装, 可在多个实例之间共享
+type Breaker struct {
+	opts BreakerOptions
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	since    time.Time // 进入当前状态 (或上次探测失败) 的时间
+	probing  bool      // 是否有进行中的探测
+}
+
+// NewBreaker 创建一个处于 BreakerClosed 状态的 Breaker
+func NewBreaker(opts BreakerOptions) *Breaker {
+	if opts.Threshold <= 0 {
+		opts.Threshold = 5
+	}
+	if opts.Fallback == nil {
+		opts.Fallback = &ProfileShort
+	}
+	opts.Fallback = normalizeProfile(opts.Fallback, "padding.Breaker")
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = 30 * time.Second
+	}
+	return &Breaker{opts: opts}
+}
+
+// State 返回当前状态
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// admit 返回本条消息应按哪一级处理, 以及它是否为恢复探测; b 为 nil 时总是 BreakerClosed
+func (b *Breaker) admit() (BreakerState, bool) {
+	if b == nil {
+		return BreakerClosed, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerClosed || b.probing || time.Since(b.since) < b.opts.Cooldown {
+		return b.state, false
+	}
+	b.probing = true
+	return b.state - 1, true
+}
+
+// report 报告一条消息的 padding 结果
+func (b *Breaker) report(err error, probe bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	from := b.state
+	switch {
+	case probe && err == nil:
+		b.probing = false
+		b.transition(b.state - 1)
+	case probe:
+		b.probing = false
+		b.since = time.Now()
+	case err == nil:
+		b.failures = 0
+	default:
+		b.failures++
+		if b.failures >= b.opts.Threshold && b.state < BreakerOpen {
+			b.transition(b.state + 1)
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+	if from != to && b.opts.OnStateChange != nil {
+		b.opts.OnStateChange(from, to)
+	}
+}
+
+// transition 切换到状态 s 并重置计数, 调用方需持有 b.mu
+func (b *Breaker) transition(s BreakerState) {
+	b.state = s
+	b.failures = 0
+	b.since = time.Now()
+}
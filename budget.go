@@ -0,0 +1,68 @@
+package padding
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Budget 是以令牌桶实现的 padding 带宽预算, 限制 padding 每秒额外产生的字节数
+// 预算耗尽时 padding 会被缩短到剩余额度, 额度为 0 时直接跳过, 而不是让带宽开销失控
+// 一个 Budget 对应一个中间件实例的全局预算, 也可以在多个实例之间共享
+type Budget struct {
+	rate  float64 // 每秒补充的字节数
+	burst float64 // 桶容量
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+
+	consumed atomic.Uint64
+	limited  atomic.Uint64
+}
+
+// BudgetStats 是 Budget 计数器的快照
+type BudgetStats struct {
+	Consumed uint64 `json:"consumed"` // 已消耗的 padding 字节数
+	Limited  uint64 `json:"limited"`  // padding 因预算不足被缩短或跳过的次数
+}
+
+// NewBudget 创建一个每秒补充 bytesPerSecond 字节、容量为 burst 字节的预算
+// burst 小于等于 0 时等于 bytesPerSecond; 初始时桶是满的
+func NewBudget(bytesPerSecond, burst int) *Budget {
+	if burst <= 0 {
+		burst = bytesPerSecond
+	}
+	return &Budget{
+		rate:   float64(max(bytesPerSecond, 0)),
+		burst:  float64(max(burst, 0)),
+		tokens: float64(max(burst, 0)),
+	}
+}
+
+// Stats 返回预算计数器的快照
+func (b *Budget) Stats() BudgetStats {
+	return BudgetStats{Consumed: b.consumed.Load(), Limited: b.limited.Load()}
+}
+
+// take 申请 n 字节的 padding 额度, 返回实际获得的字节数; b 为 nil 时不限制
+func (b *Budget) take(n int) int {
+	if b == nil || n <= 0 {
+		return n
+	}
+	now := time.Now()
+	b.mu.Lock()
+	if !b.last.IsZero() {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+	got := min(n, int(b.tokens))
+	b.tokens -= float64(got)
+	b.mu.Unlock()
+
+	if got < n {
+		b.limited.Add(1)
+	}
+	b.consumed.Add(uint64(got))
+	return got
+}
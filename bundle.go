@@ -0,0 +1,28 @@
+package padding
+
+import (
+	"github.com/WJQSERVER-STUDIO/httpc"
+	"github.com/infinite-iroha/touka"
+)
+
+// Bundle 是网关部署使用的双向 padding 组件: Server 为下游响应添加 padding, Client 为上游请求添加 padding
+// 二者由同一个 Padder 驱动, 共享配置 (含 Reload 与启停)、Budget、LoadController 与统计信息
+type Bundle struct {
+	// Padder 是两个方向共用的 Padder, 可用于 Reload、Stats 与 AdminHandler
+	Padder *Padder
+	// Server 是安装在网关 touka 路由上的服务端中间件
+	Server touka.HandlerFunc
+	// Client 是安装在访问上游的 httpc 客户端上的中间件
+	Client httpc.MiddlewareFunc
+}
+
+// ProxyBundle 创建一个双向 padding 组件
+// 设置 opts.Budget 时两个方向的 padding 从同一个预算中扣除, 网关的总 padding 带宽因此受到统一限制
+func ProxyBundle(opts PaddingOptions) *Bundle {
+	p := newPadder(opts, "padding.ProxyBundle")
+	return &Bundle{
+		Padder: p,
+		Server: p.Server(),
+		Client: p.Client(),
+	}
+}
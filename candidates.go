@@ -0,0 +1,61 @@
+package padding
+
+import (
+	"net/http"
+	"time"
+)
+
+// WeightedHeaderName 是 HeaderCandidates 中的一个候选头部名称
+type WeightedHeaderName struct {
+	Name   string
+	Weight float64 // 相对权重, 小于等于 0 的候选会被忽略
+}
+
+// normalizeHeaderCandidates 返回名称统一为规范形式的候选副本, 移除空名称、权重不为正与重复的候选
+func normalizeHeaderCandidates(candidates []WeightedHeaderName) []WeightedHeaderName {
+	var out []WeightedHeaderName
+	seen := make(map[string]bool, len(candidates))
+	for _, c := range candidates {
+		name := http.CanonicalHeaderKey(c.Name)
+		if name == "" || c.Weight <= 0 || seen[name] {
+			continue
+		}
+		seen[name] = true
+		out = append(out, WeightedHeaderName{Name: name, Weight: c.Weight})
+	}
+	return out
+}
+
+// pickHeaderName 返回一条消息在时刻 now 使用的 padding 头部名称:
+// 设置了 HeaderCandidates 时按权重随机选出一个候选, 否则为 HeaderName (或 RotateHeader 当前的名称)
+func (opts *PaddingOptions) pickHeaderName(now time.Time) string {
+	candidates := opts.HeaderCandidates
+	if len(candidates) == 0 {
+		return opts.headerName(now)
+	}
+	total := 0.0
+	for _, c := range candidates {
+		total += c.Weight
+	}
+	r, err := randFloat64(opts.Rand)
+	if err != nil {
+		return candidates[0].Name
+	}
+	r *= total
+	for _, c := range candidates {
+		if r < c.Weight {
+			return c.Name
+		}
+		r -= c.Weight
+	}
+	return candidates[len(candidates)-1].Name
+}
+
+// candidateNames 返回所有候选头部名称
+func candidateNames(candidates []WeightedHeaderName) []string {
+	names := make([]string, len(candidates))
+	for i, c := range candidates {
+		names[i] = c.Name
+	}
+	return names
+}
@@ -0,0 +1,77 @@
+package padding
+
+import "slices"
+
+// Chain 将多个 Strategy 按顺序组合为一个流水线, 用于由内置的部件拼装复杂的方案, 例如
+// 按 URL 确定的基础长度 + 随机抖动 + 分桶取整:
+//
+//	Chain(SampleProfile, Jitter(32), RoundTo(64))
+//
+// 各阶段从左到右依次调用: 每个阶段的 RequestInfo.Prior 指向前一阶段的决定 (第一个阶段为 nil),
+// 其返回值整体代替当前的决定, 最后一个阶段的返回值即为最终决定; 只调整部分字段的阶段应原样保留其余字段
+// (内置的 Jitter 与 RoundTo 只修改头部长度); nil 阶段会被忽略, 没有阶段时不添加 padding
+func Chain(stages ...Strategy) Strategy {
+	stages = slices.DeleteFunc(slices.Clone(stages), func(s Strategy) bool { return s == nil })
+	return StrategyFunc(func(info RequestInfo) Decision {
+		var d Decision
+		for i, s := range stages {
+			if i > 0 {
+				prior := d
+				info.Prior = &prior
+			}
+			d = s.Decide(info)
+		}
+		return d
+	})
+}
+
+// SampleProfile 是一个 Strategy: 按 RequestInfo.Profile (内置规则为本次消息选出的 Profile) 为默认头部采样一个长度
+// 与 PaddingProfile.Decide 不同, 它使用逐条消息选出的 Profile, 因此 ProfileByStatus、Session、Seed.PerURL 等
+// 确定的长度在流水线中同样生效, 适合作为 Chain 的第一个阶段; Profile 为 nil 时不添加 padding
+var SampleProfile Strategy = StrategyFunc(func(info RequestInfo) Decision {
+	if info.Profile == nil {
+		return Decision{}
+	}
+	return info.Profile.Decide(info)
+})
+
+// Jitter 返回一个 Chain 阶段: 前一阶段决定的每个头部长度加上 [-n, n] 内的均匀随机偏移, 结果不小于 0
+// 随机数生成失败时保留原长度, 没有前一阶段时不添加 padding
+func Jitter(n int) Strategy {
+	return StrategyFunc(func(info RequestInfo) Decision {
+		return adjustLengths(info, func(length int) int {
+			src := info.Rand
+			if src == nil {
+				src = defaultRandSource
+			}
+			delta, err := randInt(src, -n, n)
+			if err != nil {
+				return length
+			}
+			return max(length+delta, 0)
+		})
+	})
+}
+
+// RoundTo 返回一个 Chain 阶段: 将前一阶段决定的每个头部长度向上取整到 bucket 的整数倍, 长度为 0 的头部保持不变
+// bucket 小于等于 1 时原样返回前一阶段的决定, 没有前一阶段时不添加 padding
+func RoundTo(bucket int) Strategy {
+	return StrategyFunc(func(info RequestInfo) Decision {
+		return adjustLengths(info, func(length int) int { return roundUp(length, bucket) })
+	})
+}
+
+// adjustLengths 返回前一阶段决定的副本, 其中每个头部长度经 f 调整; 没有前一阶段时返回零值
+func adjustLengths(info RequestInfo, f func(int) int) Decision {
+	if info.Prior == nil {
+		return Decision{}
+	}
+	d := *info.Prior
+	d.Headers = slices.Clone(d.Headers)
+	for i := range d.Headers {
+		if d.Headers[i].Length > 0 {
+			d.Headers[i].Length = f(d.Headers[i].Length)
+		}
+	}
+	return d
+}
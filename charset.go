@@ -0,0 +1,35 @@
+package padding
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 可用于 PoolOptions.Charset 的预设字符集, 均可以安全地用作头部值
+const (
+	// CharsetToken 是 RFC 9110 token 允许的全部字符, 值可以出现在只接受 token 的位置
+	CharsetToken = "!#$%&'*+-.^_`|~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// CharsetBase64 是标准 Base64 字母表 (不含填充字符 '='), 值看起来像常见的令牌或摘要
+	CharsetBase64 = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+	// CharsetVisibleASCII 是除空格外全部可见的 ASCII 字符 (0x21-0x7E), 字符最多, 最难被压缩
+	CharsetVisibleASCII = "!\"#$%&'()*+,-./0123456789:;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_`abcdefghijklmnopqrstuvwxyz{|}~"
+)
+
+// validateCharset 检查 charset 中的每个字符都可以出现在头部值中
+// 只接受可见的 ASCII 字符: 控制字符 (包括 DEL) 与非 ASCII 字节在头部值中非法或会被拒绝,
+// 空格与制表符在值的首尾会被截去, 使 padding 的实际长度与预期不符; 重复的字符会使字符分布不均匀
+func validateCharset(charset string) error {
+	if charset == "" {
+		return fmt.Errorf("padding: charset is empty")
+	}
+	for i := 0; i < len(charset); i++ {
+		c := charset[i]
+		if c <= ' ' || c >= 0x7f {
+			return fmt.Errorf("padding: charset contains byte 0x%02x at offset %d, only visible ASCII characters are allowed", c, i)
+		}
+		if strings.IndexByte(charset[:i], c) >= 0 {
+			return fmt.Errorf("padding: charset contains duplicate character %q", c)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package padding
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"time"
+)
+
+// 客户端中间件未添加 padding 时交给 ClientTrace.Skipped 的原因
+const (
+	TraceSkipConnect = "connect" // CONNECT 请求且未设置 PadConnect
+	TraceSkipRequest = "request" // 命中 SkipPaths、SkipUserAgents、SkipRequest、Probability 等跳过规则
+	TraceSkipUpgrade = "upgrade" // 协议升级请求且设置了 SkipUpgrade
+	TraceSkipHeader  = "header"  // 请求携带 SkipWithHeaders 中的头部
+	TraceSkipDryRun  = "dry-run" // 演练模式, 只记录决定而不修改请求
+)
+
+// ClientTrace 是客户端中间件的跟踪钩子, 与 net/http/httptrace 的 ClientTrace 并行使用,
+// 用于观察每次尝试 (包括 httpc 的重试) 添加了多少 padding、经由哪个连接发送, 排查上游的 431 等异常响应
+// 以 WithClientTrace 附加到请求的 context 上; 各回调都可以为 nil, 在发送请求的 goroutine 中同步调用
+type ClientTrace struct {
+	// Padded 在一次尝试添加 padding 之后、发送之前调用
+	Padded func(PaddingTraceInfo)
+	// Skipped 在一次尝试未添加 padding 时调用, reason 为 TraceSkip 开头的常量之一
+	Skipped func(reason string)
+	// GotConn 在添加了 padding 的尝试获得连接时调用, 可将 padding 与所用的连接 (是否复用、空闲时长) 对应起来
+	// 经由 httptrace 实现, 与请求 context 中已有的 httptrace.ClientTrace 的 GotConn 同时生效
+	GotConn func(PaddingTraceInfo, httptrace.GotConnInfo)
+}
+
+// PaddingTraceInfo 描述一次尝试添加的 padding
+type PaddingTraceInfo struct {
+	// Attempt 是第几次重试, 第一次尝试为 0
+	Attempt int
+	// Length 是 padding 头部与查询参数的总长度
+	Length int
+	// Headers 是本次尝试添加或修改的头部名称, 按字典序排列
+	Headers []string
+	// HeaderBytes 是添加 padding 后请求头部按 HTTP/1.1 格式序列化的字节数, 超过上游的限制时通常会得到 431 响应
+	HeaderBytes int
+	// Pinned 为 true 表示本次重试沿用了第一次尝试的 padding (Retry 为 RetryPin)
+	Pinned bool
+	// Delay 是发送前等待的时长
+	Delay time.Duration
+}
+
+// clientTraceKey 是 WithClientTrace 在 context 中记录 ClientTrace 所用的键
+type clientTraceKey struct{}
+
+// WithClientTrace 返回携带 trace 的 ctx 派生, 以它为 context 的请求经过客户端中间件时调用 trace 的回调
+func WithClientTrace(ctx context.Context, trace *ClientTrace) context.Context {
+	return context.WithValue(ctx, clientTraceKey{}, trace)
+}
+
+// ContextClientTrace 返回 ctx 中由 WithClientTrace 附加的 ClientTrace, 没有时返回 nil
+func ContextClientTrace(ctx context.Context) *ClientTrace {
+	t, _ := ctx.Value(clientTraceKey{}).(*ClientTrace)
+	return t
+}
+
+// traceSkipped 以 reason 调用请求 context 中 ClientTrace 的 Skipped
+func traceSkipped(req *http.Request, reason string) {
+	if t := ContextClientTrace(req.Context()); t != nil && t.Skipped != nil {
+		t.Skipped(reason)
+	}
+}
+
+// tracePadded 以本次尝试的 padding 调用请求 context 中 ClientTrace 的 Padded
+// 设置了 GotConn 时返回一个 context 附加了 httptrace 钩子的浅拷贝, 应以它发送请求; 否则返回 req 本身
+func tracePadded(req *http.Request, retry *retryState, attempt int, pinned bool) *http.Request {
+	t := ContextClientTrace(req.Context())
+	if t == nil || t.Padded == nil && t.GotConn == nil {
+		return req
+	}
+	info := retry.traceInfo()
+	info.Attempt, info.Pinned = attempt, pinned
+	info.HeaderBytes = headerSize(req.Header, "")
+	if t.Padded != nil {
+		t.Padded(info)
+	}
+	if t.GotConn == nil {
+		return req
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GotConn: func(c httptrace.GotConnInfo) { t.GotConn(info, c) },
+	}))
+}
+
+// traceInfo 返回记录的 padding 对应的 PaddingTraceInfo, 只填入长度、头部名称与延迟
+func (s *retryState) traceInfo() PaddingTraceInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.padded))
+	for name := range s.padded {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return PaddingTraceInfo{Length: s.n, Headers: names, Delay: s.delay}
+}
@@ -0,0 +1,41 @@
+package padding
+
+import (
+	"context"
+	"time"
+)
+
+// Clock 是随时间变化的特性 (Schedule、RotateHeader、ReplayWindow 的 nonce 与 Strategy 的延迟) 读取时间的来源
+// 测试可以实现一个可手动推进的 Clock, 模拟时间的流逝并得到确定的结果
+type Clock interface {
+	// Now 返回当前时间
+	Now() time.Time
+	// After 在 d 之后向返回的通道发送当时的时间
+	After(d time.Duration) <-chan time.Time
+}
+
+// now 返回 Clock 的当前时间, 未设置 Clock 时为 time.Now()
+func (opts *PaddingOptions) now() time.Time {
+	if opts.Clock == nil {
+		return time.Now()
+	}
+	return opts.Clock.Now()
+}
+
+// sleep 按 Clock 等待 d 或直到 ctx 结束, ctx 先结束时返回其错误
+func (opts *PaddingOptions) sleep(ctx context.Context, d time.Duration) error {
+	var after <-chan time.Time
+	if opts.Clock == nil {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		after = t.C
+	} else {
+		after = opts.Clock.After(d)
+	}
+	select {
+	case <-after:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
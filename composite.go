@@ -0,0 +1,68 @@
+package padding
+
+// WeightedProfile 是组合策略中的一个分量
+type WeightedProfile struct {
+	Profile *PaddingProfile
+	Weight  float64 // 相对权重, 小于等于 0 的分量会被忽略
+}
+
+// CompositeProfile 创建一个按权重混合多个 Profile 的组合策略
+// 每次采样先按权重选出一个分量, 再由该分量采样长度, 从而得到类似真实网站的多峰大小分布
+// 例如 70% ProfileShort + 30% ProfileLong:
+//
+//	CompositeProfile(
+//		WeightedProfile{Profile: &ProfileShort, Weight: 0.7},
+//		WeightedProfile{Profile: &ProfileLong, Weight: 0.3},
+//	)
+//
+// 返回的 Profile 的 MinLength/MaxLength 为所有分量范围的并集, 仅供参考, 不参与采样
+func CompositeProfile(components ...WeightedProfile) *PaddingProfile {
+	p := &PaddingProfile{}
+	first := true
+	for _, c := range components {
+		if c.Profile == nil || c.Weight <= 0 {
+			continue
+		}
+		p.Components = append(p.Components, c)
+		if first || c.Profile.MinLength < p.MinLength {
+			p.MinLength = c.Profile.MinLength
+		}
+		if first || c.Profile.MaxLength > p.MaxLength {
+			p.MaxLength = c.Profile.MaxLength
+		}
+		first = false
+	}
+	return p
+}
+
+// pick 按权重随机选出一个分量
+func (p *PaddingProfile) pick(src RandSource) (*PaddingProfile, error) {
+	total := 0.0
+	for _, c := range p.Components {
+		total += c.Weight
+	}
+	r, err := randFloat64(src)
+	if err != nil {
+		return nil, err
+	}
+	r *= total
+	for _, c := range p.Components {
+		if r < c.Weight {
+			return c.Profile, nil
+		}
+		r -= c.Weight
+	}
+	return p.Components[len(p.Components)-1].Profile, nil
+}
+
+// normalizeComponents 校验组合策略的各个分量, 移除无效分量
+func normalizeComponents(components []WeightedProfile, logPrefix string) []WeightedProfile {
+	out := make([]WeightedProfile, 0, len(components))
+	for _, c := range components {
+		if c.Profile == nil || c.Weight <= 0 {
+			continue
+		}
+		out = append(out, WeightedProfile{Profile: normalizeProfile(c.Profile, logPrefix), Weight: c.Weight})
+	}
+	return out
+}
@@ -0,0 +1,18 @@
+package padding
+
+import (
+	"github.com/infinite-iroha/touka"
+)
+
+// WithCompression 按正确的顺序组合压缩中间件与 padding 中间件, 用法: r.Use(padding.WithCompression(gzip, opts)...)
+// 压缩中间件位于外层, padding 中间件位于内层: 响应体 padding 在压缩之前追加, 压缩后仍是合法的编码流,
+// 而随机的 padding 内容难以被压缩, 压缩后仍保留大部分开销; padding 头部不受响应体压缩影响
+// 顺序相反时 padding 中间件看到的是已压缩的字节, 会自动跳过响应体 padding 以免破坏响应
+func WithCompression(compress touka.HandlerFunc, opts PaddingOptions) []touka.HandlerFunc {
+	return newPadder(opts, "toukaPadding").WithCompression(compress)
+}
+
+// WithCompression 与包级的 WithCompression 相同, 但使用 p 的可热更新配置
+func (p *Padder) WithCompression(compress touka.HandlerFunc) []touka.HandlerFunc {
+	return []touka.HandlerFunc{compress, p.Server()}
+}
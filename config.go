@@ -0,0 +1,253 @@
+package padding
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// fileOptions 是配置文件的结构, 字段名使用 snake_case
+//
+//	header_name: T-Padding
+//	header_candidates: {X-Trace-Context: 3, X-Client-Data: 1} # 按权重随机选用的头部名称
+//	profile: short            # 已注册的 Profile 名称, 或内联定义:
+//	# profile: {min_length: 64, max_length: 512, distribution: normal}
+//	skip_paths: ["/healthz", "/static/*"]
+//	skip_user_agents: ["kube-probe", "UptimeRobot"]
+//	probability: 0.8          # 取值 [0, 1], 0 表示从不添加, 省略时总是添加
+//	distribution: exponential # 覆盖 profile 中的分布
+//	fail_closed: true
+//	retry: pin                # 客户端重试沿用第一次尝试的 padding
+//	skip_status_codes: [404]
+//	max_header_bytes: 8192    # 下游头部大小上限, padding 会被自动缩短
+//	pad_all_responses: false  # true 时 HEAD 与 101/204/304 响应也添加 padding
+type fileOptions struct {
+	HeaderName   string       `json:"header_name" yaml:"header_name" toml:"header_name"`
+	Profile      *fileProfile `json:"profile" yaml:"profile" toml:"profile"`
+	SkipPaths    []string     `json:"skip_paths" yaml:"skip_paths" toml:"skip_paths"`
+	Probability  *float64     `json:"probability" yaml:"probability" toml:"probability"`
+	Distribution Distribution `json:"distribution" yaml:"distribution" toml:"distribution"`
+	Retry        RetryPolicy  `json:"retry" yaml:"retry" toml:"retry"`
+	FailClosed   bool         `json:"fail_closed" yaml:"fail_closed" toml:"fail_closed"`
+	DryRun       bool         `json:"dry_run" yaml:"dry_run" toml:"dry_run"`
+	Diagnostics  bool         `json:"diagnostics" yaml:"diagnostics" toml:"diagnostics"`
+
+	SkipUserAgents      []string           `json:"skip_user_agents" yaml:"skip_user_agents" toml:"skip_user_agents"`
+	SkipStatusCodes     []int              `json:"skip_status_codes" yaml:"skip_status_codes" toml:"skip_status_codes"`
+	MaxHeaderBytes      int                `json:"max_header_bytes" yaml:"max_header_bytes" toml:"max_header_bytes"`
+	MinTotalSize        int                `json:"min_total_size" yaml:"min_total_size" toml:"min_total_size"`
+	ContentLengthBucket int                `json:"content_length_bucket" yaml:"content_length_bucket" toml:"content_length_bucket"`
+	RangeQuantum        int                `json:"range_quantum" yaml:"range_quantum" toml:"range_quantum"`
+	SniffBytes          int                `json:"sniff_bytes" yaml:"sniff_bytes" toml:"sniff_bytes"`
+	MaxOverheadPercent  float64            `json:"max_overhead_percent" yaml:"max_overhead_percent" toml:"max_overhead_percent"`
+	PadAllResponses     bool               `json:"pad_all_responses" yaml:"pad_all_responses" toml:"pad_all_responses"`
+	PadConnect          bool               `json:"pad_connect" yaml:"pad_connect" toml:"pad_connect"`
+	SkipUpgrade         bool               `json:"skip_upgrade" yaml:"skip_upgrade" toml:"skip_upgrade"`
+	SelfDescribing      bool               `json:"self_describing" yaml:"self_describing" toml:"self_describing"`
+	SkipSigned          bool               `json:"skip_signed" yaml:"skip_signed" toml:"skip_signed"`
+	SkipWithHeaders     []string           `json:"skip_with_headers" yaml:"skip_with_headers" toml:"skip_with_headers"`
+	HeaderCandidates    map[string]float64 `json:"header_candidates" yaml:"header_candidates" toml:"header_candidates"`
+	StripVary           bool               `json:"strip_vary" yaml:"strip_vary" toml:"strip_vary"`
+
+	CORSExposeHeaders        bool     `json:"cors_expose_headers" yaml:"cors_expose_headers" toml:"cors_expose_headers"`
+	ContentLengthBucketTypes []string `json:"content_length_bucket_types" yaml:"content_length_bucket_types" toml:"content_length_bucket_types"`
+}
+
+// fileProfile 是配置文件中的 Profile, 可以写作名称字符串, 也可以内联定义
+// 同时给出名称与内联字段时, 以已注册的 Profile 为基础, 非零的内联字段覆盖对应的值
+type fileProfile struct {
+	Name         string       `json:"name" yaml:"name" toml:"name"`
+	MinLength    int          `json:"min_length" yaml:"min_length" toml:"min_length"`
+	MaxLength    int          `json:"max_length" yaml:"max_length" toml:"max_length"`
+	BlockSize    int          `json:"block_size" yaml:"block_size" toml:"block_size"`
+	Distribution Distribution `json:"distribution" yaml:"distribution" toml:"distribution"`
+}
+
+// fileProfileFields 用于在自定义解码中避免递归调用 Unmarshal 方法
+type fileProfileFields fileProfile
+
+// UnmarshalJSON 支持字符串 (Profile 名称) 与对象两种写法
+func (fp *fileProfile) UnmarshalJSON(data []byte) error {
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &fp.Name)
+	}
+	return json.Unmarshal(data, (*fileProfileFields)(fp))
+}
+
+// UnmarshalYAML 支持标量 (Profile 名称) 与映射两种写法
+func (fp *fileProfile) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&fp.Name)
+	}
+	return node.Decode((*fileProfileFields)(fp))
+}
+
+// UnmarshalTOML 支持字符串 (Profile 名称) 与表两种写法
+func (fp *fileProfile) UnmarshalTOML(v any) error {
+	switch v := v.(type) {
+	case string:
+		fp.Name = v
+		return nil
+	case map[string]any:
+		for key, dst := range map[string]*int{
+			"min_length": &fp.MinLength,
+			"max_length": &fp.MaxLength,
+			"block_size": &fp.BlockSize,
+		} {
+			if raw, ok := v[key]; ok {
+				n, ok := raw.(int64)
+				if !ok {
+					return fmt.Errorf("padding: profile.%s must be an integer", key)
+				}
+				*dst = int(n)
+			}
+		}
+		for key, dst := range map[string]*string{
+			"name":         &fp.Name,
+			"distribution": (*string)(&fp.Distribution),
+		} {
+			if raw, ok := v[key]; ok {
+				s, ok := raw.(string)
+				if !ok {
+					return fmt.Errorf("padding: profile.%s must be a string", key)
+				}
+				*dst = s
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("padding: profile must be a string or a table, got %T", v)
+	}
+}
+
+// resolve 将配置文件中的 Profile 解析为 PaddingProfile
+func (fp *fileProfile) resolve() (*PaddingProfile, error) {
+	var p PaddingProfile
+	if fp.Name != "" {
+		registered, ok := ProfileByName(fp.Name)
+		if !ok {
+			return nil, fmt.Errorf("padding: unknown profile %q", fp.Name)
+		}
+		p = *registered
+	}
+	if fp.MinLength != 0 {
+		p.MinLength = fp.MinLength
+	}
+	if fp.MaxLength != 0 {
+		p.MaxLength = fp.MaxLength
+	}
+	if fp.BlockSize != 0 {
+		p.BlockSize = fp.BlockSize
+	}
+	if fp.Distribution != "" {
+		if !fp.Distribution.valid() {
+			return nil, fmt.Errorf("padding: unknown profile distribution %q", fp.Distribution)
+		}
+		p.Distribution = fp.Distribution
+	}
+	return &p, nil
+}
+
+// LoadOptions 从 YAML、JSON 或 TOML 配置文件加载 PaddingOptions, 格式由扩展名决定
+// (.yaml/.yml、.json、.toml); 返回的配置在传给中间件时才会被校验与补全默认值
+func LoadOptions(path string) (PaddingOptions, error) {
+	return OverlayOptions(PaddingOptions{}, path)
+}
+
+// OverlayOptions 从配置文件加载配置并覆盖到 base 上: 配置文件能够表达的字段 (见 fileOptions) 全部取自文件,
+// 文件中未写出的这类字段恢复为零值; 其余只能在代码中设置的字段 (Strategy、Metrics、Budget、Rand、回调、AuthKey 与各种密钥等)
+// 保留 base 中的值; 热加载时以当前配置为 base, 避免这些字段在文件变化时被清空
+func OverlayOptions(base PaddingOptions, path string) (PaddingOptions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return PaddingOptions{}, err
+	}
+
+	var fo fileOptions
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &fo)
+	case ".json":
+		err = json.Unmarshal(data, &fo)
+	case ".toml":
+		err = toml.Unmarshal(data, &fo)
+	default:
+		return PaddingOptions{}, fmt.Errorf("padding: unsupported config file extension %q", ext)
+	}
+	if err != nil {
+		return PaddingOptions{}, fmt.Errorf("padding: failed to parse %s: %w", path, err)
+	}
+	return fo.overlay(base)
+}
+
+// overlay 将配置文件结构中的字段覆盖到 opts 上, 其余字段保持不变
+func (fo *fileOptions) overlay(opts PaddingOptions) (PaddingOptions, error) {
+	opts.HeaderName = fo.HeaderName
+	opts.SkipPaths = fo.SkipPaths
+	opts.Probability = nil
+	if fo.Probability != nil {
+		p := *fo.Probability
+		opts.Probability = &p
+	}
+	opts.Retry = fo.Retry
+	opts.FailClosed = fo.FailClosed
+	opts.DryRun = fo.DryRun
+	opts.Diagnostics = fo.Diagnostics
+
+	opts.SkipUserAgents = fo.SkipUserAgents
+	opts.SkipStatusCodes = fo.SkipStatusCodes
+	opts.MaxHeaderBytes = fo.MaxHeaderBytes
+	opts.MinTotalSize = fo.MinTotalSize
+	opts.ContentLengthBucket = fo.ContentLengthBucket
+	opts.ContentLengthBucketTypes = fo.ContentLengthBucketTypes
+	opts.RangeQuantum = fo.RangeQuantum
+	opts.SniffBytes = fo.SniffBytes
+	opts.MaxOverheadPercent = fo.MaxOverheadPercent
+	opts.PadAllResponses = fo.PadAllResponses
+	opts.PadConnect = fo.PadConnect
+	opts.SkipUpgrade = fo.SkipUpgrade
+	opts.SelfDescribing = fo.SelfDescribing
+	opts.SkipSigned = fo.SkipSigned
+	opts.SkipWithHeaders = fo.SkipWithHeaders
+	opts.StripVary = fo.StripVary
+
+	opts.CORSExposeHeaders = fo.CORSExposeHeaders
+
+	opts.HeaderCandidates = nil
+	opts.Profile = nil
+	for _, name := range slices.Sorted(maps.Keys(fo.HeaderCandidates)) {
+		opts.HeaderCandidates = append(opts.HeaderCandidates, WeightedHeaderName{Name: name, Weight: fo.HeaderCandidates[name]})
+	}
+	if fo.Profile != nil {
+		p, err := fo.Profile.resolve()
+		if err != nil {
+			return PaddingOptions{}, err
+		}
+		opts.Profile = p
+	}
+	if fo.Distribution != "" {
+		if !fo.Distribution.valid() {
+			return PaddingOptions{}, fmt.Errorf("padding: unknown distribution %q", fo.Distribution)
+		}
+		if opts.Profile == nil {
+			p := ProfileDefault
+			opts.Profile = &p
+		}
+		opts.Profile.Distribution = fo.Distribution
+	}
+	if !opts.Retry.valid() {
+		return PaddingOptions{}, fmt.Errorf("padding: unknown retry policy %q", opts.Retry)
+	}
+	if p := opts.Probability; p != nil && (*p < 0 || *p > 1) {
+		return PaddingOptions{}, fmt.Errorf("padding: probability %g is outside [0, 1]", *p)
+	}
+	return opts, nil
+}
@@ -0,0 +1,68 @@
+package padding
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadConfig 将 content 写入临时目录中扩展名为 ext 的文件并以 LoadOptions 加载
+func loadConfig(t *testing.T, ext, content string) (PaddingOptions, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "padding"+ext)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadOptions(path)
+}
+
+func TestLoadOptionsProfileDistribution(t *testing.T) {
+	for ext, content := range map[string]string{
+		".yaml": "profile: {min_length: 10, max_length: 20, distribution: gaussian}\n",
+		".json": `{"profile": {"min_length": 10, "max_length": 20, "distribution": "gaussian"}}`,
+		".toml": "[profile]\nmin_length = 10\nmax_length = 20\ndistribution = \"gaussian\"\n",
+	} {
+		if _, err := loadConfig(t, ext, content); err == nil || !strings.Contains(err.Error(), "gaussian") {
+			t.Errorf("%s: unknown inline distribution: err = %v, want an error naming it", ext, err)
+		}
+	}
+
+	opts, err := loadConfig(t, ".yaml", "profile: {min_length: 10, max_length: 20, distribution: normal}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Profile.Distribution != DistributionNormal {
+		t.Errorf("Distribution = %q, want normal", opts.Profile.Distribution)
+	}
+}
+
+func TestLoadOptionsProbability(t *testing.T) {
+	for _, c := range []struct {
+		content string
+		want    float64 // 负数表示 Probability 应为 nil (总是添加)
+		wantErr bool
+	}{
+		{"header_name: X-Pad\n", -1, false},
+		{"probability: 0.25\n", 0.25, false},
+		{"probability: 1\n", 1, false},
+		{"probability: 0\n", 0, false},
+		{"probability: 1.5\n", 0, true},
+		{"probability: -0.1\n", 0, true},
+	} {
+		opts, err := loadConfig(t, ".yaml", c.content)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%q: err = %v, wantErr %v", c.content, err, c.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		switch {
+		case c.want < 0 && opts.Probability != nil:
+			t.Errorf("%q: Probability = %g, want nil", c.content, *opts.Probability)
+		case c.want >= 0 && (opts.Probability == nil || *opts.Probability != c.want):
+			t.Errorf("%q: Probability = %v, want %g", c.content, opts.Probability, c.want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package padding
+
+import (
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// TrafficProfile 配置 Conn 对原始字节流的整形方式, 仿照 obfs4 的概率状态机:
+// 上层写入按随机大小切分为多个数据帧, 并按概率附带一个随机长度的 padding 帧,
+// 同一次写入产生的所有帧合并为一次底层写入发送; 连接空闲时还会发送只含 padding 的帧
+type TrafficProfile struct {
+	// FrameSize 决定数据帧的载荷大小, 每个帧单独采样, 长度被限制在 [1, 65535] 内 (不受 4096 字节的数据池大小限制);
+	// 为 nil 时不切分 (单帧上限为 65535 字节)
+	FrameSize *PaddingProfile
+	// Padding 决定附带的 padding 帧载荷长度, 为 nil 时使用 ProfileDefault
+	Padding *PaddingProfile
+	// PaddingProbability 是一次写入附带 padding 帧的概率, 取值 (0, 1]; 0 表示总是附带
+	PaddingProbability float64
+	// MinIdleInterval 与 MaxIdleInterval 是连接空闲时发送 padding 帧的随机间隔范围
+	// MaxIdleInterval 小于等于 0 时不发送空闲 padding; MinIdleInterval 小于等于 0 时为 MaxIdleInterval 的一半
+	MinIdleInterval time.Duration
+	MaxIdleInterval time.Duration
+}
+
+// normalizeTrafficProfile 返回补全默认值后的 TrafficProfile 副本
+func normalizeTrafficProfile(t TrafficProfile, logPrefix string) *TrafficProfile {
+	if t.FrameSize != nil {
+		t.FrameSize = normalizeFrameSize(t.FrameSize, logPrefix)
+	}
+	if t.Padding == nil {
+		t.Padding = &ProfileDefault
+	}
+	t.Padding = normalizeProfile(t.Padding, logPrefix)
+	if t.PaddingProbability < 0 || t.PaddingProbability > 1 {
+		t.PaddingProbability = 0
+	}
+	if t.MaxIdleInterval > 0 {
+		if t.MinIdleInterval <= 0 {
+			t.MinIdleInterval = t.MaxIdleInterval / 2
+		}
+		t.MinIdleInterval = min(t.MinIdleInterval, t.MaxIdleInterval)
+	}
+	return &t
+}
+
+// normalizeFrameSize 返回校验后的 FrameSize 副本
+// 帧载荷不经过数据池, 因此上限是单帧载荷的最大长度 (maxFramePayload) 而不是 maxPaddingSize; 下限为 1 字节
+func normalizeFrameSize(p *PaddingProfile, logPrefix string) *PaddingProfile {
+	profile := *p
+	if profile.MaxLength > maxFramePayload {
+		log.Printf("%s: Warning - FrameSize.MaxLength (%d) exceeds the maximum frame payload (%d). It will be capped.",
+			logPrefix, profile.MaxLength, maxFramePayload)
+		profile.MaxLength = maxFramePayload
+	}
+	profile.MaxLength = max(profile.MaxLength, 1)
+	profile.MinLength = max(profile.MinLength, 1)
+	if profile.MinLength > profile.MaxLength {
+		log.Printf("%s: Warning - FrameSize.MinLength (%d) is greater than MaxLength (%d). Adjusting to be equal.",
+			logPrefix, profile.MinLength, profile.MaxLength)
+		profile.MinLength = profile.MaxLength
+	}
+	if len(profile.Components) > 0 {
+		components := make([]WeightedProfile, 0, len(profile.Components))
+		for _, c := range profile.Components {
+			if c.Profile != nil && c.Weight > 0 {
+				components = append(components, WeightedProfile{Profile: normalizeFrameSize(c.Profile, logPrefix), Weight: c.Weight})
+			}
+		}
+		profile.Components = components
+	}
+	profile.Distribution = normalizeDistribution(profile.Distribution, logPrefix)
+	return &profile
+}
+
+// Conn 包装一个原始连接 (TCP、TLS 等), 按 profile 对出站数据分帧、附加 padding 并在空闲时发送掩护帧,
+// 同时从入站数据中剥离对端的 padding 帧; 连接两端都必须使用 Conn 包装, 帧格式与 PadWriter/PadReader 相同
+// 关闭返回的连接即停止空闲 padding
+func Conn(conn net.Conn, profile TrafficProfile) net.Conn {
+	pc := &paddedConn{
+		Conn:    conn,
+		profile: normalizeTrafficProfile(profile, "padding.Conn"),
+		src:     defaultRandSource,
+		reader:  NewPadReader(conn),
+		stop:    make(chan struct{}),
+		active:  make(chan struct{}, 1),
+	}
+	if pc.profile.MaxIdleInterval > 0 {
+		go pc.idle()
+	}
+	return pc
+}
+
+// paddedConn 是 Conn 返回的连接包装器
+type paddedConn struct {
+	net.Conn
+	profile *TrafficProfile
+	src     RandSource
+	reader  *PadReader
+
+	mu  sync.Mutex // 串行化上层写入与空闲 padding 帧
+	buf []byte
+
+	stop      chan struct{}
+	active    chan struct{} // 每次上层写入后通知空闲任务重新计时
+	closeOnce sync.Once
+}
+
+// Read 读取对端发送的数据, padding 帧会被丢弃
+func (pc *paddedConn) Read(p []byte) (int, error) {
+	return pc.reader.Read(p)
+}
+
+// Write 将 p 切分为数据帧, 按概率附带 padding 帧, 然后通过一次底层写入发送
+func (pc *paddedConn) Write(p []byte) (int, error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.buf = pc.buf[:0]
+	for rest := p; len(rest) > 0; {
+		size := maxFramePayload
+		if pc.profile.FrameSize != nil {
+			if n, err := pc.profile.FrameSize.sample(pc.src); err == nil {
+				size = n
+			}
+		}
+		chunk := rest[:min(len(rest), size)]
+		pc.buf = appendFrame(pc.buf, frameData, chunk)
+		rest = rest[len(chunk):]
+	}
+	if p := pc.profile.PaddingProbability; p == 0 || randChance(pc.src, p) {
+		if length, err := pc.profile.Padding.sample(pc.src); err == nil && length > 0 {
+			pc.buf = appendFrame(pc.buf, framePadding, getPaddingSlice(pc.src, length))
+		}
+	}
+	if _, err := pc.Conn.Write(pc.buf); err != nil {
+		return 0, err
+	}
+
+	select {
+	case pc.active <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// Close 停止空闲 padding 并关闭底层连接
+func (pc *paddedConn) Close() error {
+	pc.closeOnce.Do(func() { close(pc.stop) })
+	return pc.Conn.Close()
+}
+
+// idle 在连接空闲超过随机间隔时发送一个 padding 帧, 直到连接关闭或写入失败
+func (pc *paddedConn) idle() {
+	for {
+		interval, err := randInt(pc.src, int(pc.profile.MinIdleInterval), int(pc.profile.MaxIdleInterval))
+		if err != nil {
+			interval = int(pc.profile.MaxIdleInterval)
+		}
+		timer := time.NewTimer(time.Duration(interval))
+		select {
+		case <-pc.stop:
+			timer.Stop()
+			return
+		case <-pc.active:
+			timer.Stop()
+			continue
+		case <-timer.C:
+		}
+		if err := pc.writePadding(); err != nil {
+			return
+		}
+	}
+}
+
+// writePadding 写出一个只含 padding 的帧
+func (pc *paddedConn) writePadding() error {
+	length, err := pc.profile.Padding.sample(pc.src)
+	if err != nil {
+		return nil
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.buf = appendFrame(pc.buf[:0], framePadding, getPaddingSlice(pc.src, length))
+	_, err = pc.Conn.Write(pc.buf)
+	return err
+}
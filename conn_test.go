@@ -0,0 +1,54 @@
+package padding
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestNormalizeFrameSize(t *testing.T) {
+	for _, c := range []struct {
+		in       PaddingProfile
+		min, max int
+	}{
+		// 大于数据池大小 (4096) 的帧长度是合法的
+		{PaddingProfile{MinLength: 8192, MaxLength: 16384}, 8192, 16384},
+		{PaddingProfile{MinLength: 0, MaxLength: 1 << 20}, 1, maxFramePayload},
+		{PaddingProfile{MinLength: 0, MaxLength: 0}, 1, 1},
+		{PaddingProfile{MinLength: 9000, MaxLength: 5000}, 5000, 5000},
+	} {
+		got := normalizeTrafficProfile(TrafficProfile{FrameSize: &c.in}, "test").FrameSize
+		if got.MinLength != c.min || got.MaxLength != c.max {
+			t.Errorf("FrameSize %d-%d normalized to %d-%d, want %d-%d",
+				c.in.MinLength, c.in.MaxLength, got.MinLength, got.MaxLength, c.min, c.max)
+		}
+	}
+
+	composite := CompositeProfile(
+		WeightedProfile{Profile: &PaddingProfile{MinLength: 30000, MaxLength: 1 << 17}, Weight: 1},
+		WeightedProfile{Profile: &PaddingProfile{MinLength: 512, MaxLength: 1024}, Weight: 1},
+	)
+	got := normalizeTrafficProfile(TrafficProfile{FrameSize: composite}, "test").FrameSize
+	if c := got.Components[0].Profile; c.MinLength != 30000 || c.MaxLength != maxFramePayload {
+		t.Errorf("component normalized to %d-%d, want 30000-%d", c.MinLength, c.MaxLength, maxFramePayload)
+	}
+}
+
+func TestConnLargeFrames(t *testing.T) {
+	c1, c2 := net.Pipe()
+	a := Conn(c1, TrafficProfile{FrameSize: &PaddingProfile{MinLength: 20000, MaxLength: 40000}})
+	b := Conn(c2, TrafficProfile{})
+	defer a.Close()
+	defer b.Close()
+
+	want := bytes.Repeat([]byte("frame payload "), 20000)
+	go func() { _, _ = a.Write(want) }()
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(b, got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("payload was corrupted across large frames")
+	}
+}
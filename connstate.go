@@ -0,0 +1,147 @@
+package padding
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// connStateKey 是 ConnContext 在连接的 context 中记录 connState 所用的键
+type connStateKey struct{}
+
+// connState 是一个连接上已发送响应的 padding 记录
+type connState struct {
+	mu        sync.Mutex
+	responses int // 已记录的响应数
+	first     int // 第一个响应的 padding 头部长度
+	last      int // 上一个响应的 padding 头部长度
+	used      time.Time
+}
+
+// snapshot 返回已记录的响应数以及第一个与上一个响应的 padding 长度
+func (s *connState) snapshot() (responses, first, last int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.responses, s.first, s.last
+}
+
+// record 记录一个响应的 padding 头部长度
+func (s *connState) record(n int, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.responses == 0 {
+		s.first = n
+	}
+	s.responses++
+	s.last = n
+	s.used = now
+}
+
+// ConnContext 可以设置为 http.Server.ConnContext, 为每个连接附加一份 padding 记录,
+// 服务端中间件据此得知同一个 keep-alive 连接上之前的响应使用了多长的 padding (见 ConnPadding 与 RequestInfo.ConnResponses)
+// 已有 ConnContext 时在其中调用即可; touka 的 Run 等不支持设置 ConnContext 时, ConnPadding 改为按远端地址记录
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connStateKey{}, &connState{})
+}
+
+// ConnPadding 配置同一个连接上相继响应之间 padding 长度的关系 (仅服务端)
+// 同一个 keep-alive 连接上的响应对观察者是可以按顺序对应起来的: 连续出现相同的长度会暴露 padding 的存在,
+// 而每个响应的长度毫无关联又与真实站点 (同一页面的资源往往大小相近) 不符; 由它在两者之间选择
+type ConnPadding struct {
+	// Correlate 为 true 时, 连接上的所有响应沿用该连接第一个响应的 padding 长度;
+	// 否则每个响应按 Profile 采样, 并保证与上一个响应的长度至少相差 MinDelta
+	Correlate bool
+	// MinDelta 是去相关时相邻两个响应 padding 长度的最小差值, 小于等于 0 时为 32
+	MinDelta int
+	// MaxConns 是未使用 ConnContext 时按远端地址记录的连接数上限, 小于等于 0 时为 4096;
+	// 超过时先清理空闲超过 IdleTimeout 的记录, 仍然超过时随机丢弃一条
+	MaxConns int
+	// IdleTimeout 是按远端地址记录时, 一个连接的记录在没有新响应后保留的时长, 小于等于 0 时为 2 分钟
+	IdleTimeout time.Duration
+
+	table *connTable
+}
+
+// normalizeConnPadding 返回补全默认值的 ConnPadding 副本, 每份配置使用各自的地址记录表
+func normalizeConnPadding(c ConnPadding) *ConnPadding {
+	if c.MinDelta <= 0 {
+		c.MinDelta = 32
+	}
+	if c.MaxConns <= 0 {
+		c.MaxConns = 4096
+	}
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = 2 * time.Minute
+	}
+	c.table = &connTable{states: make(map[string]*connState)}
+	return &c
+}
+
+// connTable 按远端地址保存 connState, 用于无法设置 ConnContext 的服务器
+// 远端地址 (IP 与端口) 在连接存续期间唯一标识一个 TCP 连接
+type connTable struct {
+	mu     sync.Mutex
+	states map[string]*connState
+}
+
+// get 返回远端地址 addr 对应的记录, 不存在时创建; 记录数超过上限时先清理空闲的记录
+func (t *connTable) get(addr string, c *ConnPadding, now time.Time) *connState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s, ok := t.states[addr]; ok {
+		return s
+	}
+	if len(t.states) >= c.MaxConns {
+		for a, s := range t.states {
+			s.mu.Lock()
+			idle := now.Sub(s.used) > c.IdleTimeout
+			s.mu.Unlock()
+			if idle {
+				delete(t.states, a)
+			}
+		}
+		for a := range t.states {
+			if len(t.states) < c.MaxConns {
+				break
+			}
+			delete(t.states, a)
+		}
+	}
+	s := &connState{used: now}
+	t.states[addr] = s
+	return s
+}
+
+// connStateFor 返回请求所在连接的记录: 优先使用 ConnContext 附加的记录, 否则在设置了 ConnPadding 时按远端地址查找
+// 两者都不可用时返回 nil
+func connStateFor(req *http.Request, opts *PaddingOptions) *connState {
+	if req == nil {
+		return nil
+	}
+	if s, ok := req.Context().Value(connStateKey{}).(*connState); ok {
+		return s
+	}
+	if opts.ConnPadding == nil || req.RemoteAddr == "" {
+		return nil
+	}
+	return opts.ConnPadding.table.get(req.RemoteAddr, opts.ConnPadding, opts.now())
+}
+
+// connProfile 按 ConnPadding 的设置与连接上之前的响应调整本次响应的 Profile
+// 连接上还没有响应或未设置 ConnPadding 时返回 base
+func connProfile(s *connState, base *PaddingProfile, opts *PaddingOptions) *PaddingProfile {
+	c := opts.ConnPadding
+	if s == nil || c == nil {
+		return base
+	}
+	responses, first, last := s.snapshot()
+	if responses == 0 {
+		return base
+	}
+	if c.Correlate {
+		return &PaddingProfile{MinLength: first, MaxLength: first}
+	}
+	return apartProfile(base, last, c.MinDelta, opts)
+}
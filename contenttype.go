@@ -0,0 +1,65 @@
+package padding
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// mediaType 返回 h 中 Content-Type 的媒体类型 (小写, 不含参数), 未设置时返回空字符串
+func mediaType(h http.Header) string {
+	ct := h.Get("Content-Type")
+	if ct == "" {
+		return ""
+	}
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		mt, _, _ = strings.Cut(ct, ";")
+		mt = strings.ToLower(strings.TrimSpace(mt))
+	}
+	return mt
+}
+
+// normalizeMediaTypes 返回统一为小写、去除参数与空项的媒体类型列表副本
+func normalizeMediaTypes(types []string) []string {
+	if types == nil {
+		return nil
+	}
+	out := make([]string, 0, len(types))
+	for _, t := range types {
+		t, _, _ = strings.Cut(t, ";")
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// normalizeContentTypeProfiles 返回键名统一为小写、Profile 经过校验的映射副本
+func normalizeContentTypeProfiles(m map[string]*PaddingProfile, logPrefix string) map[string]*PaddingProfile {
+	out := make(map[string]*PaddingProfile, len(m))
+	for k, p := range m {
+		if p == nil {
+			continue
+		}
+		out[strings.ToLower(strings.TrimSpace(k))] = normalizeProfile(p, logPrefix)
+	}
+	return out
+}
+
+// profileForContentType 按媒体类型查找 Profile: 先精确匹配 (如 "text/html"),
+// 再匹配主类型通配 (如 "image/*"), 最后匹配 "*/*"; 均未命中时返回 nil
+func profileForContentType(m map[string]*PaddingProfile, mt string) *PaddingProfile {
+	if len(m) == 0 || mt == "" {
+		return nil
+	}
+	if p, ok := m[mt]; ok {
+		return p
+	}
+	if major, _, ok := strings.Cut(mt, "/"); ok {
+		if p, ok := m[major+"/*"]; ok {
+			return p
+		}
+	}
+	return m["*/*"]
+}
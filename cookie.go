@@ -0,0 +1,98 @@
+package padding
+
+import (
+	"net/http"
+)
+
+// CookiePaddingOptions 配置通过 cookie 携带的 padding
+// cookie 的大小本就因站点而异, 经过中间设备时也比未知的自定义头部更不容易被标记
+type CookiePaddingOptions struct {
+	// Name 是 cookie 名称, 默认为 "_pd"
+	Name string
+	// Profile 决定 cookie 值的长度, 为 nil 时使用为本次消息选出的 Profile
+	Profile *PaddingProfile
+	// Only 为 true 时只通过 cookie 携带 padding, 不再添加 padding 头部
+	Only bool
+	// Path 是服务端 Set-Cookie 的 Path 属性, 默认为 "/"
+	Path string
+	// Ephemeral 为 true 时服务端 Set-Cookie 带有 Max-Age=0, 浏览器收到后立即丢弃, 不会在后续请求中回传
+	Ephemeral bool
+}
+
+// normalizeCookiePadding 返回补全默认值后的 CookiePaddingOptions 副本
+func normalizeCookiePadding(c CookiePaddingOptions, logPrefix string) *CookiePaddingOptions {
+	if c.Name == "" {
+		c.Name = "_pd"
+	}
+	if c.Path == "" {
+		c.Path = "/"
+	}
+	if c.Profile != nil {
+		c.Profile = normalizeProfile(c.Profile, logPrefix)
+	}
+	return &c
+}
+
+// setPaddingCookie 将随机长度的 padding 写入 cookie: 请求方向追加到 Cookie 头部, 响应方向添加一个 Set-Cookie
+// 请求中已存在同名 cookie 时跳过; 返回 cookie 值的长度, 仅在随机数生成失败时返回错误
+func setPaddingCookie(h http.Header, dir Direction, profile *PaddingProfile, opts *PaddingOptions) (int, error) {
+	c := opts.CookiePadding
+	if c.Profile != nil {
+		profile = c.Profile
+	}
+	length, err := profile.sample(opts.Rand)
+	if err != nil {
+		return 0, err
+	}
+	length = opts.Load.scaleLength(length)
+	if opts.MaxHeaderBytes > 0 {
+		// cookie 可能与已有的 Cookie/Set-Cookie 共存, 全部头部都计入; 名称与属性的开销按 64 字节估算
+		length = max(min(length, opts.MaxHeaderBytes-headerLimitReserve-headerSize(h, "")-64), 0)
+	}
+	length = opts.Budget.take(length)
+	if length <= 0 {
+		return 0, nil
+	}
+	value := paddingString(opts.Rand, length, nil, opts.ValueCache)
+	if dir == DirectionResponse {
+		cookie := &http.Cookie{Name: c.Name, Value: value, Path: c.Path}
+		if c.Ephemeral {
+			cookie.MaxAge = -1 // 序列化为 Max-Age=0
+		}
+		h.Add("Set-Cookie", cookie.String())
+		return length, nil
+	}
+	for _, line := range h.Values("Cookie") {
+		cookies, _ := http.ParseCookie(line)
+		for _, ck := range cookies {
+			if ck.Name == c.Name {
+				return 0, nil
+			}
+		}
+	}
+	existing := h.Get("Cookie")
+	pair := c.Name + "=" + value
+	if existing != "" {
+		pair = existing + "; " + pair
+	}
+	h.Set("Cookie", pair)
+	return length, nil
+}
+
+// setPadding 按配置为一条消息设置所有的 padding 载体: padding 头部 (未被 Only 选项关闭时) 与 padding cookie
+// 返回 padding 的总长度, 仅在随机数生成失败时返回错误
+func setPadding(h http.Header, dir Direction, contentLen int64, profile *PaddingProfile, opts *PaddingOptions) (int, error) {
+	var n int
+	var err error
+	headerOff := opts.CookiePadding != nil && opts.CookiePadding.Only ||
+		dir == DirectionRequest && opts.QueryPadding != nil && opts.QueryPadding.Only
+	if !headerOff {
+		n, err = setPaddingHeader(h, contentLen, profile, opts)
+	}
+	if opts.CookiePadding != nil && err == nil {
+		var cn int
+		cn, err = setPaddingCookie(h, dir, profile, opts)
+		n += cn
+	}
+	return n, err
+}
@@ -0,0 +1,53 @@
+package padding
+
+import (
+	"net/http"
+	"strings"
+)
+
+// decideResponsePadding 在 decidePadding 的基础上, 于启用 CORSExposeHeaders 时
+// 将本次新增的 padding 头部名称追加到跨域响应的 Access-Control-Expose-Headers 中
+func decideResponsePadding(h http.Header, info RequestInfo, opts *PaddingOptions) (int, Decision, error) {
+	if !opts.CORSExposeHeaders || h.Get("Access-Control-Allow-Origin") == "" {
+		return decidePadding(h, info, opts)
+	}
+	before := make(map[string]struct{}, len(h))
+	for name := range h {
+		before[name] = struct{}{}
+	}
+	n, d, err := decidePadding(h, info, opts)
+	var added []string
+	for name := range h {
+		if _, ok := before[name]; !ok && !strings.EqualFold(name, "Set-Cookie") {
+			added = append(added, name)
+		}
+	}
+	exposeHeaders(h, added)
+	return n, d, err
+}
+
+// exposeHeaders 将 names 中尚未列出的名称追加到 Access-Control-Expose-Headers
+// 已列出通配符 "*" 且响应不允许携带凭据时, 所有头部都已暴露, 不做修改
+func exposeHeaders(h http.Header, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	listed := map[string]struct{}{}
+	for _, v := range h.Values("Access-Control-Expose-Headers") {
+		for name := range strings.SplitSeq(v, ",") {
+			listed[strings.ToLower(strings.TrimSpace(name))] = struct{}{}
+		}
+	}
+	if _, ok := listed["*"]; ok && h.Get("Access-Control-Allow-Credentials") != "true" {
+		return
+	}
+	var missing []string
+	for _, name := range names {
+		if _, ok := listed[strings.ToLower(name)]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		h.Add("Access-Control-Expose-Headers", strings.Join(missing, ", "))
+	}
+}
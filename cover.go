@@ -0,0 +1,105 @@
+package padding
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/WJQSERVER-STUDIO/httpc"
+)
+
+// CoverTrafficOptions 配置掩护流量生成器
+type CoverTrafficOptions struct {
+	// URLs 是诱饵请求的目标地址, 每次请求从中随机选取一个; 为空时不产生任何流量
+	URLs []string
+	// Method 是诱饵请求使用的方法, 默认为 GET
+	Method string
+	// MinInterval 与 MaxInterval 是同一个工作协程两次请求之间的随机间隔范围
+	// 小于等于 0 时分别为 5 秒与 30 秒
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	// Concurrency 是同时运行的工作协程数, 小于等于 0 时为 1
+	Concurrency int
+}
+
+// normalizeCoverTraffic 返回补全默认值后的 CoverTrafficOptions 副本
+func normalizeCoverTraffic(o CoverTrafficOptions) CoverTrafficOptions {
+	if o.Method == "" {
+		o.Method = http.MethodGet
+	}
+	if o.MinInterval <= 0 {
+		o.MinInterval = 5 * time.Second
+	}
+	if o.MaxInterval <= 0 {
+		o.MaxInterval = 30 * time.Second
+	}
+	if o.MinInterval > o.MaxInterval {
+		o.MinInterval = o.MaxInterval
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 1
+	}
+	o.URLs = append([]string(nil), o.URLs...)
+	return o
+}
+
+// StartCoverTraffic 使用 client 在后台以随机间隔向诱饵地址发送请求, 形成基线掩护流量,
+// 使真实请求的时间特征不那么突出; 响应体会被完整读取后丢弃
+// client 应已安装 ToukaPadding (或 Padder.Client) 中间件, 诱饵请求才会带上 padding
+// 调用返回的 stop 函数会取消进行中的请求并等待所有工作协程退出
+func StartCoverTraffic(client *httpc.Client, opts CoverTrafficOptions) (stop func()) {
+	opts = normalizeCoverTraffic(opts)
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	if len(opts.URLs) > 0 {
+		for range opts.Concurrency {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				coverWorker(ctx, client, &opts)
+			}()
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cancel()
+			wg.Wait()
+		})
+	}
+}
+
+// coverWorker 循环等待随机间隔并发送一个诱饵请求, 直到 ctx 被取消
+func coverWorker(ctx context.Context, client *httpc.Client, opts *CoverTrafficOptions) {
+	for {
+		interval, err := randInt(defaultRandSource, int(opts.MinInterval), int(opts.MaxInterval))
+		if err != nil {
+			interval = int(opts.MaxInterval)
+		}
+		timer := time.NewTimer(time.Duration(interval))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		i, err := randInt(defaultRandSource, 0, len(opts.URLs)-1)
+		if err != nil {
+			i = 0
+		}
+		resp, err := client.NewRequestBuilder(opts.Method, opts.URLs[i]).WithContext(ctx).Execute()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("padding.CoverTraffic: request to %s failed: %v", opts.URLs[i], err)
+			}
+			continue
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,103 @@
+package padding
+
+import (
+	"net/http"
+)
+
+const (
+	hexCharset    = "0123456789abcdef"
+	alnumCharset  = "abcdefghijklmnopqrstuvwxyz0123456789"
+	base64Charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+)
+
+// DecoyHeader 描述一个伪装头部的模板, 其值为 Prefix + 随机部分 + Suffix
+type DecoyHeader struct {
+	Name   string
+	Prefix string
+	Suffix string
+	// Charset 是随机部分使用的字符集, 为空时使用小写十六进制字符
+	Charset string
+	// MinLength 与 MaxLength 是随机部分的长度范围; MaxLength 小于等于 0 时等于 MinLength
+	MinLength int
+	MaxLength int
+}
+
+// DefaultDecoyHeaders 是一组模仿常见 CDN 与反向代理响应头的伪装模板
+var DefaultDecoyHeaders = []DecoyHeader{
+	{Name: "X-Request-Id", Charset: hexCharset, MinLength: 32, MaxLength: 32},
+	{Name: "X-Amz-Cf-Id", Charset: base64Charset, Suffix: "==", MinLength: 54, MaxLength: 54},
+	{Name: "X-Served-By", Prefix: "cache-", Charset: alnumCharset, MinLength: 8, MaxLength: 16},
+	{Name: "X-Trace-Id", Charset: hexCharset, MinLength: 16, MaxLength: 32},
+	{Name: "X-Correlation-Id", Charset: base64Charset, MinLength: 22, MaxLength: 43},
+	{Name: "X-Upstream-Token", Charset: base64Charset, MinLength: 32, MaxLength: 512},
+	{Name: "X-Edge-Session", Charset: base64Charset, MinLength: 64, MaxLength: 1024},
+}
+
+// normalizeDecoys 返回补全默认值后的模板副本, 移除没有名称的模板
+func normalizeDecoys(decoys []DecoyHeader) []DecoyHeader {
+	out := make([]DecoyHeader, 0, len(decoys))
+	for _, d := range decoys {
+		if d.Name == "" {
+			continue
+		}
+		if d.Charset == "" {
+			d.Charset = hexCharset
+		}
+		d.MinLength = max(d.MinLength, 0)
+		if d.MaxLength < d.MinLength {
+			d.MaxLength = d.MinLength
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// setDecoyHeaders 将 total 字节的 padding 按随机顺序分摊到伪装头部上, 每个头部的值长度 (含前后缀)
+// 都落在其模板允许的范围内; 剩余额度不足以容纳某个模板的最小长度时跳过该模板
+// 所有模板的最大长度之和小于 total 时, 超出的部分被丢弃
+// h 中已存在的头部 (如处理函数、调用方或上游设置的真实 X-Request-Id) 保持不变, 对应的模板被跳过
+func setDecoyHeaders(h http.Header, total int, decoys []DecoyHeader, src RandSource) {
+	order := make([]int, len(decoys))
+	for i := range order {
+		order[i] = i
+	}
+	for i := len(order) - 1; i > 0; i-- {
+		j, err := randInt(src, 0, i)
+		if err != nil {
+			break
+		}
+		order[i], order[j] = order[j], order[i]
+	}
+
+	remaining := total
+	for _, i := range order {
+		d := &decoys[i]
+		fixed := len(d.Prefix) + len(d.Suffix)
+		if remaining < fixed+d.MinLength || len(h.Values(d.Name)) > 0 {
+			continue
+		}
+		n, err := randInt(src, d.MinLength, min(d.MaxLength, remaining-fixed))
+		if err != nil {
+			n = d.MinLength
+		}
+		h.Set(d.Name, d.Prefix+randomString(src, d.Charset, n)+d.Suffix)
+		remaining -= fixed + n
+		if remaining <= 0 {
+			return
+		}
+	}
+}
+
+// randomString 返回由 charset 中字符组成的长度为 n 的随机字符串
+// 伪装值只需在外观上随机, 取模带来的轻微偏差可以接受; 随机源出错时退化为数据池中的内容
+// n 小于等于 0 时返回空字符串
+func randomString(src RandSource, charset string, n int) string {
+	buf := make([]byte, max(n, 0))
+	if _, err := src.Read(buf); err != nil {
+		return string(getPaddingSlice(src, n))
+	}
+	for i, b := range buf {
+		buf[i] = charset[int(b)%len(charset)]
+	}
+	return string(buf)
+}
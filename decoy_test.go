@@ -0,0 +1,45 @@
+package padding
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDecoyKeepsExistingHeaders(t *testing.T) {
+	decoys := normalizeDecoys(DefaultDecoyHeaders)
+	for range 50 {
+		h := http.Header{}
+		h.Set("X-Request-Id", "req-123")
+		h.Set("X-Trace-Id", "trace-456")
+		h.Set("X-Correlation-Id", "")
+		setDecoyHeaders(h, 2000, decoys, defaultRandSource)
+
+		if got := h.Values("X-Request-Id"); len(got) != 1 || got[0] != "req-123" {
+			t.Fatalf("X-Request-Id = %v, want the original value", got)
+		}
+		if got := h.Get("X-Trace-Id"); got != "trace-456" {
+			t.Fatalf("X-Trace-Id = %q, want the original value", got)
+		}
+		if got := h.Values("X-Correlation-Id"); len(got) != 1 || got[0] != "" {
+			t.Fatalf("X-Correlation-Id = %v, want the original empty value", got)
+		}
+		if h.Get("X-Edge-Session") == "" && h.Get("X-Upstream-Token") == "" {
+			t.Fatalf("no decoy headers were added: %v", h)
+		}
+	}
+}
+
+func TestDecoyServerKeepsHandlerRequestID(t *testing.T) {
+	opts := normalizeOptions(PaddingOptions{
+		Profile: &PaddingProfile{MinLength: 512, MaxLength: 512},
+		Decoys:  DefaultDecoyHeaders,
+	}, "test")
+	h := http.Header{}
+	h.Set("X-Request-Id", "from-handler")
+	if _, err := setPaddingHeader(h, -1, opts.Profile, &opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Get("X-Request-Id"); got != "from-handler" {
+		t.Errorf("X-Request-Id = %q, want the handler's value", got)
+	}
+}
@@ -0,0 +1,64 @@
+package padding
+
+import (
+	"context"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// 启用 Diagnostics 后, padding 的生成与写出在 pprof 标签与 runtime/trace 区域内执行:
+//   - CPU 与 goroutine profile 中的样本带有 padding.direction 与 padding.profile 标签,
+//     可以用 go tool pprof -tagfocus=padding.profile=default 等方式单独查看 padding 的开销
+//   - 执行跟踪 (go tool trace) 中出现 padding.decide 与 padding.body 区域
+//
+// 标签只在调用期间附加在当前 goroutine 上, 返回后恢复原有的标签; 未启用时没有任何额外开销
+
+// diagnose 在启用 Diagnostics 时, 以带有本次 padding 方向与 Profile 名称的 pprof 标签及名为 region 的跟踪区域执行 fn
+func diagnose(info RequestInfo, region string, opts *PaddingOptions, fn func()) {
+	if !opts.Diagnostics {
+		fn()
+		return
+	}
+	ctx := context.Background()
+	if info.Request != nil {
+		ctx = info.Request.Context()
+	}
+	labels := pprof.Labels("padding.direction", info.Direction.String(), "padding.profile", profileLabel(info.Profile))
+	pprof.Do(ctx, labels, func(ctx context.Context) {
+		trace.WithRegion(ctx, region, fn)
+	})
+}
+
+// profileLabel 返回 p 在诊断标签中使用的名称: 与某个已注册 Profile 相同时为其名称,
+// 否则按形态分为 "fixed" (会话、实体等派生的固定长度)、"composite" 与 "custom"
+func profileLabel(p *PaddingProfile) string {
+	if p == nil {
+		return "none"
+	}
+	if len(p.Components) > 0 {
+		return "composite"
+	}
+	profileRegistryMu.RLock()
+	defer profileRegistryMu.RUnlock()
+	for name, r := range profileRegistry {
+		if len(r.Components) == 0 && r.MinLength == p.MinLength && r.MaxLength == p.MaxLength &&
+			r.BlockSize == p.BlockSize && sameDistribution(r.Distribution, p.Distribution) {
+			return name
+		}
+	}
+	if p.MinLength == p.MaxLength {
+		return "fixed"
+	}
+	return "custom"
+}
+
+// sameDistribution 报告两个 Distribution 是否相同, 空字符串等同于均匀分布
+func sameDistribution(a, b Distribution) bool {
+	if a == "" {
+		a = DistributionUniform
+	}
+	if b == "" {
+		b = DistributionUniform
+	}
+	return a == b
+}
@@ -0,0 +1,92 @@
+package padding
+
+import (
+	"log"
+	"math"
+)
+
+// Distribution 决定 Profile 在 [MinLength, MaxLength] 范围内采样长度时使用的概率分布
+type Distribution string
+
+const (
+	// DistributionUniform 均匀分布, 为默认值 (空字符串等同于此值)
+	DistributionUniform Distribution = "uniform"
+	// DistributionNormal 截断正态分布, 均值位于范围中点, 标准差为范围宽度的 1/6
+	DistributionNormal Distribution = "normal"
+	// DistributionExponential 截断指数分布, 长度集中在 MinLength 附近, 偶尔出现较长的 padding
+	// 尺度参数为范围宽度的 1/4
+	DistributionExponential Distribution = "exponential"
+)
+
+// maxDistributionRetries 是截断分布在范围外重新采样的最大次数, 超过后将结果截断到范围边界
+const maxDistributionRetries = 8
+
+// valid 报告 d 是否为已知的分布
+func (d Distribution) valid() bool {
+	switch d {
+	case "", DistributionUniform, DistributionNormal, DistributionExponential:
+		return true
+	}
+	return false
+}
+
+// normalizeDistribution 校验分布名称, 未知的分布会记录警告并退化为均匀分布
+func normalizeDistribution(d Distribution, logPrefix string) Distribution {
+	if d.valid() {
+		return d
+	}
+	log.Printf("%s: Warning - unknown Profile.Distribution %q. Falling back to uniform.", logPrefix, d)
+	return DistributionUniform
+}
+
+// sample 按分布在 [min, max] 范围内采样一个整数
+func (d Distribution) sample(src RandSource, min, max int) (int, error) {
+	if min >= max {
+		return randInt(src, min, max)
+	}
+	width := float64(max - min)
+	switch d {
+	case DistributionNormal:
+		mean, stddev := float64(min)+width/2, width/6
+		return sampleTruncated(min, max, func() (float64, error) {
+			z, err := randNormal(src)
+			return mean + z*stddev, err
+		})
+	case DistributionExponential:
+		scale := width / 4
+		return sampleTruncated(min, max, func() (float64, error) {
+			u, err := randFloat64(src)
+			return float64(min) - math.Log(1-u)*scale, err
+		})
+	default:
+		return randInt(src, min, max)
+	}
+}
+
+// sampleTruncated 反复调用 draw 直到结果落在 [min, max] 范围内, 多次失败后截断到范围边界
+func sampleTruncated(min, max int, draw func() (float64, error)) (int, error) {
+	var v float64
+	for i := 0; i < maxDistributionRetries; i++ {
+		var err error
+		if v, err = draw(); err != nil {
+			return 0, err
+		}
+		if n := int(math.Round(v)); n >= min && n <= max {
+			return n, nil
+		}
+	}
+	return int(math.Max(float64(min), math.Min(float64(max), math.Round(v)))), nil
+}
+
+// randNormal 使用 Box-Muller 变换生成一个标准正态分布的随机数
+func randNormal(src RandSource) (float64, error) {
+	u1, err := randFloat64(src)
+	if err != nil {
+		return 0, err
+	}
+	u2, err := randFloat64(src)
+	if err != nil {
+		return 0, err
+	}
+	return math.Sqrt(-2*math.Log(1-u1)) * math.Cos(2*math.Pi*u2), nil
+}
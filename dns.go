@@ -0,0 +1,178 @@
+package padding
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// RFC 8467 推荐的块长度填充策略, 用于 DNS over HTTPS 等加密 DNS 传输
+// 已知原始长度时 (如 Content-Length 或 DNS 消息本身), padding 使总长度对齐到块大小;
+// 原始长度未知时退化为在一个块的范围内随机采样
+var (
+	// ProfileDoHQuery 将查询填充到 128 字节的整数倍
+	ProfileDoHQuery = PaddingProfile{MinLength: 0, MaxLength: 128, BlockSize: 128}
+
+	// ProfileDoHResponse 将响应填充到 468 字节的整数倍
+	ProfileDoHResponse = PaddingProfile{MinLength: 0, MaxLength: 468, BlockSize: 468}
+)
+
+// BlockPaddingLength 返回将 length 字节向上对齐到 block 的整数倍所需的 padding 长度
+// block 小于等于 0 时返回 0
+func BlockPaddingLength(length, block int) int {
+	if block <= 0 || length < 0 {
+		return 0
+	}
+	return roundUp(length, block) - length
+}
+
+const (
+	dnsHeaderLen      = 12
+	dnsTypeOPT        = 41
+	dnsTypeTSIG       = 250
+	ednsOptionPadding = 12 // RFC 7830
+	ednsOptionHdrLen  = 4  // OPTION-CODE + OPTION-LENGTH
+	// ednsUDPPayloadSize 是新建 OPT 记录时声明的 UDP 负载大小
+	ednsUDPPayloadSize = 1232
+)
+
+var (
+	errDNSMessageTruncated = errors.New("padding: malformed or truncated DNS message")
+	errDNSMessageSigned    = errors.New("padding: DNS message carries a TSIG record and cannot be modified")
+)
+
+// PadDNSMessage 为 DNS 消息添加 EDNS(0) Padding 选项 (RFC 7830), 使消息总长度对齐到 block 的整数倍
+// 消息已有 OPT 记录时在其中追加 (或替换已有的) Padding 选项, 否则新建一条 OPT 记录
+// 带有 TSIG 签名的消息无法修改, 会返回错误; msg 本身不会被修改
+func PadDNSMessage(msg []byte, block int) ([]byte, error) {
+	if len(msg) < dnsHeaderLen {
+		return nil, errDNSMessageTruncated
+	}
+	counts := [4]int{}
+	for i := range counts {
+		counts[i] = int(binary.BigEndian.Uint16(msg[4+2*i:]))
+	}
+
+	off := dnsHeaderLen
+	var err error
+	for i := 0; i < counts[0]; i++ {
+		if off, err = skipDNSName(msg, off); err != nil {
+			return nil, err
+		}
+		off += 4 // QTYPE + QCLASS
+	}
+	if off > len(msg) {
+		return nil, errDNSMessageTruncated
+	}
+
+	optStart, optEnd := -1, -1
+	for i := 0; i < counts[1]+counts[2]+counts[3]; i++ {
+		start := off
+		if off, err = skipDNSName(msg, off); err != nil {
+			return nil, err
+		}
+		if off+10 > len(msg) {
+			return nil, errDNSMessageTruncated
+		}
+		rrType := binary.BigEndian.Uint16(msg[off:])
+		rdLen := int(binary.BigEndian.Uint16(msg[off+8:]))
+		off += 10 + rdLen
+		if off > len(msg) {
+			return nil, errDNSMessageTruncated
+		}
+		if i >= counts[1]+counts[2] {
+			switch rrType {
+			case dnsTypeTSIG:
+				return nil, errDNSMessageSigned
+			case dnsTypeOPT:
+				optStart, optEnd = start, off
+			}
+		}
+	}
+
+	if optStart < 0 {
+		return appendOPTRecord(msg[:off], block), nil
+	}
+	return rebuildOPTRecord(msg[:off], optStart, optEnd, block)
+}
+
+// appendOPTRecord 在消息末尾追加一条只包含 Padding 选项的 OPT 记录, 并递增 ARCOUNT
+func appendOPTRecord(msg []byte, block int) []byte {
+	// 根域名 (1) + TYPE/CLASS/TTL/RDLENGTH (10) + 选项头
+	const overhead = 1 + 10 + ednsOptionHdrLen
+	padLen := BlockPaddingLength(len(msg)+overhead, block)
+
+	out := make([]byte, len(msg), len(msg)+overhead+padLen)
+	copy(out, msg)
+	binary.BigEndian.PutUint16(out[10:], binary.BigEndian.Uint16(out[10:])+1)
+	out = append(out, 0)
+	out = binary.BigEndian.AppendUint16(out, dnsTypeOPT)
+	out = binary.BigEndian.AppendUint16(out, ednsUDPPayloadSize)
+	out = binary.BigEndian.AppendUint32(out, 0)
+	out = binary.BigEndian.AppendUint16(out, uint16(ednsOptionHdrLen+padLen))
+	return appendPaddingOption(out, padLen)
+}
+
+// rebuildOPTRecord 移除 OPT 记录中已有的 Padding 选项, 再追加新的 Padding 选项
+func rebuildOPTRecord(msg []byte, optStart, optEnd, block int) ([]byte, error) {
+	nameEnd, err := skipDNSName(msg, optStart)
+	if err != nil {
+		return nil, err
+	}
+	rdStart := nameEnd + 10
+	var options []byte
+	for p := rdStart; p < optEnd; {
+		if p+ednsOptionHdrLen > optEnd {
+			return nil, errDNSMessageTruncated
+		}
+		code := binary.BigEndian.Uint16(msg[p:])
+		end := p + ednsOptionHdrLen + int(binary.BigEndian.Uint16(msg[p+2:]))
+		if end > optEnd {
+			return nil, errDNSMessageTruncated
+		}
+		if code != ednsOptionPadding {
+			options = append(options, msg[p:end]...)
+		}
+		p = end
+	}
+
+	// OPT 记录之后的附加记录 (若有) 保持原样
+	rest := msg[optEnd:]
+	base := rdStart + len(options) + len(rest)
+	padLen := BlockPaddingLength(base+ednsOptionHdrLen, block)
+	if len(options)+ednsOptionHdrLen+padLen > 0xFFFF {
+		return nil, errDNSMessageTruncated
+	}
+
+	out := make([]byte, 0, base+ednsOptionHdrLen+padLen)
+	out = append(out, msg[:rdStart]...)
+	binary.BigEndian.PutUint16(out[rdStart-2:], uint16(len(options)+ednsOptionHdrLen+padLen))
+	out = append(out, options...)
+	out = appendPaddingOption(out, padLen)
+	return append(out, rest...), nil
+}
+
+// appendPaddingOption 追加一个长度为 n 的 Padding 选项, RFC 7830 建议内容全部为 0
+func appendPaddingOption(b []byte, n int) []byte {
+	b = binary.BigEndian.AppendUint16(b, ednsOptionPadding)
+	b = binary.BigEndian.AppendUint16(b, uint16(n))
+	return append(b, make([]byte, n)...)
+}
+
+// skipDNSName 跳过从 off 开始的域名 (支持压缩指针), 返回其后的偏移量
+func skipDNSName(msg []byte, off int) (int, error) {
+	for {
+		if off >= len(msg) {
+			return 0, errDNSMessageTruncated
+		}
+		l := int(msg[off])
+		switch {
+		case l == 0:
+			return off + 1, nil
+		case l&0xC0 == 0xC0:
+			return off + 2, nil
+		case l&0xC0 != 0:
+			return 0, errDNSMessageTruncated
+		}
+		off += 1 + l
+	}
+}
@@ -0,0 +1,36 @@
+package padding
+
+import (
+	"log"
+	"net/http"
+)
+
+// dryRunPadding 在演练模式下为一条消息计算 padding 决定, 并像正常添加 padding 时一样记录统计、
+// 调用 OnPadding 与 Metrics, 但只作用于 h 的副本, 消息本身不被修改; 返回 Strategy 的决定
+func dryRunPadding(stats *paddingStats, h http.Header, info RequestInfo, opts *PaddingOptions, logPrefix string) Decision {
+	shadow := h.Clone()
+	if shadow == nil {
+		shadow = make(http.Header)
+	}
+	n, decision, err := decidePadding(shadow, info, opts)
+	if err != nil {
+		log.Printf("%s: dry run: failed to generate random padding length: %v", logPrefix, err)
+	}
+	stats.recordHeader(n)
+	notifyPadding(opts, info.Direction, info.Request, info.StatusCode, n)
+	return decision
+}
+
+// dryRun 是服务端在演练模式下的 WriteHeader: 记录头部与响应体 padding 的决定后原样写出头部
+func (prw *paddingResponseWriter) dryRun(statusCode int) {
+	decision := dryRunPadding(prw.stats, prw.Header(), prw.responseInfo(statusCode), prw.opts, "toukaPadding")
+	if prw.opts.Strategy != nil {
+		prw.decision = &decision
+	}
+	if err := prw.prepareBodyPadding(statusCode); err != nil {
+		log.Printf("toukaPadding: dry run: failed to generate random body padding length: %v", err)
+	}
+	prw.prepareBucket(statusCode)
+	prw.length.set(Length{})
+	prw.ResponseWriter.WriteHeader(statusCode)
+}
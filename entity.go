@@ -0,0 +1,61 @@
+package padding
+
+import (
+	"crypto/rand"
+	"net/http"
+	"strings"
+)
+
+// EntityPadding 配置按实体版本一致的 padding (仅服务端与反向代理的响应): 带有 ETag (或 Last-Modified) 的响应,
+// 其 padding 长度由请求路径与实体标签派生, 同一实体版本的 200 与 304 响应总是得到相同的 padding 头部长度,
+// 条件请求的不同流程不会因 padding 的差异泄露信息; 实体变化后长度随之改变
+type EntityPadding struct {
+	// Secret 是派生长度所用的 HMAC 密钥; 为空时在配置校验时随机生成, 仅在当前进程内保持一致
+	// 多个实例需要对同一实体给出相同长度时应显式设置
+	Secret []byte `json:"-"`
+}
+
+// normalizeEntityPadding 返回补全默认值后的 EntityPadding 副本
+func normalizeEntityPadding(e EntityPadding) *EntityPadding {
+	if len(e.Secret) == 0 {
+		e.Secret = make([]byte, 32)
+		if _, err := rand.Read(e.Secret); err != nil {
+			panic("toukaPadding: failed to generate entity padding secret: " + err.Error())
+		}
+	} else {
+		e.Secret = append([]byte(nil), e.Secret...)
+	}
+	return &e
+}
+
+// entityKey 返回响应的实体键: 请求路径加上 ETag (忽略弱标签前缀, 304 与 200 可能分别使用强弱比较),
+// 没有 ETag 时使用 Last-Modified; 两者都没有时返回 ""
+func entityKey(req *http.Request, h http.Header) string {
+	tag := strings.TrimPrefix(h.Get("ETag"), "W/")
+	if tag == "" {
+		tag = h.Get("Last-Modified")
+	}
+	if tag == "" || req == nil || req.URL == nil {
+		return ""
+	}
+	return req.URL.Path + "\n" + tag
+}
+
+// entityProfile 在启用 EntityPadding 且响应带有实体键时返回长度由实体派生的固定 Profile,
+// 派生值按 profile 采样; salt 区分同一响应中的不同 padding (头部与响应体); 否则返回 nil
+func entityProfile(req *http.Request, h http.Header, profile *PaddingProfile, salt string, opts *PaddingOptions) *PaddingProfile {
+	if opts.Entity == nil {
+		return nil
+	}
+	key := entityKey(req, h)
+	if key == "" {
+		return nil
+	}
+	return derivedProfile(opts.Entity.Secret, salt+"\n"+key, profile)
+}
+
+// entityRevalidation 报告是否应为 304 响应添加 padding: 启用 EntityPadding 且响应带有实体键时,
+// 304 与对应的 200 响应携带同样长度的 padding, 不再按 DefaultSkipStatusCodes 跳过
+func entityRevalidation(req *http.Request, statusCode int, h http.Header, opts *PaddingOptions) bool {
+	return opts.Entity != nil && statusCode == http.StatusNotModified && entityKey(req, h) != ""
+}
@@ -0,0 +1,57 @@
+package padding
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// Direction 表示 padding 所在消息的方向
+type Direction int
+
+const (
+	// DirectionRequest 表示 padding 添加在出站请求上 (客户端中间件与反向代理的上游请求)
+	DirectionRequest Direction = iota
+	// DirectionResponse 表示 padding 添加在响应上 (服务端中间件与反向代理的下游响应)
+	DirectionResponse
+)
+
+// String 返回方向的名称
+func (d Direction) String() string {
+	if d == DirectionResponse {
+		return "response"
+	}
+	return "request"
+}
+
+// PaddingEvent 描述一次 padding 头部的决策, 由 OnPadding 回调接收
+type PaddingEvent struct {
+	Direction  Direction
+	HeaderName string
+	Length     int      // padding 头部值的长度, 0 表示本次未添加头部
+	Method     string   // 请求方法
+	URL        *url.URL // 请求的 URL, 调用方不得修改
+	StatusCode int      // 响应状态码, 仅在 DirectionResponse 时有效
+}
+
+// notifyPadding 在设置了 OnPadding 或 Metrics 时以本次决策调用回调
+func notifyPadding(opts *PaddingOptions, dir Direction, req *http.Request, statusCode, length int) {
+	if opts.OnPadding == nil && opts.Metrics == nil {
+		return
+	}
+	ev := PaddingEvent{
+		Direction:  dir,
+		HeaderName: opts.headerName(opts.now()),
+		Length:     length,
+		StatusCode: statusCode,
+	}
+	if req != nil {
+		ev.Method = req.Method
+		ev.URL = req.URL
+	}
+	if opts.Metrics != nil {
+		opts.Metrics.RecordPadding(ev)
+	}
+	if opts.OnPadding != nil {
+		opts.OnPadding(ev)
+	}
+}
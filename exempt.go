@@ -0,0 +1,83 @@
+package padding
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// signatureHeaders 是 HTTP 消息签名 (RFC 9421) 使用的头部, SkipSigned 时携带其中任一头部的消息不添加 padding
+var signatureHeaders = []string{"Signature", "Signature-Input"}
+
+// normalizeSkipHeaders 返回 SkipWithHeaders 中非空名称的规范形式, SkipSigned 时加入签名头部
+func normalizeSkipHeaders(names []string, signed bool) []string {
+	var out []string
+	add := func(name string) {
+		if name = http.CanonicalHeaderKey(name); name != "" && !slices.Contains(out, name) {
+			out = append(out, name)
+		}
+	}
+	for _, name := range names {
+		add(name)
+	}
+	if signed {
+		for _, name := range signatureHeaders {
+			add(name)
+		}
+	}
+	return out
+}
+
+// skipWithHeader 报告 h 是否携带了 SkipWithHeaders 中的任一头部
+// 这类消息的头部可能被签名或摘要整体覆盖, 添加 padding 头部会使校验失败
+func skipWithHeader(h http.Header, opts *PaddingOptions) bool {
+	for _, name := range opts.SkipWithHeaders {
+		if _, ok := h[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// stripVary 从响应的 Vary 头部中移除 padding 头部名称
+// 请求的 padding 头部每次都不同, Vary 中列出它会使缓存对每个请求都无法命中
+func stripVary(h http.Header, opts *PaddingOptions) {
+	values := h.Values("Vary")
+	if len(values) == 0 {
+		return
+	}
+	names := opts.headerNames(opts.now())
+	var kept []string
+	changed := false
+	for _, v := range values {
+		for field := range strings.SplitSeq(v, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			if containsFold(names, field) {
+				changed = true
+				continue
+			}
+			kept = append(kept, field)
+		}
+	}
+	if !changed {
+		return
+	}
+	if len(kept) == 0 {
+		h.Del("Vary")
+		return
+	}
+	h.Set("Vary", strings.Join(kept, ", "))
+}
+
+// containsFold 报告 names 中是否有与 name 不区分大小写相同的项
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package padding
+
+import (
+	"bufio"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/infinite-iroha/touka"
+)
+
+// 框架自身产生的响应
+//
+// ToukaPaddingS 作为全局中间件 (Engine.Use) 安装时, 路由未命中的 404 与 405 响应同样经过它,
+// 但以下两类响应可能绕过 padding:
+//   - Recovery 安装在 padding 中间件之外时, panic 会越过 padding 写入器, 之后写出的 500 响应不带 padding;
+//     使用 Padder.Recovery 代替两者, 即可在 padding 写入器之内恢复 panic
+//   - touka 的尾部斜杠与大小写修正重定向 (301) 在执行全局中间件之前直接写出;
+//     使用 Padder.Handler 在 Engine 之外包装整个 http.Handler, 即可覆盖引擎产生的所有响应
+
+// Recovery 返回一个同时提供 padding 与 panic 恢复的服务端中间件, 代替 ToukaPaddingS + touka.Recovery 的组合
+// panic 在 padding 写入器之内被恢复, handler 写出的错误响应与普通响应一样添加 padding
+// handler 为 nil 时记录日志并通过引擎的错误处理器写出 500 (响应已开始写出时只中止处理链)
+func (p *Padder) Recovery(handler touka.PanicHandlerFunc) touka.HandlerFunc {
+	if handler == nil {
+		handler = defaultPanicHandler
+	}
+	return func(c *touka.Context) {
+		p.serveRecover(c, handler)
+	}
+}
+
+// defaultPanicHandler 是 Recovery 默认的 panic 处理
+func defaultPanicHandler(c *touka.Context, r any) {
+	log.Printf("toukaPadding: panic recovered for %s %s: %v\n%s", c.Request.Method, c.Request.URL.Path, r, debug.Stack())
+	if c.Writer.Written() {
+		c.Abort()
+		return
+	}
+	c.ErrorUseHandle(http.StatusInternalServerError, errors.New("Internal Panic Error"))
+}
+
+// Handler 在 net/http 层包装 h (通常是 touka.Engine), 使 h 写出的所有响应 (包括引擎在执行中间件之前
+// 直接写出的重定向) 都添加 padding; 应代替而不是叠加 ToukaPaddingS 使用, 否则响应会被填充两次
+// 此时 LengthFromContext 不可用
+func (p *Padder) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		opts := p.load()
+		if p.skip(req, opts) {
+			opts.Audit.record(-1, false)
+			h.ServeHTTP(w, req)
+			return
+		}
+		hw := &httpResponseWriter{ResponseWriter: w}
+		if opts.Observe.observing() {
+			h.ServeHTTP(hw, req)
+			if hw.Written() && !hw.hijacked {
+				opts.Observe.record(headerSize(w.Header(), ""), hw.size)
+			}
+			return
+		}
+		quantizeRange(req.Header, opts.RangeQuantum)
+		prw := &paddingResponseWriter{
+			ResponseWriter: hw,
+			opts:           opts,
+			req:            req,
+			stats:          &p.stats,
+			length:         &lengthRecord{},
+		}
+		defer prw.finish()
+		h.ServeHTTP(prw, req)
+	})
+}
+
+// httpResponseWriter 将 http.ResponseWriter 适配为 touka.ResponseWriter, 供 Handler 使用
+type httpResponseWriter struct {
+	http.ResponseWriter
+	status   int
+	size     int
+	hijacked bool
+}
+
+func (w *httpResponseWriter) WriteHeader(statusCode int) {
+	if w.hijacked {
+		return
+	}
+	if statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	if w.status == 0 {
+		w.status = statusCode
+		w.ResponseWriter.WriteHeader(statusCode)
+	}
+}
+
+func (w *httpResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+func (w *httpResponseWriter) Status() int      { return w.status }
+func (w *httpResponseWriter) Size() int        { return w.size }
+func (w *httpResponseWriter) Written() bool    { return w.status != 0 }
+func (w *httpResponseWriter) IsHijacked() bool { return w.hijacked }
+
+func (w *httpResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && !w.hijacked {
+		f.Flush()
+	}
+}
+
+func (w *httpResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := hj.Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap 返回底层的 http.ResponseWriter, 供 http.ResponseController 使用
+func (w *httpResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+var _ touka.ResponseWriter = &httpResponseWriter{}
@@ -0,0 +1,165 @@
+package padding
+
+import (
+	"crypto/sha256"
+	mrand "math/rand/v2"
+	"testing"
+
+	"golang.org/x/net/http/httpguts"
+	"golang.org/x/net/http2/hpack"
+)
+
+// fuzzRand 返回由 seed 确定的 RandSource, 使同一个语料的结果可以复现
+func fuzzRand(seed []byte) RandSource {
+	return mrand.NewChaCha8(sha256.Sum256(seed))
+}
+
+// fuzzRange 将任意的 lo, hi 映射到 [0, maxPaddingSize] 内的一个范围, lo 不大于 hi
+func fuzzRange(lo, hi int) (int, int) {
+	lo, hi = fuzzAbs(lo)%(maxPaddingSize+1), fuzzAbs(hi)%(maxPaddingSize+1)
+	return min(lo, hi), max(lo, hi)
+}
+
+// fuzzAbs 返回 n 的绝对值, 最小的负数映射为最大的正数而不溢出
+func fuzzAbs(n int) int {
+	if n < 0 {
+		return -(n + 1)
+	}
+	return n
+}
+
+// fuzzDistributions 是由语料中的一个字节选择的分布
+var fuzzDistributions = []Distribution{"", DistributionUniform, DistributionNormal, DistributionExponential}
+
+func FuzzRandInt(f *testing.F) {
+	f.Add([]byte("seed"), 0, 0)
+	f.Add([]byte{}, -5, 5)
+	f.Add([]byte{1, 2, 3}, 10, 3)
+	f.Add([]byte("wide"), -1<<40, 1<<40)
+	f.Fuzz(func(t *testing.T, seed []byte, lo, hi int) {
+		// 避免 max-min+1 溢出
+		lo, hi = lo>>2, hi>>2
+		src := fuzzRand(seed)
+		v, err := randInt(src, lo, hi)
+		if lo > hi {
+			if err == nil {
+				t.Fatalf("randInt(%d, %d) = %d, want error", lo, hi, v)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("randInt(%d, %d): %v", lo, hi, err)
+		}
+		if v < lo || v > hi {
+			t.Fatalf("randInt(%d, %d) = %d, out of range", lo, hi, v)
+		}
+	})
+}
+
+func FuzzGetPaddingSlice(f *testing.F) {
+	f.Add([]byte("seed"), 0)
+	f.Add([]byte{}, -1)
+	f.Add([]byte{7}, 1)
+	f.Add([]byte("max"), maxPaddingSize)
+	f.Add([]byte("over"), maxPaddingSize+100)
+	f.Fuzz(func(t *testing.T, seed []byte, length int) {
+		b := getPaddingSlice(fuzzRand(seed), length)
+		want := min(max(length, 0), maxPaddingSize)
+		if len(b) != want {
+			t.Fatalf("getPaddingSlice(%d) returned %d bytes, want %d", length, len(b), want)
+		}
+		if !httpguts.ValidHeaderFieldValue(string(b)) {
+			t.Fatalf("getPaddingSlice(%d) = %q, not a valid header value", length, b)
+		}
+	})
+}
+
+func FuzzStrategySampling(f *testing.F) {
+	f.Add([]byte("seed"), 0, 0, byte(0), 0)
+	f.Add([]byte{}, 16, 512, byte(1), 32)
+	f.Add([]byte{9}, 100, 10, byte(2), 7)
+	f.Add([]byte("exp"), 0, maxPaddingSize, byte(3), 64)
+	f.Fuzz(func(t *testing.T, seed []byte, lo, hi int, dist byte, jitter int) {
+		lo, hi = fuzzRange(lo, hi)
+		jitter = fuzzAbs(jitter) % 256
+		profile := &PaddingProfile{MinLength: lo, MaxLength: hi, Distribution: fuzzDistributions[int(dist)%len(fuzzDistributions)]}
+		info := RequestInfo{Profile: profile, Rand: fuzzRand(seed), ContentLength: -1}
+
+		check := func(name string, d Decision, lo, hi int) {
+			t.Helper()
+			for _, h := range d.Headers {
+				if h.Length < lo || h.Length > hi {
+					t.Fatalf("%s: length %d outside [%d, %d]", name, h.Length, lo, hi)
+				}
+			}
+		}
+		check("PaddingProfile", profile.Decide(info), lo, hi)
+		check("SampleProfile", SampleProfile.Decide(info), lo, hi)
+		check("Chain+Jitter", Chain(SampleProfile, Jitter(jitter)).Decide(info), max(lo-jitter, 0), hi+jitter)
+		check("Chain+RoundTo", Chain(SampleProfile, RoundTo(64)).Decide(info), lo, roundUp(hi, 64))
+
+		composite := &PaddingProfile{Components: []WeightedProfile{
+			{Profile: profile, Weight: 1},
+			{Profile: &PaddingProfile{MinLength: hi, MaxLength: hi}, Weight: 1},
+		}}
+		check("Composite", composite.Decide(info), lo, hi)
+	})
+}
+
+func FuzzValueEncoder(f *testing.F) {
+	f.Add([]byte("seed"), 0, byte(0))
+	f.Add([]byte{}, 1, byte(1))
+	f.Add([]byte{3}, 6, byte(2))
+	f.Add([]byte("sf"), 17, byte(3))
+	f.Add([]byte("wire"), 300, byte(4))
+	f.Add([]byte("big"), maxPaddingSize, byte(5))
+	f.Fuzz(func(t *testing.T, seed []byte, length int, mode byte) {
+		length = fuzzAbs(length) % (maxPaddingSize + 1)
+		opts := PaddingOptions{Rand: fuzzRand(seed)}
+		switch mode % 6 {
+		case 1:
+			opts.SelfDescribing = true
+		case 2:
+			opts.WireSize = true
+		case 3:
+			opts.StructuredField = StructuredFieldToken
+		case 4:
+			opts.StructuredField = StructuredFieldByteSequence
+		case 5:
+			opts.ValueCache = NewValueCache(0, 0)
+		}
+		opts = normalizeOptions(opts, "fuzz")
+
+		v := paddingValue(length, &opts)
+		if !httpguts.ValidHeaderFieldValue(v) {
+			t.Fatalf("paddingValue(%d) = %q, not a valid header value", length, v)
+		}
+		switch {
+		case opts.SelfDescribing:
+			if !IsPaddingValue(v) {
+				t.Fatalf("paddingValue(%d) = %q, not a self-describing value", length, v)
+			}
+			if length >= 6 && len(v) != length {
+				t.Fatalf("paddingValue(%d) returned %d bytes", length, len(v))
+			}
+		case opts.StructuredField == StructuredFieldToken:
+			// sf-token 至少包含一个字母
+			if len(v) != max(length, 1) {
+				t.Fatalf("paddingValue(%d) returned %d bytes", length, len(v))
+			}
+		case opts.StructuredField == StructuredFieldByteSequence:
+			if len(v) > max(length, 2) || len(v) < length-3 {
+				t.Fatalf("paddingValue(%d) returned %d bytes", length, len(v))
+			}
+		case opts.WireSize:
+			wire := min(int(hpack.HuffmanEncodeLength(v)), len(v))
+			if len(v) > maxPaddingSize || wire < length && len(v) < maxPaddingSize {
+				t.Fatalf("paddingValue(%d) returned %d bytes encoding to %d", length, len(v), wire)
+			}
+		default:
+			if len(v) != length {
+				t.Fatalf("paddingValue(%d) returned %d bytes", length, len(v))
+			}
+		}
+	})
+}
@@ -3,8 +3,15 @@ module github.com/fenthope/padding
 go 1.24.4
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/WJQSERVER-STUDIO/httpc v0.8.1
 	github.com/infinite-iroha/touka v0.3.1
+	go.opentelemetry.io/otel v1.36.0
+	go.opentelemetry.io/otel/metric v1.36.0
+	golang.org/x/net v0.42.0
+	google.golang.org/grpc v1.74.2
+	google.golang.org/protobuf v1.36.6
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -12,5 +19,7 @@ require (
 	github.com/fenthope/reco v0.0.3 // indirect
 	github.com/go-json-experiment/json v0.0.0-20250714165856-be8212f5270d // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
-	golang.org/x/net v0.42.0 // indirect
+	golang.org/x/sys v0.34.0 // indirect
+	golang.org/x/text v0.27.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250528174236-200df99c418a // indirect
 )
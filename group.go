@@ -0,0 +1,23 @@
+package padding
+
+import (
+	"github.com/infinite-iroha/touka"
+)
+
+// ForGroup 为路由分组 group 安装使用 opts 的服务端 padding 中间件, 并返回其 Padder (可用于 Reload、启停与读取统计信息)
+// 不同的分组各自调用 ForGroup, 即可声明式地使用不同的 Profile 等配置, 无需在自定义的 Strategy 中按 info.Request 的路径编写分支
+//
+// 外层 (Engine 或上级分组) 已经安装了 padding 中间件时, 分组的配置取代外层的配置而不是叠加:
+// 尚未写出头部的外层 padding 写入器被移除, 响应只按最内层分组的配置添加一次 padding;
+// 外层配置中的 SkipPaths 等跳过规则也不再适用, 由分组自己的配置决定
+func ForGroup(group touka.IRouter, opts PaddingOptions) *Padder {
+	p := newPadder(opts, "toukaPadding.Group")
+	group.Use(func(c *touka.Context) {
+		if prw, ok := c.Writer.(*paddingResponseWriter); ok && !prw.Written() {
+			// 外层写入器在未写出头部时不产生任何输出, 它的 finish 也不会写出内容
+			c.Writer = prw.ResponseWriter
+		}
+		p.serve(c)
+	})
+	return p
+}
@@ -0,0 +1,73 @@
+package grpcpad
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// paddingFieldNumber 是承载消息 padding 的 protobuf 字段编号 (允许的最大值)
+// 接收方会将其视为未知字段并忽略, 因此无需剥离即可与未包装 Codec 的对端互通
+const paddingFieldNumber protowire.Number = protowire.MaxValidNumber
+
+// bucketCodec 在序列化后的消息末尾追加一个未知的 bytes 字段, 使消息大小对齐到桶
+type bucketCodec struct {
+	encoding.Codec
+	bucket int
+}
+
+// NewCodec 包装一个 protobuf Codec, 将每个序列化后的消息填充到 bucket 字节的整数倍
+// 只适用于 protobuf 编码: padding 以未知字段的形式附加, 合法的 protobuf 解析器会忽略它
+// 返回的 Codec 名称与 base 相同, 可通过 grpc.ForceCodec / grpc.ForceServerCodec 使用
+func NewCodec(base encoding.Codec, bucket int) encoding.Codec {
+	return &bucketCodec{Codec: base, bucket: bucket}
+}
+
+// Marshal 序列化消息并追加 padding 字段
+func (c *bucketCodec) Marshal(v any) ([]byte, error) {
+	data, err := c.Codec.Marshal(v)
+	if err != nil || c.bucket <= 0 {
+		return data, err
+	}
+	return appendPaddingField(data, c.bucket)
+}
+
+// maxBucketAttempts 是寻找可行的对齐目标时最多尝试的桶数
+// 只有长度前缀恰好跨越 varint 的字节数边界时当前目标才不可行, 下一个桶总能避开这个边界
+const maxBucketAttempts = 4
+
+// appendPaddingField 追加一个 padding 字段, 使结果长度恰好为 bucket 的整数倍
+func appendPaddingField(data []byte, bucket int) ([]byte, error) {
+	tagSize := protowire.SizeTag(paddingFieldNumber)
+	// 字段的最小开销: 标签 + 1 字节长度
+	target := (len(data) + tagSize + 1 + bucket - 1) / bucket * bucket
+	for range maxBucketAttempts {
+		if n, ok := fieldPayloadSize(target-len(data), tagSize); ok {
+			pad := make([]byte, n)
+			if _, err := rand.Read(pad); err != nil {
+				return nil, err
+			}
+			data = protowire.AppendTag(data, paddingFieldNumber, protowire.BytesType)
+			return protowire.AppendBytes(data, pad), nil
+		}
+		target += bucket
+	}
+	return nil, fmt.Errorf("grpcpad: no padding field aligns a %d-byte message to a %d-byte bucket", len(data), bucket)
+}
+
+// fieldPayloadSize 求解负载长度 n, 使 (标签 + 长度前缀 + n) 恰好等于 total
+// 长度前缀不会长于 total 本身的 varint 编码, 因此只需尝试到该长度
+func fieldPayloadSize(total, tagSize int) (int, bool) {
+	if total <= 0 {
+		return 0, false
+	}
+	for prefix := 1; prefix <= protowire.SizeVarint(uint64(total)); prefix++ {
+		n := total - tagSize - prefix
+		if n >= 0 && protowire.SizeVarint(uint64(n)) == prefix {
+			return n, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,59 @@
+package grpcpad
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// rawCodec 将 []byte 原样作为序列化结果, 用于构造任意大小的消息
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error)      { return v.([]byte), nil }
+func (rawCodec) Unmarshal(data []byte, v any) error { *v.(*[]byte) = data; return nil }
+func (rawCodec) Name() string                       { return "raw" }
+
+func TestCodecLargeMessages(t *testing.T) {
+	for _, c := range []struct {
+		size, bucket int
+	}{
+		{100, 64},
+		{2 << 20, 1024},
+		{2<<20 - 3, 1},
+		{3 << 20, 16 << 10},
+		{1000, 4 << 20},
+		{(4 << 20) - 10, 4 << 20},
+	} {
+		msg := bytes.Repeat([]byte{0x08, 0x01}, c.size/2)
+		out, err := NewCodec(rawCodec{}, c.bucket).Marshal(msg)
+		if err != nil {
+			t.Fatalf("size %d bucket %d: %v", c.size, c.bucket, err)
+		}
+		if len(out)%c.bucket != 0 {
+			t.Errorf("size %d bucket %d: output %d bytes is not aligned", c.size, c.bucket, len(out))
+		}
+		if !bytes.HasPrefix(out, msg) {
+			t.Fatalf("size %d bucket %d: message was modified", c.size, c.bucket)
+		}
+		num, typ, n := protowire.ConsumeField(out[len(msg):])
+		if n != len(out)-len(msg) || num != paddingFieldNumber || typ != protowire.BytesType {
+			t.Errorf("size %d bucket %d: trailing bytes are not a single padding field", c.size, c.bucket)
+		}
+	}
+}
+
+func TestFieldPayloadSize(t *testing.T) {
+	tagSize := protowire.SizeTag(paddingFieldNumber)
+	for total := 1; total < 5<<20; total += 997 {
+		n, ok := fieldPayloadSize(total, tagSize)
+		if ok && tagSize+protowire.SizeVarint(uint64(n))+n != total {
+			t.Fatalf("fieldPayloadSize(%d) = %d does not add up", total, n)
+		}
+	}
+	for _, total := range []int{1 << 21, 1 << 28, 1<<28 + 100} {
+		if _, ok := fieldPayloadSize(total, tagSize); !ok {
+			t.Errorf("fieldPayloadSize(%d) found no payload size", total)
+		}
+	}
+}
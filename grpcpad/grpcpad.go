@@ -0,0 +1,117 @@
+// Copyright 2025 Infinite-Iroha. All rights reserved.
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+// Package grpcpad 为 gRPC 服务与客户端提供与 padding 包相同的流量随机化能力
+// 拦截器在 metadata 中附加随机长度的 padding, Codec 包装器可将消息对齐到固定大小的桶
+package grpcpad
+
+import (
+	"context"
+	"log"
+
+	"github.com/fenthope/padding"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Options 配置 gRPC 拦截器
+type Options struct {
+	// MetadataKey 是承载 padding 的 metadata 键名, 默认为 "t-padding"
+	// gRPC metadata 键名必须为小写, 传入的值会被转换为小写
+	MetadataKey string
+	// Profile 是 padding 长度分布策略, 为 nil 时使用 padding.ProfileDefault
+	// 在构造拦截器时按 padding 包的规则校验一次 (如上下限颠倒时修正为相等), 之后的修改不影响已构造的拦截器
+	Profile *padding.PaddingProfile
+}
+
+// normalize 补全默认值并校验 Profile
+func (o Options) normalize() Options {
+	if o.MetadataKey == "" {
+		o.MetadataKey = "t-padding"
+	}
+	o.MetadataKey = lower(o.MetadataKey)
+	o.Profile = padding.NormalizeProfile(o.Profile, "grpcpad")
+	return o
+}
+
+// lower 将 ASCII 大写字母转换为小写
+func lower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + ('a' - 'A')
+		}
+	}
+	return string(b)
+}
+
+// paddingMD 生成一组只包含 padding 的 metadata, 长度为 0 时返回 nil
+func (o Options) paddingMD() metadata.MD {
+	value, err := padding.Value(o.Profile)
+	if err != nil {
+		// 随机数生成失败是一个罕见的内部错误，记录日志但不中断调用
+		log.Printf("grpcpad: failed to generate random padding: %v", err)
+		return nil
+	}
+	if value == "" {
+		return nil
+	}
+	return metadata.Pairs(o.MetadataKey, value)
+}
+
+// UnaryServerInterceptor 返回一个在响应头部 metadata 中附加 padding 的一元服务端拦截器
+func UnaryServerInterceptor(opts Options) grpc.UnaryServerInterceptor {
+	opts = opts.normalize()
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if md := opts.paddingMD(); md != nil {
+			if err := grpc.SetHeader(ctx, md); err != nil {
+				log.Printf("grpcpad: failed to set padding header: %v", err)
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor 返回一个在响应头部 metadata 中附加 padding 的流式服务端拦截器
+func StreamServerInterceptor(opts Options) grpc.StreamServerInterceptor {
+	opts = opts.normalize()
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if md := opts.paddingMD(); md != nil {
+			if err := ss.SetHeader(md); err != nil {
+				log.Printf("grpcpad: failed to set padding header: %v", err)
+			}
+		}
+		return handler(srv, ss)
+	}
+}
+
+// UnaryClientInterceptor 返回一个在请求 metadata 中附加 padding 的一元客户端拦截器
+func UnaryClientInterceptor(opts Options) grpc.UnaryClientInterceptor {
+	opts = opts.normalize()
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		return invoker(opts.outgoing(ctx), method, req, reply, cc, callOpts...)
+	}
+}
+
+// StreamClientInterceptor 返回一个在请求 metadata 中附加 padding 的流式客户端拦截器
+func StreamClientInterceptor(opts Options) grpc.StreamClientInterceptor {
+	opts = opts.normalize()
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(opts.outgoing(ctx), desc, cc, method, callOpts...)
+	}
+}
+
+// outgoing 返回附加了 padding metadata 的出站上下文, 已存在的同名键会被覆盖
+func (o Options) outgoing(ctx context.Context) context.Context {
+	md := o.paddingMD()
+	if md == nil {
+		return ctx
+	}
+	out, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		return metadata.NewOutgoingContext(ctx, md)
+	}
+	out = out.Copy()
+	out.Set(o.MetadataKey, md.Get(o.MetadataKey)...)
+	return metadata.NewOutgoingContext(ctx, out)
+}
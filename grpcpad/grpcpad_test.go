@@ -0,0 +1,29 @@
+package grpcpad
+
+import (
+	"testing"
+
+	"github.com/fenthope/padding"
+)
+
+func TestOptionsNormalizeProfile(t *testing.T) {
+	src := &padding.PaddingProfile{MinLength: 300, MaxLength: 100}
+	o := Options{Profile: src}.normalize()
+	if o.Profile.MinLength != 100 || o.Profile.MaxLength != 100 {
+		t.Fatalf("Profile normalized to %d-%d, want 100-100", o.Profile.MinLength, o.Profile.MaxLength)
+	}
+	if src.MinLength != 300 {
+		t.Errorf("normalize modified the caller's profile")
+	}
+	for range 10 {
+		md := o.paddingMD()
+		if v := md.Get(o.MetadataKey); len(v) != 1 || len(v[0]) != 100 {
+			t.Fatalf("padding metadata = %v, want one 100-byte value", v)
+		}
+	}
+
+	d := padding.ProfileDefault
+	if o := (Options{}).normalize(); o.Profile.MinLength != d.MinLength || o.Profile.MaxLength != d.MaxLength {
+		t.Errorf("nil Profile normalized to %d-%d, want ProfileDefault", o.Profile.MinLength, o.Profile.MaxLength)
+	}
+}
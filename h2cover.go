@@ -0,0 +1,234 @@
+package padding
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+const (
+	// h2FrameHeaderSize 是 HTTP/2 帧头的长度 (RFC 9113 4.1)
+	h2FrameHeaderSize = 9
+	// h2MaxCoverPayload 是掩护帧负载的上限, 即对端 SETTINGS_MAX_FRAME_SIZE 的最小可能值
+	h2MaxCoverPayload = 16384
+
+	h2FrameHeaders      = 0x1
+	h2FramePing         = 0x6
+	h2FramePushPromise  = 0x5
+	h2FrameContinuation = 0x9
+	h2FlagEndHeaders    = 0x4
+
+	// h2FrameCover 是掩护帧使用的帧类型, 位于保留给实验用途的 0xf0-0xff 范围内
+	// 对端必须忽略并丢弃未知类型的帧 (RFC 9113 5.5)
+	h2FrameCover = 0xf7
+)
+
+// H2CoverOptions 配置 HTTP/2 连接上的掩护流量
+type H2CoverOptions struct {
+	// MinInterval 与 MaxInterval 是两次掩护帧之间的随机间隔范围, 小于等于 0 时分别为 5 秒与 30 秒
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	// Profile 决定每个掩护帧的负载长度, 上限为 16384 字节; 为 nil 时使用 ProfileDefault
+	Profile *PaddingProfile
+	// Ping 为 true 时每次还会发送一个 PING 帧, 对端必须以 PING ACK 应答, 使掩护流量同时出现在两个方向
+	Ping bool
+	// PingOnly 为 true 时只发送 PING 帧 (固定 17 字节), 不发送未知类型的掩护帧
+	// Go 的 http2.Transport 等客户端会为每个未知类型的帧记录一行日志, 面向这类客户端时可以开启
+	PingOnly bool
+}
+
+// normalizeH2Cover 返回补全默认值后的 H2CoverOptions 副本
+func normalizeH2Cover(o H2CoverOptions, logPrefix string) *H2CoverOptions {
+	if o.MinInterval <= 0 {
+		o.MinInterval = 5 * time.Second
+	}
+	if o.MaxInterval <= 0 {
+		o.MaxInterval = 30 * time.Second
+	}
+	if o.MinInterval > o.MaxInterval {
+		o.MinInterval = o.MaxInterval
+	}
+	if o.Profile == nil {
+		o.Profile = &ProfileDefault
+	}
+	o.Profile = normalizeProfile(o.Profile, logPrefix)
+	o.Profile.MaxLength = min(o.Profile.MaxLength, h2MaxCoverPayload)
+	o.Profile.MinLength = min(o.Profile.MinLength, o.Profile.MaxLength)
+	return &o
+}
+
+// H2CoverConn 包装服务端一侧已完成 TLS 握手 (或 h2c) 的 HTTP/2 连接, 在真实响应之间以随机间隔注入
+// 未知类型的掩护帧 (对端会忽略) 与可选的 PING 帧, 使连接层面的流量模式在空闲期间同样带有噪声
+// 注入只发生在出站帧的边界上, 且不会插入头部块 (HEADERS 与其 CONTINUATION) 之间; 关闭返回的连接即停止注入
+// conn 为 *tls.Conn 时, 返回的连接仍提供 ConnectionState, 供 HTTP/2 服务端校验 TLS 参数
+func H2CoverConn(conn net.Conn, opts H2CoverOptions) net.Conn {
+	hc := &h2CoverConn{
+		Conn: conn,
+		opts: normalizeH2Cover(opts, "padding.H2CoverConn"),
+		src:  defaultRandSource,
+		stop: make(chan struct{}),
+	}
+	go hc.run()
+	if tc, ok := conn.(*tls.Conn); ok {
+		return &tlsH2CoverConn{h2CoverConn: hc, tls: tc}
+	}
+	return hc
+}
+
+// ConfigureH2Cover 配置 srv 使用 golang.org/x/net/http2 处理 TLS 上的 HTTP/2 连接, 并为每个连接启用 H2CoverConn
+// 适用于 ListenAndServeTLS / ServeTLS, 可在 touka 的 TLSServerConfigurator 中调用
+func ConfigureH2Cover(srv *http.Server, opts H2CoverOptions) error {
+	h2s := &http2.Server{}
+	if err := http2.ConfigureServer(srv, h2s); err != nil {
+		return err
+	}
+	srv.TLSNextProto[http2.NextProtoTLS] = func(hs *http.Server, c *tls.Conn, h http.Handler) {
+		// net/http 通过 Handler 上未公开的 BaseContext 方法传递连接的基础 context
+		var ctx context.Context
+		if bc, ok := h.(interface{ BaseContext() context.Context }); ok {
+			ctx = bc.BaseContext()
+		}
+		h2s.ServeConn(H2CoverConn(c, opts), &http2.ServeConnOpts{
+			Context:    ctx,
+			Handler:    h,
+			BaseConfig: hs,
+		})
+	}
+	return nil
+}
+
+// h2CoverConn 是 H2CoverConn 返回的连接包装器
+type h2CoverConn struct {
+	net.Conn
+	opts *H2CoverOptions
+	src  RandSource
+
+	mu      sync.Mutex // 串行化上层写入与注入的掩护帧
+	tracker h2FrameTracker
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// tlsH2CoverConn 为底层为 *tls.Conn 的包装器提供 ConnectionState
+type tlsH2CoverConn struct {
+	*h2CoverConn
+	tls *tls.Conn
+}
+
+// ConnectionState 返回底层 TLS 连接的状态
+func (c *tlsH2CoverConn) ConnectionState() tls.ConnectionState {
+	return c.tls.ConnectionState()
+}
+
+// Write 写入上层数据并跟踪出站帧边界
+func (hc *h2CoverConn) Write(p []byte) (int, error) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	n, err := hc.Conn.Write(p)
+	hc.tracker.feed(p[:n])
+	return n, err
+}
+
+// Close 停止注入并关闭底层连接
+func (hc *h2CoverConn) Close() error {
+	hc.closeOnce.Do(func() { close(hc.stop) })
+	return hc.Conn.Close()
+}
+
+// run 以随机间隔注入掩护帧, 直到连接关闭或写入失败
+func (hc *h2CoverConn) run() {
+	for {
+		interval, err := randInt(hc.src, int(hc.opts.MinInterval), int(hc.opts.MaxInterval))
+		if err != nil {
+			interval = int(hc.opts.MaxInterval)
+		}
+		timer := time.NewTimer(time.Duration(interval))
+		select {
+		case <-hc.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if err := hc.inject(); err != nil {
+			return
+		}
+	}
+}
+
+// inject 在出站帧边界处写入一个掩护帧 (及可选的 PING 帧); 不在边界时跳过本次注入
+// 在服务端写出第一个帧 (SETTINGS) 之前不会注入
+func (hc *h2CoverConn) inject() error {
+	length, err := hc.opts.Profile.sample(hc.src)
+	if err != nil {
+		return nil
+	}
+
+	var frames []byte
+	if !hc.opts.PingOnly {
+		frames = appendH2Frame(frames, h2FrameCover, getPaddingSlice(hc.src, length))
+	}
+	if hc.opts.Ping || hc.opts.PingOnly {
+		frames = appendH2Frame(frames, h2FramePing, getPaddingSlice(hc.src, 8))
+	}
+
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	if !hc.tracker.started || !hc.tracker.atBoundary() {
+		return nil
+	}
+	_, err = hc.Conn.Write(frames)
+	return err
+}
+
+// appendH2Frame 向 buf 追加一个流 0 上、不带标志的帧
+func appendH2Frame(buf []byte, frameType byte, payload []byte) []byte {
+	n := len(payload)
+	buf = append(buf, byte(n>>16), byte(n>>8), byte(n), frameType, 0, 0, 0, 0, 0)
+	return append(buf, payload...)
+}
+
+// h2FrameTracker 解析出站字节流中的 HTTP/2 帧头, 用于判断当前是否处于可以插入帧的位置
+type h2FrameTracker struct {
+	header    [h2FrameHeaderSize]byte
+	headerLen int
+	remaining int  // 当前帧尚未写出的负载字节数
+	inBlock   bool // 位于未以 END_HEADERS 结束的头部块中, 此时只能出现 CONTINUATION 帧
+	started   bool // 是否已写出至少一个完整的帧头
+}
+
+// atBoundary 报告已写出的数据是否恰好结束于一个完整帧之后, 且不在头部块中间
+func (t *h2FrameTracker) atBoundary() bool {
+	return t.headerLen == 0 && t.remaining == 0 && !t.inBlock
+}
+
+// feed 消费一段已写出的数据, 更新帧解析状态
+func (t *h2FrameTracker) feed(p []byte) {
+	for len(p) > 0 {
+		if t.remaining > 0 {
+			n := min(len(p), t.remaining)
+			t.remaining -= n
+			p = p[n:]
+			continue
+		}
+		n := copy(t.header[t.headerLen:], p)
+		t.headerLen += n
+		p = p[n:]
+		if t.headerLen < h2FrameHeaderSize {
+			return
+		}
+		t.headerLen = 0
+		t.started = true
+		t.remaining = int(binary.BigEndian.Uint32(t.header[:4]) >> 8)
+		switch frameType, flags := t.header[3], t.header[4]; frameType {
+		case h2FrameHeaders, h2FramePushPromise, h2FrameContinuation:
+			t.inBlock = flags&h2FlagEndHeaders == 0
+		}
+	}
+}
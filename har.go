@@ -0,0 +1,107 @@
+package padding
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// EmpiricalProfile 是从真实流量样本中得到的响应大小经验分布
+// 可以由此构造 Profile, 让 padding 后的流量模仿某个正常网站的大小特征
+type EmpiricalProfile struct {
+	// HeaderSizes 是各个响应头部的大小 (字节), 升序排列
+	HeaderSizes []int
+	// BodySizes 是各个响应体的大小 (字节, 压缩后的传输大小), 升序排列
+	BodySizes []int
+}
+
+// harFile 是 HAR 1.2 格式中 ProfileFromHAR 用到的部分
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Response struct {
+				Headers []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+				HeadersSize int `json:"headersSize"`
+				BodySize    int `json:"bodySize"`
+				Content     struct {
+					Size int `json:"size"`
+				} `json:"content"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// ProfileFromHAR 解析 r 中的 HAR 抓包 (如浏览器开发者工具导出的掩护站点访问记录),
+// 统计其中每个响应的头部与响应体大小, 得到一个经验分布
+// HAR 未给出 headersSize 时按头部列表估算, 未给出 bodySize 时使用 content.size
+func ProfileFromHAR(r io.Reader) (*EmpiricalProfile, error) {
+	var har harFile
+	if err := json.NewDecoder(r).Decode(&har); err != nil {
+		return nil, fmt.Errorf("padding: failed to parse HAR: %w", err)
+	}
+	ep := &EmpiricalProfile{}
+	for _, e := range har.Log.Entries {
+		resp := e.Response
+		headerSize := resp.HeadersSize
+		if headerSize < 0 {
+			headerSize = 0
+			for _, h := range resp.Headers {
+				headerSize += len(h.Name) + len(h.Value) + 4
+			}
+		}
+		if headerSize > 0 {
+			ep.HeaderSizes = append(ep.HeaderSizes, headerSize)
+		}
+		bodySize := resp.BodySize
+		if bodySize < 0 {
+			bodySize = resp.Content.Size
+		}
+		if bodySize >= 0 {
+			ep.BodySizes = append(ep.BodySizes, bodySize)
+		}
+	}
+	if len(ep.HeaderSizes) == 0 && len(ep.BodySizes) == 0 {
+		return nil, errors.New("padding: HAR contains no usable response entries")
+	}
+	slices.Sort(ep.HeaderSizes)
+	slices.Sort(ep.BodySizes)
+	return ep, nil
+}
+
+// HeaderProfile 按头部大小的经验分布构造一个组合 Profile, 可用作 padding 头部的 Profile
+// 样本按分位数划分为 buckets 个等权重的区间 (小于等于 0 时为 8), 每个区间内均匀采样;
+// 超过数据池大小 (4096 字节) 的长度会被截断; 没有样本时返回 nil
+func (ep *EmpiricalProfile) HeaderProfile(buckets int) *PaddingProfile {
+	return empiricalProfile(ep.HeaderSizes, buckets)
+}
+
+// BodyProfile 与 HeaderProfile 相同, 但使用响应体大小的经验分布, 适合作为 HTMLBodyPadding 等响应体 padding 的 Profile
+func (ep *EmpiricalProfile) BodyProfile(buckets int) *PaddingProfile {
+	return empiricalProfile(ep.BodySizes, buckets)
+}
+
+// empiricalProfile 将升序样本按分位数划分为等权重的区间, 构造组合 Profile
+func empiricalProfile(sizes []int, buckets int) *PaddingProfile {
+	if len(sizes) == 0 {
+		return nil
+	}
+	if buckets <= 0 {
+		buckets = 8
+	}
+	buckets = min(buckets, len(sizes))
+	components := make([]WeightedProfile, 0, buckets)
+	for i := range buckets {
+		lo := sizes[i*len(sizes)/buckets]
+		hi := sizes[(i+1)*len(sizes)/buckets-1]
+		components = append(components, WeightedProfile{
+			Profile: &PaddingProfile{MinLength: min(lo, maxPaddingSize), MaxLength: min(hi, maxPaddingSize)},
+			Weight:  1,
+		})
+	}
+	return CompositeProfile(components...)
+}
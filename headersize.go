@@ -0,0 +1,126 @@
+package padding
+
+import "net/http"
+
+// headerSize 返回 h 按 HTTP/1.1 格式 ("Name: value\r\n") 序列化后的字节数
+// exclude 指定的头部不计入, 用于排除即将被覆盖的 padding 头部本身
+func headerSize(h http.Header, exclude string) int {
+	size := 0
+	for name, values := range h {
+		if name == exclude {
+			continue
+		}
+		for _, v := range values {
+			size += len(name) + len(v) + 4
+		}
+	}
+	return size
+}
+
+// fixedHeaderPaddingLength 计算固定头部大小模式下所需的 padding 长度
+// 使 (已有头部 + padding 头部) 的序列化大小恰好等于 TargetHeaderSize,
+// 或向上取整到 HeaderSizeBucket 的整数倍
+// name 是本次使用的 padding 头部名称
+func fixedHeaderPaddingLength(h http.Header, name string, opts *PaddingOptions) int {
+	name = http.CanonicalHeaderKey(name)
+	// padding 头部自身的固定开销: 名称、": " 与 "\r\n"
+	needed := headerSize(h, name) + len(name) + 4
+
+	target := opts.TargetHeaderSize
+	if needed > target && opts.HeaderSizeBucket > 0 {
+		target = roundUp(needed, opts.HeaderSizeBucket)
+	}
+	if target <= needed {
+		return 0
+	}
+	return min(target-needed, maxPaddingSize)
+}
+
+// targetHeaderPaddingLength 返回使 (已有头部 + padding 头部) 的序列化大小等于 target 所需的 padding 长度
+// 已有头部已达到或超过 target 时返回 0
+func targetHeaderPaddingLength(h http.Header, name string, target int) int {
+	name = http.CanonicalHeaderKey(name)
+	needed := headerSize(h, name) + len(name) + 4
+	if target <= needed {
+		return 0
+	}
+	return min(target-needed, maxPaddingSize)
+}
+
+// 常见下游组件的默认头部大小上限, 用于 MaxHeaderBytes
+const (
+	// HeaderLimitNginx 是 nginx 反向代理默认的响应头缓冲区大小 (proxy_buffer_size, 按 8K 计)
+	HeaderLimitNginx = 8 << 10
+	// HeaderLimitALB 是 AWS Application Load Balancer 的头部大小上限
+	HeaderLimitALB = 16 << 10
+	// HeaderLimitGo 是 net/http 服务端默认的 MaxHeaderBytes (http.DefaultMaxHeaderBytes)
+	HeaderLimitGo = 1 << 20
+)
+
+// headerLimitReserve 是 MaxHeaderBytes 中为之后才添加的头部 (Date、Content-Length、
+// 起始行等) 预留的字节数
+const headerLimitReserve = 256
+
+// capHeaderPadding 将名为 name 的头部上的 padding 长度 n 限制在 MaxHeaderBytes 允许的范围内,
+// 使 (已有头部 + 该头部) 的序列化大小加上预留量不超过上限; 未设置 MaxHeaderBytes 时原样返回
+func capHeaderPadding(h http.Header, name string, n int, opts *PaddingOptions) int {
+	if opts.MaxHeaderBytes <= 0 || n <= 0 {
+		return n
+	}
+	room := opts.MaxHeaderBytes - headerLimitReserve - headerSize(h, http.CanonicalHeaderKey(name)) - len(name) - 4
+	return max(min(n, room), 0)
+}
+
+// roundUp 将 n 向上取整到 multiple 的整数倍
+func roundUp(n, multiple int) int {
+	if multiple <= 0 {
+		return n
+	}
+	return (n + multiple - 1) / multiple * multiple
+}
+
+// randomizeHeaderCase 将 h 中名为 name 的头部改存为随机大小写的键
+// net/http 按键的原样写出 HTTP/1.1 头部, 因此线上的名称与排序位置都会随之变化
+func randomizeHeaderCase(h http.Header, name string, src RandSource) {
+	key := http.CanonicalHeaderKey(name)
+	values, ok := h[key]
+	if !ok {
+		return
+	}
+	mask := make([]byte, len(key))
+	if _, err := src.Read(mask); err != nil {
+		return
+	}
+	buf := []byte(key)
+	for i, c := range buf {
+		if mask[i]&1 == 0 {
+			continue
+		}
+		switch {
+		case 'a' <= c && c <= 'z':
+			buf[i] = c - ('a' - 'A')
+		case 'A' <= c && c <= 'Z':
+			buf[i] = c + ('a' - 'A')
+		}
+	}
+	delete(h, key)
+	h[string(buf)] = values
+}
+
+// statusLineSize 是 HTTP/1.1 起始行的估计大小 (如 "HTTP/1.1 200 OK\r\n")
+const statusLineSize = 17
+
+// alignedPaddingLength 计算首包对齐模式下所需的 padding 长度
+// 首段数据为起始行、头部块 (含结尾空行) 与已知长度的消息体, 补足到 boundary 的整数倍;
+// 超过一个边界时首个记录已满, 返回 0; 所需长度超过数据池大小时截断, 此时只能尽量接近边界
+func alignedPaddingLength(h http.Header, name string, contentLen int64, boundary int) int {
+	name = http.CanonicalHeaderKey(name)
+	head := statusLineSize + headerSize(h, name) + len(name) + 4 + 2
+	if contentLen > 0 {
+		head += int(min(contentLen, int64(boundary)))
+	}
+	if head >= boundary {
+		return 0
+	}
+	return min(boundary-head, maxPaddingSize)
+}
@@ -0,0 +1,53 @@
+package padding
+
+import (
+	"net/http"
+	"strings"
+)
+
+// normalizeHostProfiles 返回键名统一为小写、Profile 经过校验的映射副本
+func normalizeHostProfiles(m map[string]*PaddingProfile, logPrefix string) map[string]*PaddingProfile {
+	out := make(map[string]*PaddingProfile, len(m))
+	for k, p := range m {
+		if p == nil {
+			continue
+		}
+		out[strings.ToLower(strings.TrimSuffix(strings.TrimSpace(k), "."))] = normalizeProfile(p, logPrefix)
+	}
+	return out
+}
+
+// profileForHost 按主机名查找 Profile: 先精确匹配, 再由近及远匹配通配符
+// (如 "a.b.example.com" 依次尝试 "*.b.example.com"、"*.example.com"、"*.com"), 最后匹配 "*"
+// 通配符 "*.example.com" 只匹配子域名, 不匹配 "example.com" 本身; 均未命中时返回 nil
+func profileForHost(m map[string]*PaddingProfile, host string) *PaddingProfile {
+	if len(m) == 0 || host == "" {
+		return nil
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if p, ok := m[host]; ok {
+		return p
+	}
+	for rest := host; ; {
+		_, after, ok := strings.Cut(rest, ".")
+		if !ok {
+			break
+		}
+		if p, ok := m["*."+after]; ok {
+			return p
+		}
+		rest = after
+	}
+	return m["*"]
+}
+
+// requestProfile 为出站请求选择 Profile: 优先按目标主机匹配 ProfileByHost, 其次按协议提示匹配 ProfileByProtocol, 否则使用 Profile
+// 设置了 Upstream 且请求的 context 来自 UpstreamContext 时, 再按入站请求的 padding 长度调整
+func requestProfile(req *http.Request, opts *PaddingOptions) *PaddingProfile {
+	if req.URL != nil {
+		if p := profileForHost(opts.ProfileByHost, req.URL.Hostname()); p != nil {
+			return upstreamProfile(req, sessionProfile(req, p, opts), opts)
+		}
+	}
+	return upstreamProfile(req, sessionProfile(req, opts.protocolProfile(outboundProtocol(req), opts.now()), opts), opts)
+}
@@ -0,0 +1,56 @@
+package padding
+
+import (
+	"bytes"
+
+	"golang.org/x/net/http2/hpack"
+)
+
+// huffmanBits 记录每个字节在 HPACK/QPACK 静态 Huffman 表中的编码位数
+// 将同一字节重复 8 次后, 其编码长度 (字节) 恰好等于单个字节的编码位数
+var huffmanBits = func() (table [256]int) {
+	for i := range table {
+		table[i] = int(hpack.HuffmanEncodeLength(string(bytes.Repeat([]byte{byte(i)}, 8))))
+	}
+	return table
+}()
+
+// wirePaddingSlice 从数据池 pool (nil 时为默认数据池) 中获取一个切片, 使其作为头部值在 HPACK/QPACK 中
+// 编码后的长度达到 target 字节; 若整个数据池都不足以达到目标, 返回能取到的最长切片
+// 编码器只在 Huffman 编码更短时才使用它, 因此编码长度取原始长度与 Huffman 长度的较小值
+func wirePaddingSlice(src RandSource, target int, pool *Pool) []byte {
+	if target <= 0 {
+		return nil
+	}
+	d := pool.load()
+	data := d.b
+	if d.enc != nil {
+		// 编码在取出时进行, 先取出一段最长的编码结果再从头扫描
+		data = d.slice(src, maxPaddingSize)
+		b, _ := scanWirePadding(data, 0, target)
+		return b
+	}
+	start, err := randInt(src, 0, len(data)-1)
+	if err != nil {
+		start = 0 // 保证功能可用性
+	}
+	if b, ok := scanWirePadding(data, start, target); ok {
+		return b
+	}
+	b, _ := scanWirePadding(data, 0, target)
+	return b
+}
+
+// scanWirePadding 从 data[start] 开始向后累积编码位数, 直到编码长度达到 target
+// 切片长度不超过 maxPaddingSize
+func scanWirePadding(data []byte, start, target int) ([]byte, bool) {
+	bits := 0
+	end := min(len(data), start+maxPaddingSize)
+	for i := start; i < end; i++ {
+		bits += huffmanBits[data[i]]
+		if min((bits+7)/8, i-start+1) >= target {
+			return data[start : i+1], true
+		}
+	}
+	return data[start:end], false
+}
@@ -0,0 +1,141 @@
+package padding
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// JSONPaddingMode 决定 JSON 响应体 padding 的注入方式
+type JSONPaddingMode int
+
+const (
+	// JSONPaddingField 向顶层对象注入一个会被忽略的字段 (如 "_padding": "...")
+	// 需要缓冲整个响应体; 响应体不是对象或超过 MaxBufferSize 时退化为 JSONPaddingWhitespace
+	JSONPaddingField JSONPaddingMode = iota
+	// JSONPaddingWhitespace 在响应体末尾追加空白字符, 任何 JSON 解析器都会忽略它们
+	JSONPaddingWhitespace
+)
+
+// JSONPaddingOptions 配置 application/json 响应的响应体 padding
+type JSONPaddingOptions struct {
+	Mode JSONPaddingMode
+	// FieldName 是 JSONPaddingField 模式下注入的字段名, 默认为 "_padding"
+	FieldName string
+	// Profile 决定 padding 的长度, 为 nil 时使用 ProfileShort
+	Profile *PaddingProfile
+	// MaxBufferSize 是 JSONPaddingField 模式下缓冲响应体的上限 (字节), 小于等于 0 时为 64KB
+	MaxBufferSize int
+}
+
+// normalizeJSONPadding 返回补全默认值后的 JSONPaddingOptions 副本
+func normalizeJSONPadding(j JSONPaddingOptions, logPrefix string) *JSONPaddingOptions {
+	if j.FieldName == "" {
+		j.FieldName = "_padding"
+	}
+	if j.Profile == nil {
+		j.Profile = &ProfileShort
+	}
+	j.Profile = normalizeProfile(j.Profile, logPrefix)
+	if j.MaxBufferSize <= 0 {
+		j.MaxBufferSize = 64 << 10
+	}
+	return &j
+}
+
+// isJSONMediaType 报告 mt 是否为 JSON 媒体类型 (application/json 或 +json 后缀)
+func isJSONMediaType(mt string) bool {
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+// jsonInjector 缓冲 JSON 响应体, 在响应结束时向顶层对象注入 padding 字段
+type jsonInjector struct {
+	opts   *JSONPaddingOptions
+	src    RandSource
+	length int
+	buf    bytes.Buffer
+}
+
+// prepareJSONPadding 在 WriteHeader 中调用, 按模式准备 JSON 响应体 padding
+func (prw *paddingResponseWriter) prepareJSONPadding(length int) {
+	if prw.opts.JSONBodyPadding.Mode == JSONPaddingField {
+		prw.json = &jsonInjector{opts: prw.opts.JSONBodyPadding, src: prw.opts.Rand, length: length}
+		return
+	}
+	prw.bodyPadding = whitespaceFiller(length)
+}
+
+// bufferJSON 缓冲一段响应体数据, 超过上限时放弃注入并改为追加空白, 调用方需持有 writeMu
+func (prw *paddingResponseWriter) bufferJSON(data []byte) (int, error) {
+	prw.json.buf.Write(data)
+	if prw.json.buf.Len() <= prw.json.opts.MaxBufferSize {
+		return len(data), nil
+	}
+	if err := prw.abandonJSON(); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// abandonJSON 写出已缓冲的数据并退化为空白 padding, 调用方需持有 writeMu
+func (prw *paddingResponseWriter) abandonJSON() error {
+	inj := prw.json
+	prw.json = nil
+	prw.bodyPadding = whitespaceFiller(inj.length)
+	_, err := prw.writeBody(inj.buf.Bytes())
+	return err
+}
+
+// flushJSON 在响应结束时注入 padding 字段并写出缓冲的响应体, 调用方需持有 writeMu
+func (prw *paddingResponseWriter) flushJSON() error {
+	inj := prw.json
+	prw.json = nil
+	body, ok := inj.inject()
+	if !ok {
+		prw.bodyPadding = whitespaceFiller(inj.length)
+	}
+	_, err := prw.writeBody(body)
+	return err
+}
+
+// inject 返回注入了 padding 字段的响应体; 响应体不是 JSON 对象时返回原数据与 false
+func (inj *jsonInjector) inject() ([]byte, bool) {
+	body := inj.buf.Bytes()
+	open := skipJSONSpace(body, 0)
+	if open >= len(body) || body[open] != '{' {
+		return body, false
+	}
+	name, err := json.Marshal(inj.opts.FieldName)
+	if err != nil {
+		return body, false
+	}
+	value, err := json.Marshal(string(getPaddingSlice(inj.src, inj.length)))
+	if err != nil {
+		return body, false
+	}
+
+	out := make([]byte, 0, len(body)+len(name)+len(value)+2)
+	out = append(out, body[:open+1]...)
+	out = append(out, name...)
+	out = append(out, ':')
+	out = append(out, value...)
+	// 空对象 "{}" 注入后不能带逗号
+	if next := skipJSONSpace(body, open+1); next < len(body) && body[next] != '}' {
+		out = append(out, ',')
+	}
+	out = append(out, body[open+1:]...)
+	return out, true
+}
+
+// skipJSONSpace 返回 data 中从 i 开始第一个非 JSON 空白字符的位置
+func skipJSONSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch data[i] {
+		case ' ', '\t', '\n', '\r':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
@@ -0,0 +1,52 @@
+package padding
+
+import (
+	"sync"
+
+	"github.com/infinite-iroha/touka"
+)
+
+// lengthKey 是 ToukaPaddingS 在 Context 中记录本次响应 padding 决定所用的键
+const lengthKey = "padding.length"
+
+// Length 描述服务端中间件为当前响应做出的 padding 决定
+type Length struct {
+	// Header 是所有 padding 头部值的总长度 (不含诱饵头部)
+	Header int
+	// Body 是响应体 padding 的长度; JSON 字段注入模式下为注入前预定的长度
+	Body int
+}
+
+// lengthRecord 保存一次响应的 padding 决定, 由 WriteHeader 写入、处理函数读取
+type lengthRecord struct {
+	mu      sync.Mutex
+	length  Length
+	decided bool
+}
+
+func (r *lengthRecord) set(l Length) {
+	r.mu.Lock()
+	r.length, r.decided = l, true
+	r.mu.Unlock()
+}
+
+func (r *lengthRecord) get() (Length, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.length, r.decided
+}
+
+// LengthFromContext 返回 ToukaPaddingS 为当前响应做出的 padding 决定
+// 决定在响应头部写出 (WriteHeader 或第一次 Write) 时才会产生, 在此之前或未经过该中间件时 ok 为 false;
+// 被跳过的请求与状态码会得到零值的 Length 与 true
+func LengthFromContext(c *touka.Context) (l Length, ok bool) {
+	v, exists := c.Get(lengthKey)
+	if !exists {
+		return Length{}, false
+	}
+	r, _ := v.(*lengthRecord)
+	if r == nil {
+		return Length{}, false
+	}
+	return r.get()
+}
@@ -0,0 +1,109 @@
+package padding
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// LoadControllerOptions 配置自适应降载控制器
+type LoadControllerOptions struct {
+	// HighRate 是视为饱和的请求速率 (次/秒), 大于 0 时控制器以内部 EWMA 估计请求速率,
+	// 并以 rate / HighRate 作为负载; 为 0 时只使用 SetLoad 提供的外部负载
+	HighRate float64
+	// Window 是请求速率 EWMA 的时间常数, 小于等于 0 时为 10 秒
+	Window time.Duration
+	// LowWatermark 是开始降低 padding 的负载值, 取值 (0, 1); 为 0 或超出范围时为 0.5
+	// 负载低于该值时 padding 保持原样, 达到 1 时缩小到 MinScale, 中间线性过渡
+	LowWatermark float64
+	// MinScale 是饱和时 padding 长度的缩放比例, 取值 (0, 1]; 为 0 或超出范围时为 0.25
+	// 需要饱和时完全不添加 padding 时设置 DropAtSaturation
+	MinScale float64
+	// DropAtSaturation 为 true 时饱和时的缩放比例为 0 (忽略 MinScale), 负载接近饱和时 padding 逐渐缩小直至消失
+	DropAtSaturation bool
+}
+
+// LoadController 根据服务负载动态缩小 padding 长度, 负载下降后自动恢复
+// 负载来自内部的请求速率 EWMA 与 SetLoad 提供的外部信号 (CPU、延迟、QPS 等) 中的较大者
+// 一个 LoadController 可以被多个中间件实例共享
+type LoadController struct {
+	opts LoadControllerOptions
+
+	mu       sync.Mutex
+	rate     float64   // 请求速率的 EWMA 估计 (次/秒)
+	last     time.Time // 上一次更新 rate 的时间
+	external float64   // SetLoad 提供的外部负载
+}
+
+// NewLoadController 创建一个 LoadController
+func NewLoadController(opts LoadControllerOptions) *LoadController {
+	if opts.Window <= 0 {
+		opts.Window = 10 * time.Second
+	}
+	if opts.LowWatermark <= 0 || opts.LowWatermark >= 1 {
+		opts.LowWatermark = 0.5
+	}
+	switch {
+	case opts.DropAtSaturation:
+		opts.MinScale = 0
+	case opts.MinScale <= 0 || opts.MinScale > 1:
+		opts.MinScale = 0.25
+	}
+	return &LoadController{opts: opts}
+}
+
+// SetLoad 设置外部负载信号, 取值 [0, 1], 1 表示饱和; 超出范围的值会被截断
+func (lc *LoadController) SetLoad(load float64) {
+	lc.mu.Lock()
+	lc.external = math.Max(0, math.Min(1, load))
+	lc.mu.Unlock()
+}
+
+// Load 返回当前负载, 1 表示饱和
+func (lc *LoadController) Load() float64 {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	load := lc.external
+	if lc.opts.HighRate > 0 {
+		load = math.Max(load, lc.decayedRate(time.Now())/lc.opts.HighRate)
+	}
+	return math.Min(load, 1)
+}
+
+// Scale 返回当前 padding 长度的缩放比例, 取值 [MinScale, 1]
+func (lc *LoadController) Scale() float64 {
+	load, low := lc.Load(), lc.opts.LowWatermark
+	if load <= low {
+		return 1
+	}
+	return 1 - (1-lc.opts.MinScale)*(load-low)/(1-low)
+}
+
+// observe 记录一次请求, 更新请求速率估计
+func (lc *LoadController) observe() {
+	if lc.opts.HighRate <= 0 {
+		return
+	}
+	now := time.Now()
+	lc.mu.Lock()
+	lc.rate = lc.decayedRate(now) + 1/lc.opts.Window.Seconds()
+	lc.last = now
+	lc.mu.Unlock()
+}
+
+// decayedRate 返回按 now 衰减后的请求速率估计, 调用方需持有 mu
+func (lc *LoadController) decayedRate(now time.Time) float64 {
+	if lc.last.IsZero() {
+		return 0
+	}
+	dt := now.Sub(lc.last).Seconds()
+	return lc.rate * math.Exp(-dt/lc.opts.Window.Seconds())
+}
+
+// scaleLength 按当前负载缩放 padding 长度, lc 为 nil 时原样返回
+func (lc *LoadController) scaleLength(n int) int {
+	if lc == nil || n <= 0 {
+		return n
+	}
+	return int(math.Round(float64(n) * lc.Scale()))
+}
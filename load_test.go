@@ -0,0 +1,41 @@
+package padding
+
+import "testing"
+
+func TestLoadControllerZeroValueDefaults(t *testing.T) {
+	lc := NewLoadController(LoadControllerOptions{})
+	for _, c := range []struct {
+		load, scale float64
+	}{
+		{0, 1},
+		{0.5, 1},
+		{0.75, 0.625},
+		{1, 0.25},
+	} {
+		lc.SetLoad(c.load)
+		if got := lc.Scale(); got != c.scale {
+			t.Errorf("load %g: Scale() = %g, want %g", c.load, got, c.scale)
+		}
+	}
+
+	lc = NewLoadController(LoadControllerOptions{HighRate: 100})
+	if got := lc.Scale(); got != 1 {
+		t.Errorf("idle controller with only HighRate: Scale() = %g, want 1", got)
+	}
+	lc.SetLoad(1)
+	if got := lc.Scale(); got != 0.25 {
+		t.Errorf("saturated controller with only HighRate: Scale() = %g, want 0.25", got)
+	}
+}
+
+func TestLoadControllerDropAtSaturation(t *testing.T) {
+	lc := NewLoadController(LoadControllerOptions{MinScale: 0.8, DropAtSaturation: true})
+	lc.SetLoad(1)
+	if got := lc.Scale(); got != 0 {
+		t.Errorf("Scale() = %g at saturation, want 0", got)
+	}
+	lc.SetLoad(0.75)
+	if got := lc.Scale(); got != 0.5 {
+		t.Errorf("Scale() = %g at load 0.75, want 0.5", got)
+	}
+}
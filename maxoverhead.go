@@ -0,0 +1,22 @@
+package padding
+
+// overheadLimit 返回大小为 size 的响应按 MaxOverheadPercent 允许的 padding 总长度 (头部与响应体之和):
+// size 的 MaxOverheadPercent%, 但不低于 floor, 使小响应仍能得到 Profile 的最小长度
+// 未设置 MaxOverheadPercent 或大小未知时 ok 为 false
+func overheadLimit(size int64, floor int, opts *PaddingOptions) (limit int, ok bool) {
+	if opts.MaxOverheadPercent <= 0 || size < 0 {
+		return 0, false
+	}
+	limit = int(min(float64(size)*opts.MaxOverheadPercent/100, maxPaddingSize))
+	return max(limit, floor), true
+}
+
+// overheadProfile 在响应大小已知时将 profile 限制在 MaxOverheadPercent 允许的长度以内, 并返回该长度;
+// 下限取 profile 的 MinLength; 无需限制时原样返回 profile, limit 为 -1
+func overheadProfile(profile *PaddingProfile, size int64, opts *PaddingOptions) (*PaddingProfile, int) {
+	limit, ok := overheadLimit(size, profile.MinLength, opts)
+	if !ok {
+		return profile, -1
+	}
+	return limitProfile(profile, limit), limit
+}
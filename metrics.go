@@ -0,0 +1,83 @@
+package padding
+
+import (
+	"expvar"
+	"strconv"
+	"time"
+)
+
+// Metrics 是 padding 计数与分布的导出接口, 用于接入宿主应用已有的遥测系统
+// 包内提供 expvar 实现 (NewExpvarMetrics), OpenTelemetry 实现位于 otelpad 子包; 方法可能被并发调用
+type Metrics interface {
+	// RecordPadding 在每次为请求或响应做出 padding 头部决策后调用, e.Length 为 0 表示未添加头部
+	RecordPadding(e PaddingEvent)
+	// RecordBodyPadding 在为响应体添加 n 字节 padding 时调用
+	RecordBodyPadding(n int)
+	// RecordSkip 在一次请求 (或响应) 被跳过时调用
+	RecordSkip()
+}
+
+// DelayMetrics 是 Metrics 可选实现的扩展接口, 用于观察注入的延迟
+// 实现了该接口的 Metrics 会在每次注入延迟 (Strategy 的 Decision.Delay 或恒定速率整形的等待) 前收到
+// 请求的延迟与按截止时间截断后实际使用的延迟
+type DelayMetrics interface {
+	RecordDelay(requested, actual time.Duration)
+}
+
+// expvarMetrics 以 expvar.Map 导出 padding 计数
+type expvarMetrics struct {
+	m       *expvar.Map
+	buckets *expvar.Map
+}
+
+// NewExpvarMetrics 创建一个以 expvar 导出的 Metrics, 在 /debug/vars 中以 name 发布为一个 Map:
+// requests、responses、skipped、header_bytes、body_bytes、delay_ns (累计注入延迟)、delays_clamped、backoffs (Backoff 的回退次数) 计数, 以及按 Stats 相同上界分桶的 header_length 直方图
+// 同一进程内 name 不能重复发布, 否则 expvar 会 panic
+func NewExpvarMetrics(name string) Metrics {
+	m := expvar.NewMap(name)
+	buckets := new(expvar.Map).Init()
+	m.Set("header_length", buckets)
+	return &expvarMetrics{m: m, buckets: buckets}
+}
+
+func (em *expvarMetrics) RecordPadding(e PaddingEvent) {
+	if e.Direction == DirectionResponse {
+		em.m.Add("responses", 1)
+	} else {
+		em.m.Add("requests", 1)
+	}
+	if e.Length <= 0 {
+		return
+	}
+	em.m.Add("header_bytes", int64(e.Length))
+	em.buckets.Add(histogramLabel(e.Length), 1)
+}
+
+func (em *expvarMetrics) RecordBodyPadding(n int) {
+	em.m.Add("body_bytes", int64(n))
+}
+
+func (em *expvarMetrics) RecordSkip() {
+	em.m.Add("skipped", 1)
+}
+
+func (em *expvarMetrics) RecordBackoff(e BackoffEvent) {
+	em.m.Add("backoffs", 1)
+}
+
+func (em *expvarMetrics) RecordDelay(requested, actual time.Duration) {
+	em.m.Add("delay_ns", int64(actual))
+	if actual < requested {
+		em.m.Add("delays_clamped", 1)
+	}
+}
+
+// histogramLabel 返回长度 n 所在直方图桶的标签 (上界, 溢出桶为 "+Inf")
+func histogramLabel(n int) string {
+	for _, bound := range histogramBounds {
+		if n <= bound {
+			return strconv.Itoa(bound)
+		}
+	}
+	return "+Inf"
+}
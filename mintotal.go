@@ -0,0 +1,81 @@
+package padding
+
+import (
+	"net/http"
+	"strings"
+)
+
+// prepareMinTotal 在 WriteHeader 中调用, 使响应的总大小不低于 MinTotalSize
+// 响应体长度已知时立即加长 padding 头部, 否则为 text/* 与 JSON 响应体设置响应结束时的补齐目标;
+// 其他类型按空响应体计算并加长头部
+func (prw *paddingResponseWriter) prepareMinTotal(statusCode int) {
+	floor := prw.opts.MinTotalSize
+	if floor <= 0 {
+		return
+	}
+	h := prw.Header()
+	body := int64(0)
+	if bodyAllowed(prw.req.Method, statusCode) {
+		body = responseContentLength(h)
+	}
+	if body < 0 && (partialContent(statusCode) || encoded(h) || !uniformFillable(mediaType(h))) {
+		// 响应体长度未知且无法在末尾补齐, 按空响应体加长头部, 总大小至少达到下限
+		body = 0
+	}
+	if body >= 0 {
+		if deficit := floor - headerSize(h, "") - int(body); deficit > 0 {
+			n := extendPaddingHeader(h, deficit, prw.opts)
+			prw.stats.recordHeader(n)
+		}
+		return
+	}
+	if int64(floor) > prw.fillTarget {
+		prw.fillTarget = int64(floor)
+		prw.fillMax = floor
+	}
+}
+
+// extendPaddingHeader 将 padding 头部加长 deficit 字节 (不存在时新建), 返回增加的 padding 长度
+// 受数据池大小与 MaxHeaderBytes 限制, 实际增加的长度可能不足 deficit
+func extendPaddingHeader(h http.Header, deficit int, opts *PaddingOptions) int {
+	now := opts.now()
+	name, key := "", ""
+	for _, n := range opts.headerNames(now) {
+		if key = headerKey(h, n); key != "" {
+			name = n
+			break
+		}
+	}
+	if key == "" {
+		name = opts.pickHeaderName(now)
+	}
+	current := 0
+	if key != "" {
+		if values := h[key]; len(values) > 0 {
+			current = len(values[0])
+		}
+	} else {
+		key = http.CanonicalHeaderKey(name)
+		// 新建的头部自身还有名称、": " 与 "\r\n" 的开销
+		deficit -= len(key) + 4
+	}
+	length := capHeaderPadding(h, key, min(current+max(deficit, 1), maxPaddingSize), opts)
+	if length <= current {
+		return 0
+	}
+	h[key] = []string{paddingValue(length, opts)}
+	return length - current
+}
+
+// headerKey 返回 h 中与 name 不区分大小写相同的键 (RandomizeHeaderCase 可能使用非规范形式), 不存在时返回 ""
+func headerKey(h http.Header, name string) string {
+	if _, ok := h[http.CanonicalHeaderKey(name)]; ok {
+		return http.CanonicalHeaderKey(name)
+	}
+	for key := range h {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+	return ""
+}
@@ -0,0 +1,5 @@
+//go:build !race
+
+package padding
+
+const raceEnabled = false
@@ -0,0 +1,165 @@
+package padding
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// ObserveOptions 配置 Observer
+type ObserveOptions struct {
+	// Samples 是观察阶段需要记录的响应数, 小于等于 0 时为 1000
+	Samples int
+	// Duration 大于 0 时, 观察阶段最长持续该时间, 到期时即使样本不足也会结束
+	Duration time.Duration
+	// OnRecommendation 在观察阶段结束时以推荐结果调用一次, 可以为 nil
+	OnRecommendation func(rec Recommendation) `json:"-"`
+}
+
+// SizeSummary 是一组大小样本的统计摘要 (字节)
+type SizeSummary struct {
+	Min int `json:"min"`
+	P10 int `json:"p10"`
+	P50 int `json:"p50"`
+	P90 int `json:"p90"`
+	P99 int `json:"p99"`
+	Max int `json:"max"`
+}
+
+// Recommendation 是 Observer 根据观察到的真实响应大小给出的配置建议
+type Recommendation struct {
+	Samples int         `json:"samples"`
+	Header  SizeSummary `json:"header"` // 响应头部大小
+	Body    SizeSummary `json:"body"`   // 响应体大小
+	Total   SizeSummary `json:"total"`  // 头部与响应体大小之和
+	// Profile 是推荐的 padding 头部 Profile: MaxLength 取总大小的 P10 到 P90 的跨度 (限制在 [64, 4096]),
+	// 使大多数响应的大小差异能够被 padding 覆盖; MinLength 取 MaxLength 的 1/8
+	Profile PaddingProfile `json:"profile"`
+	// Empirical 是观察到的经验分布, 可用 HeaderProfile/BodyProfile 构造模仿该分布的组合 Profile
+	Empirical *EmpiricalProfile `json:"-"`
+}
+
+// Observer 实现被动的观察模式: 观察阶段内服务端中间件不添加 padding, 只记录真实响应的头部与响应体大小,
+// 阶段结束后给出推荐的 Profile (通过 OnRecommendation 与 AdminHandler 输出), 随后恢复正常 padding
+// 通过 PaddingOptions.Observe 安装
+type Observer struct {
+	opts     ObserveOptions
+	deadline time.Time
+
+	mu      sync.Mutex
+	headers []int
+	bodies  []int
+	done    bool
+	rec     *Recommendation
+}
+
+// NewObserver 创建一个 Observer, 观察阶段从创建时开始
+func NewObserver(opts ObserveOptions) *Observer {
+	if opts.Samples <= 0 {
+		opts.Samples = 1000
+	}
+	o := &Observer{opts: opts}
+	if opts.Duration > 0 {
+		o.deadline = time.Now().Add(opts.Duration)
+	}
+	return o
+}
+
+// observing 报告观察阶段是否仍在进行, 到期时结束观察阶段; o 为 nil 时返回 false
+func (o *Observer) observing() bool {
+	if o == nil {
+		return false
+	}
+	o.mu.Lock()
+	if o.done {
+		o.mu.Unlock()
+		return false
+	}
+	if o.deadline.IsZero() || time.Now().Before(o.deadline) {
+		o.mu.Unlock()
+		return true
+	}
+	rec := o.finishLocked()
+	o.mu.Unlock()
+	o.emit(rec)
+	return false
+}
+
+// record 记录一个未填充响应的头部与响应体大小, 样本足够时结束观察阶段
+func (o *Observer) record(headerSize, bodySize int) {
+	o.mu.Lock()
+	if o.done {
+		o.mu.Unlock()
+		return
+	}
+	o.headers = append(o.headers, headerSize)
+	o.bodies = append(o.bodies, bodySize)
+	if len(o.headers) < o.opts.Samples {
+		o.mu.Unlock()
+		return
+	}
+	rec := o.finishLocked()
+	o.mu.Unlock()
+	o.emit(rec)
+}
+
+// finishLocked 结束观察阶段并计算推荐结果, 调用方需持有 o.mu
+func (o *Observer) finishLocked() *Recommendation {
+	o.done = true
+	ep := &EmpiricalProfile{HeaderSizes: slices.Clone(o.headers), BodySizes: slices.Clone(o.bodies)}
+	totals := make([]int, len(o.headers))
+	for i := range totals {
+		totals[i] = o.headers[i] + o.bodies[i]
+	}
+	slices.Sort(ep.HeaderSizes)
+	slices.Sort(ep.BodySizes)
+	slices.Sort(totals)
+	rec := &Recommendation{
+		Samples:   len(totals),
+		Header:    summarize(ep.HeaderSizes),
+		Body:      summarize(ep.BodySizes),
+		Total:     summarize(totals),
+		Empirical: ep,
+	}
+	spread := min(max(rec.Total.P90-rec.Total.P10, 64), maxPaddingSize)
+	rec.Profile = PaddingProfile{MinLength: spread / 8, MaxLength: spread}
+	o.headers, o.bodies = nil, nil
+	o.rec = rec
+	return rec
+}
+
+// emit 在观察阶段结束时调用 OnRecommendation
+func (o *Observer) emit(rec *Recommendation) {
+	if o.opts.OnRecommendation != nil {
+		o.opts.OnRecommendation(*rec)
+	}
+}
+
+// Recommendation 返回观察阶段的推荐结果, 观察阶段尚未结束时 ok 为 false
+func (o *Observer) Recommendation() (rec Recommendation, ok bool) {
+	o.observing()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.rec == nil {
+		return Recommendation{}, false
+	}
+	return *o.rec, true
+}
+
+// summarize 计算升序样本的统计摘要
+func summarize(sorted []int) SizeSummary {
+	if len(sorted) == 0 {
+		return SizeSummary{}
+	}
+	q := func(p float64) int {
+		return sorted[min(int(p*float64(len(sorted))), len(sorted)-1)]
+	}
+	return SizeSummary{
+		Min: sorted[0],
+		P10: q(0.10),
+		P50: q(0.50),
+		P90: q(0.90),
+		P99: q(0.99),
+		Max: sorted[len(sorted)-1],
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2025 Infinite-Iroha. All rights reserved.
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+// Package otelpad 将 padding 的计数与分布通过 OpenTelemetry Meter 导出
+package otelpad
+
+import (
+	"context"
+	"time"
+
+	"github.com/fenthope/padding"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// metrics 是基于 OpenTelemetry Meter 的 padding.Metrics 实现
+type metrics struct {
+	messages metric.Int64Counter
+	skipped  metric.Int64Counter
+	bytes    metric.Int64Counter
+	length   metric.Int64Histogram
+	delay    metric.Float64Histogram
+	clamped  metric.Int64Counter
+	backoffs metric.Int64Counter
+}
+
+var (
+	attrRequest  = metric.WithAttributes(attribute.String("direction", "request"))
+	attrResponse = metric.WithAttributes(attribute.String("direction", "response"))
+	attrHeader   = attribute.String("carrier", "header")
+	attrBody     = metric.WithAttributes(attribute.String("carrier", "body"), attribute.String("direction", "response"))
+)
+
+// NewMetrics 使用 meter 创建一个 padding.Metrics, 导出以下指标:
+// padding.messages (做出 padding 决策的消息数, 按 direction 区分)、padding.skipped (跳过数)、
+// padding.bytes (padding 字节数, 按 direction 与 carrier 区分)、padding.header.length (padding 头部长度的直方图)、
+// padding.delay (实际注入的延迟)、padding.delay.clamped (因截止时间被截断的延迟数)
+// 与 padding.backoffs (Backoff 因 431、413 等响应降低主机上限的次数, 按 status 区分)
+func NewMetrics(meter metric.Meter) (padding.Metrics, error) {
+	messages, err := meter.Int64Counter("padding.messages",
+		metric.WithDescription("Messages for which a padding decision was made"))
+	if err != nil {
+		return nil, err
+	}
+	skipped, err := meter.Int64Counter("padding.skipped",
+		metric.WithDescription("Requests or responses sent without padding because of skip rules"))
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := meter.Int64Counter("padding.bytes",
+		metric.WithDescription("Padding bytes added"), metric.WithUnit("By"))
+	if err != nil {
+		return nil, err
+	}
+	length, err := meter.Int64Histogram("padding.header.length",
+		metric.WithDescription("Length of padding header values"), metric.WithUnit("By"),
+		metric.WithExplicitBucketBoundaries(64, 128, 256, 512, 1024, 2048, 4096))
+	if err != nil {
+		return nil, err
+	}
+	delay, err := meter.Float64Histogram("padding.delay",
+		metric.WithDescription("Injected delay after clamping to request deadlines"), metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	clamped, err := meter.Int64Counter("padding.delay.clamped",
+		metric.WithDescription("Injected delays shortened to respect request deadlines"))
+	if err != nil {
+		return nil, err
+	}
+	backoffs, err := meter.Int64Counter("padding.backoffs",
+		metric.WithDescription("Per-host padding limit reductions after 431, 413 or similar responses"))
+	if err != nil {
+		return nil, err
+	}
+	return &metrics{messages: messages, skipped: skipped, bytes: bytes, length: length, delay: delay, clamped: clamped, backoffs: backoffs}, nil
+}
+
+func (m *metrics) RecordPadding(e padding.PaddingEvent) {
+	ctx := context.Background()
+	dir := attrRequest
+	if e.Direction == padding.DirectionResponse {
+		dir = attrResponse
+	}
+	m.messages.Add(ctx, 1, dir)
+	if e.Length <= 0 {
+		return
+	}
+	m.bytes.Add(ctx, int64(e.Length), dir, metric.WithAttributes(attrHeader))
+	m.length.Record(ctx, int64(e.Length), dir)
+}
+
+func (m *metrics) RecordBodyPadding(n int) {
+	m.bytes.Add(context.Background(), int64(n), attrBody)
+}
+
+func (m *metrics) RecordSkip() {
+	m.skipped.Add(context.Background(), 1)
+}
+
+func (m *metrics) RecordDelay(requested, actual time.Duration) {
+	ctx := context.Background()
+	m.delay.Record(ctx, actual.Seconds())
+	if actual < requested {
+		m.clamped.Add(ctx, 1)
+	}
+}
+
+func (m *metrics) RecordBackoff(e padding.BackoffEvent) {
+	m.backoffs.Add(context.Background(), 1, metric.WithAttributes(attribute.Int("status", e.StatusCode)))
+}
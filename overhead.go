@@ -0,0 +1,63 @@
+package padding
+
+import (
+	"math/rand/v2"
+	"slices"
+)
+
+// overheadSamples 是 EstimateOverhead 模拟的响应数
+const overheadSamples = 20000
+
+// OverheadReport 是 EstimateOverhead 对一份配置的带宽开销估计
+type OverheadReport struct {
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	Samples           int     `json:"samples"` // 模拟的响应数
+	// Mean、P95 与 Max 是每个响应的 padding 开销 (字节), 含 padding 头部的名称与分隔符
+	Mean float64 `json:"mean"`
+	P95  int     `json:"p95"`
+	Max  int     `json:"max"`
+	// BytesPerSecond 与 BytesPerDay 是按 RequestsPerSecond 与 Mean 推算的带宽开销
+	BytesPerSecond float64 `json:"bytes_per_second"`
+	BytesPerDay    float64 `json:"bytes_per_day"`
+}
+
+// EstimateOverhead 按 opts 的 Profile (含 Distribution 与 Components)、头部数量与 Probability
+// 以确定性的随机序列模拟大量响应, 估计每个响应的 padding 开销与 requestsPerSecond 下的带宽开销,
+// 便于在部署前比较不同的配置; 结果只依赖 opts, 相同的输入总是得到相同的报告
+// 只计入按 Profile 采样的头部 padding: 固定头部大小、Strategy、响应体 padding 与 MinTotalSize 依赖实际响应, 不在估计之内
+func EstimateOverhead(opts PaddingOptions, requestsPerSecond float64) OverheadReport {
+	opts = normalizeOptions(opts, "padding.EstimateOverhead")
+	src := rand.NewChaCha8([32]byte{})
+	names := opts.emitHeaderNames(opts.now())
+	if opts.SampleHeaderSize || len(opts.Decoys) > 0 {
+		names = names[:1]
+	}
+
+	sizes := make([]int, overheadSamples)
+	var sum float64
+	for i := range sizes {
+		if opts.Probability != nil && !randChance(src, *opts.Probability) {
+			continue
+		}
+		for _, name := range names {
+			n, err := opts.Profile.sampleFor(src, -1)
+			if err != nil || n <= 0 {
+				continue
+			}
+			sizes[i] += min(n, maxPaddingSize) + len(name) + 4
+		}
+		sum += float64(sizes[i])
+	}
+	slices.Sort(sizes)
+
+	r := OverheadReport{
+		RequestsPerSecond: requestsPerSecond,
+		Samples:           overheadSamples,
+		Mean:              sum / overheadSamples,
+		P95:               sizes[int(0.95*overheadSamples)],
+		Max:               sizes[overheadSamples-1],
+	}
+	r.BytesPerSecond = r.Mean * requestsPerSecond
+	r.BytesPerDay = r.BytesPerSecond * 86400
+	return r
+}
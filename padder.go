@@ -0,0 +1,148 @@
+package padding
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Padder 持有一份可以在运行时原子替换的 padding 配置
+// 由同一个 Padder 创建的服务端中间件、客户端中间件与反向代理钩子共享这份配置,
+// 调用 Reload 后, 新配置对之后开始处理的请求立即生效, 已在处理中的请求继续使用旧配置
+//
+// 并发保证:
+//   - NewPadder、Reload 与 ToukaPaddingS 等构造函数校验并复制传入的配置, Profile、切片与映射都不与调用方共享,
+//     之后修改传入的 PaddingOptions (或其中的 Profile) 不会影响中间件, 也不会与处理中的请求产生数据竞争
+//   - 每个请求在开始时取得一份快照, 快照在发布后不再被修改, 整个请求 (包括其后台 padding 任务) 只读取这一份;
+//     Reload、Options、Enable 与 Disable 可以与请求以及彼此并发调用, 同时调用的多个 Reload 以最后一个为准
+//   - Pool、ValueCache、Budget、Breaker、LoadController、UniformErrors、Auditor、Observer 这类按引用共享的有状态对象,
+//     以及 Rand、Clock、Metrics、Strategy 与各个回调函数, 不会被复制, 新旧快照使用同一个实例;
+//     本包提供的实现都可以并发使用, 自定义实现同样需要是并发安全的
+//   - 同一份 PaddingOptions 可以同时用于创建任意多个中间件与 Padder, 它们互不影响
+//
+// paddingtest.StressReload 可以在 -race 下对一份配置检查这些保证
+type Padder struct {
+	opts     atomic.Pointer[PaddingOptions]
+	disabled atomic.Bool // 为 true 时所有中间件与钩子原样放行
+	stats    paddingStats
+}
+
+// NewPadder 使用给定配置创建一个 Padder, 配置的校验与默认值规则与 ToukaPaddingS 一致
+func NewPadder(opts PaddingOptions) *Padder {
+	return newPadder(opts, "padding.Padder")
+}
+
+// newPadder 创建 Padder, logPrefix 用于区分配置校验日志的来源
+func newPadder(opts PaddingOptions, logPrefix string) *Padder {
+	p := &Padder{}
+	normalized := normalizeOptions(opts, logPrefix)
+	p.opts.Store(&normalized)
+	return p
+}
+
+// Reload 校验并原子地替换当前配置
+func (p *Padder) Reload(opts PaddingOptions) {
+	normalized := normalizeOptions(opts, "padding.Padder")
+	p.opts.Store(&normalized)
+}
+
+// Options 返回当前生效配置 (已补全默认值) 的一份副本
+// Profile、切片与映射同样被复制, 修改后可以传给 Reload, 不会影响正在使用的配置
+func (p *Padder) Options() PaddingOptions {
+	return normalizeOptions(*p.opts.Load(), "padding.Padder")
+}
+
+// load 返回当前生效配置的快照, 调用方不得修改
+func (p *Padder) load() *PaddingOptions {
+	return p.opts.Load()
+}
+
+// skip 报告是否应跳过本次请求 (或其响应) 的 padding, 并记录跳过次数
+// 每次为请求或响应做 padding 决策时调用, 同时向 LoadController 报告一次请求
+func (p *Padder) skip(req *http.Request, opts *PaddingOptions) bool {
+	if opts.Load != nil {
+		opts.Load.observe()
+	}
+	switch override(req, opts) {
+	case overrideOn:
+		return false
+	case overrideOff:
+		p.recordSkip(opts)
+		return true
+	}
+	if p.disabled.Load() || skipRequest(req, opts) {
+		p.recordSkip(opts)
+		return true
+	}
+	return false
+}
+
+// recordSkip 记录一次跳过
+func (p *Padder) recordSkip(opts *PaddingOptions) {
+	p.stats.skipped.Add(1)
+	if opts.Metrics != nil {
+		opts.Metrics.RecordSkip()
+	}
+}
+
+// Enable 重新启用 padding
+func (p *Padder) Enable() {
+	p.disabled.Store(false)
+}
+
+// Disable 立即停用 padding, 之后开始处理的请求与响应都会原样放行, 无需重新部署
+// 适用于事故响应; 配置与统计信息保持不变, 调用 Enable 即可恢复
+func (p *Padder) Disable() {
+	p.disabled.Store(true)
+}
+
+// Enabled 报告 padding 当前是否处于启用状态
+func (p *Padder) Enabled() bool {
+	return !p.disabled.Load()
+}
+
+// WatchFile 每隔 interval 检查一次配置文件的修改时间, 文件变化时通过 OverlayOptions 将文件覆盖到当前配置上并调用 Reload:
+// 文件能够表达的字段取自文件, Strategy、Metrics、AuthKey 等只能在代码中设置的字段保持不变
+// 加载失败时保留当前配置并记录日志; interval 小于等于 0 时为 5 秒; 调用返回的 stop 函数停止监视
+func (p *Padder) WatchFile(path string, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	var lastMod time.Time
+	if fi, err := os.Stat(path); err == nil {
+		lastMod = fi.ModTime()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			fi, err := os.Stat(path)
+			if err != nil || fi.ModTime().Equal(lastMod) {
+				continue
+			}
+			lastMod = fi.ModTime()
+			opts, err := OverlayOptions(p.Options(), path)
+			if err != nil {
+				log.Printf("padding.Padder: failed to reload %s: %v", path, err)
+				continue
+			}
+			p.Reload(opts)
+		}
+	}()
+
+	var once atomic.Bool
+	return func() {
+		if once.CompareAndSwap(false, true) {
+			close(done)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package padding_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fenthope/padding"
+	"github.com/fenthope/padding/paddingtest"
+)
+
+// waitFor 轮询 cond 直到其为 true, 超时时使测试失败
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// watchMetrics 只创建一次: expvar 不允许重复发布同名变量 (如 -count 大于 1 时)
+var watchMetrics = padding.NewExpvarMetrics("padding_test_watchfile")
+
+func TestWatchFileKeepsCodeOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "padding.yaml")
+	if err := os.WriteFile(path, []byte("header_name: X-First\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := padding.NewPadder(padding.PaddingOptions{
+		HeaderName: "X-First",
+		AuthKey:    []byte("code-only-key"),
+		Strategy:   padding.Chain(padding.SampleProfile, padding.RoundTo(64)),
+		Metrics:    watchMetrics,
+	})
+	stop := p.WatchFile(path, 10*time.Millisecond)
+	defer stop()
+
+	// 保证修改时间变化
+	future := time.Now().Add(time.Second)
+	if err := os.WriteFile(path, []byte("header_name: X-Second\nskip_paths: [/healthz]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return p.Options().HeaderName == "X-Second" })
+
+	opts := p.Options()
+	if string(opts.AuthKey) != "code-only-key" {
+		t.Errorf("AuthKey = %q after reload, want the value set in code", opts.AuthKey)
+	}
+	if opts.Strategy == nil || opts.Metrics != watchMetrics {
+		t.Errorf("Strategy or Metrics was cleared by the file reload")
+	}
+	if len(opts.SkipPaths) != 1 || opts.SkipPaths[0] != "/healthz" {
+		t.Errorf("SkipPaths = %v, want the value from the file", opts.SkipPaths)
+	}
+}
+
+func TestStressReloadWithWatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "padding.yaml")
+	write := func(i int) error {
+		return os.WriteFile(path, fmt.Appendf(nil, "header_name: X-Watched-%d\nprofile:\n  min_length: %d\n  max_length: %d\n", i%3, 8*i%64, 64+8*i%64), 0o644)
+	}
+	if err := write(0); err != nil {
+		t.Fatal(err)
+	}
+	base := padding.PaddingOptions{
+		AuthKey:  []byte("stress"),
+		Strategy: padding.Chain(padding.SampleProfile, padding.Jitter(8)),
+	}
+	configs := []padding.PaddingOptions{
+		base,
+		{Profile: &padding.PaddingProfile{MinLength: 32, MaxLength: 256}, SelfDescribing: true, HeaderCandidates: []padding.WeightedHeaderName{{Name: "X-Pad-A", Weight: 1}, {Name: "X-Pad-B", Weight: 2}}},
+		{Profile: &padding.ProfileDefault, QueryPadding: &padding.QueryPaddingOptions{}, ReplayWindow: time.Minute, AuthKey: []byte("other")},
+	}
+	p := padding.NewPadder(base)
+	stop := p.WatchFile(path, time.Millisecond)
+	defer stop()
+
+	// 在整个压力测试期间不断改写配置文件, 使 WatchFile 与 Reload 同时进行
+	done := make(chan struct{})
+	var writer sync.WaitGroup
+	writer.Add(1)
+	go func() {
+		defer writer.Done()
+		for i := 1; ; i++ {
+			select {
+			case <-done:
+				return
+			case <-time.After(2 * time.Millisecond):
+			}
+			future := time.Now().Add(time.Duration(i) * time.Second)
+			if write(i) != nil || os.Chtimes(path, future, future) != nil {
+				return
+			}
+		}
+	}()
+	paddingtest.StressReload(t, p, configs, 8, 200)
+	close(done)
+	writer.Wait()
+}
@@ -7,41 +7,65 @@ package padding
 import (
 	"crypto/rand"
 	"errors"
+	"log"
 	"math/big"
+	"net/http"
+	"slices"
+	"time"
 )
 
-// --- 预生成的随机数据池 (高性能 Padding 的基础) ---
+// --- 预生成的随机数据池 (高性能 Padding 的基础, 见 Pool) ---
 const (
-	// maxPaddingSize 定义了预生成随机数据池的大小，也是单个 padding 头的最大可能长度
+	// maxPaddingSize 定义了默认数据池的大小，也是单个 padding 头的最大可能长度
 	// 4KB 是一个合理的大小，可以覆盖大多数头部长度需求
 	maxPaddingSize = 4096
 	// paddingCharset 是用于生成随机 padding 内容的字符集
-	paddingCharset = "X"
+	// 使用 64 个字符的随机内容而不是单一字符, 使 padding 难以被 gzip/brotli 压缩,
+	// 也使相同长度的 padding 值几乎不会重复, 不会被 HPACK/QPACK 动态表索引后以极小的开销重复发送
+	// 不包含 '-' 与 '>', 可以安全地放入 HTML 注释、JSON 字符串、SSE 注释与结构化字段 token
+	paddingCharset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_."
 )
 
-var (
-	// precomputedPaddingData 在程序启动时生成，用于高效获取随机 padding 内容
-	// 这是一个包级别的只读变量，在初始化后不会被修改，因此并发读取是安全的
-	precomputedPaddingData []byte
-)
+// PaddingProfile 定义了一种特定的 padding 长度分布策略
+type PaddingProfile struct {
+	MinLength int // Padding 的最小长度（字节）
+	MaxLength int // Padding 的最大长度（字节）
+	// BlockSize 大于 0 时启用块长度填充 (RFC 8467): 已知原始内容长度时,
+	// padding 使 (内容 + padding) 向上对齐到 BlockSize 的整数倍, 否则按 [MinLength, MaxLength] 随机采样
+	BlockSize int
+	// Components 不为空时, 该 Profile 是一个组合策略: 每次采样按权重选出一个分量并由其决定长度
+	// 通常通过 CompositeProfile 构造
+	Components []WeightedProfile
+	// Distribution 决定在 [MinLength, MaxLength] 范围内采样时使用的概率分布, 默认为均匀分布
+	Distribution Distribution
+}
 
-func init() {
-	precomputedPaddingData = make([]byte, maxPaddingSize)
-	charsetLen := big.NewInt(int64(len(paddingCharset)))
-	for i := 0; i < maxPaddingSize; i++ {
-		randIndex, err := rand.Int(rand.Reader, charsetLen)
+// sample 按照 Profile 的范围采样一个随机长度
+func (p *PaddingProfile) sample(src RandSource) (int, error) {
+	if len(p.Components) > 0 {
+		c, err := p.pick(src)
 		if err != nil {
-			// 如果在初始化时无法生成随机数据，这是一个严重错误，应立即 panic
-			panic("toukaPadding: failed to initialize precomputed padding data: " + err.Error())
+			return 0, err
 		}
-		precomputedPaddingData[i] = paddingCharset[randIndex.Int64()]
+		return c.sample(src)
 	}
+	return p.Distribution.sample(src, p.MinLength, p.MaxLength)
 }
 
-// PaddingProfile 定义了一种特定的 padding 长度分布策略
-type PaddingProfile struct {
-	MinLength int // Padding 的最小长度（字节）
-	MaxLength int // Padding 的最大长度（字节）
+// sampleFor 在已知原始内容长度 contentLen 时采样 padding 长度, contentLen 小于 0 表示未知
+// 启用了块长度填充且长度已知时返回对齐所需的长度, 否则等同于 sample
+func (p *PaddingProfile) sampleFor(src RandSource, contentLen int) (int, error) {
+	if len(p.Components) > 0 {
+		c, err := p.pick(src)
+		if err != nil {
+			return 0, err
+		}
+		return c.sampleFor(src, contentLen)
+	}
+	if p.BlockSize > 0 && contentLen >= 0 {
+		return min(BlockPaddingLength(contentLen, p.BlockSize), maxPaddingSize), nil
+	}
+	return p.sample(src)
 }
 
 // 内置的 Padding 策略，模仿不同类型网站的响应大小
@@ -69,38 +93,576 @@ type PaddingOptions struct {
 	// 可以使用内置的 ProfileDefault, ProfileShort, ProfileLong 等，或自定义
 	// 如果为 nil，将使用 ProfileDefault 作为默认值
 	Profile *PaddingProfile
+	// WireSize 为 true 时, Profile 中的长度表示 padding 值经 HPACK/QPACK Huffman 编码后的线上字节数,
+	// 而不是值本身的逻辑字节数; 适用于 HTTP/2 与 HTTP/3 部署
+	WireSize bool
+	// TargetHeaderSize 大于 0 时启用固定头部大小模式: 不再按 Profile 随机采样长度,
+	// 而是测量已有头部的序列化大小, 补足 padding 使整个头部块恰好达到该大小
+	// 只能测量到设置 padding 时已存在的头部, net/http 之后自动添加的头部 (如 Date) 不计入
+	TargetHeaderSize int
+	// HeaderSizeBucket 大于 0 时, 头部块大小向上取整到该值的整数倍
+	// 可单独使用, 也可与 TargetHeaderSize 配合, 处理已有头部超过目标大小的情况
+	HeaderSizeBucket int
+	// SampleHeaderSize 为 true 时, Profile 采样的长度表示整个头部块 (已有头部 + padding 头部) 的目标大小,
+	// padding 补足两者之差; 这样 cookie 等已有头部大小不同的响应在 padding 后仍服从同一个大小分布,
+	// 而不是在各自的基础上叠加; Profile 的范围应覆盖常见的头部大小, 已有头部超过目标时不添加 padding
+	SampleHeaderSize bool
+	// MaxHeaderBytes 大于 0 时声明下游 (反向代理、负载均衡器或对端服务器) 的头部大小上限,
+	// 如 HeaderLimitNginx、HeaderLimitALB; padding 会被自动缩短, 使包括已有头部在内的整个头部块
+	// 留有余量地低于该上限, 避免中间设备因头部过大返回 502 或 431
+	MaxHeaderBytes int
+	// AlignFirstWrite 大于 0 时启用首包对齐模式: 不再随机采样, 而是让 HTTP/1.1 的起始行、头部块与
+	// 首段消息体 (已知 Content-Length 时) 的总大小恰好落在该值的整数倍上, 如 1400 (常见 MTU 载荷) 或 16384
+	// (TLS 记录上限), 使不同响应的首个 TLS 记录与数据包大小对齐; 消息超过一个边界时首个记录本就是满的, 不再 padding
+	// 与 TargetHeaderSize 一样只能测量到设置 padding 时已存在的头部, 且只对 HTTP/1.1 有意义
+	AlignFirstWrite int
+	// Session 不为 nil 时启用会话一致的 padding: padding 头部长度由会话键 (cookie 或连接) 派生,
+	// 同一会话内保持不变; 长度范围取自按状态码、内容类型或主机选出的 Profile
+	Session *SessionPadding
+	// Entity 不为 nil 时启用按实体版本一致的 padding: 带有 ETag 或 Last-Modified 的响应, padding 长度由路径与实体标签派生,
+	// 同一实体版本的 200 与 304 响应长度相同 (304 响应因此也会添加 padding); 长度范围取自默认的 Profile,
+	// 不受 ProfileByStatus 与 ProfileByContentType 影响, 优先于 Session
+	Entity *EntityPadding
+	// QueryPadding 不为 nil 时, 客户端中间件还会在出站请求的 URL 中追加一个随机命名、随机长度的查询参数,
+	// 用于头部 padding 会被中间设备移除的环境; 设置 Only 时只添加查询参数 (仅客户端)
+	QueryPadding *QueryPaddingOptions
+	// CookiePadding 不为 nil 时, 还会通过 cookie 携带 padding: 客户端与反向代理的上游请求使用 Cookie 头部,
+	// 服务端与反向代理的下游响应使用 Set-Cookie; 设置 Only 时不再添加 padding 头部
+	CookiePadding *CookiePaddingOptions
+	// Strategy 不为 nil 时, 由它决定每条消息的 padding 头部名称与长度、响应体 padding 长度以及发送前的延迟,
+	// 代替内置的头部 padding 逻辑; *PaddingProfile 本身也实现了 Strategy
+	Strategy Strategy `json:"-"`
+	// Breaker 不为 nil 时, padding 生成连续失败会先降级到更小的 Profile、再停用 padding, 冷却后逐级探测恢复;
+	// 停用期间 FailClosed 模式下的消息以 ErrBreakerOpen 失败, 否则原样发送且不再逐条记录日志
+	Breaker *Breaker `json:"-"`
+	// Backoff 不为 nil 时 (仅客户端), 携带 padding 的请求收到 431、413 等响应后, 发往该主机的请求的 padding 长度上限
+	// 自动降低, 使 padding 适应中间设备的头部大小限制; 见 NewBackoff
+	Backoff *Backoff `json:"-"`
+	// DeadlineMargin 是注入延迟 (Strategy 的 Decision.Delay 与恒定速率整形) 时在请求 context 的截止时间之前保留的余量,
+	// 延迟会被截断, 使其结束时距截止时间至少还有该时长; 小于等于 0 时为 100 毫秒
+	DeadlineMargin time.Duration
+	// AuthKey 不为空时 (仅客户端与反向代理的上游请求), padding 头部值的末尾 43 个字符是以该密钥对
+	// 请求方法、路径与其余内容计算的 HMAC, 服务端可用 VerifyPaddingS 校验; 不适用于字节序列形式的结构化字段
+	AuthKey []byte `json:"-"`
+	// ReplayWindow 大于 0 且设置了 AuthKey 时, 签名的内容以一个带时间戳的随机 nonce 开头;
+	// 对端 VerifyPaddingS 设置同样的选项后, 拒绝 nonce 的时间戳与当前时间相差超过 ReplayWindow 或在窗口内重复出现的请求,
+	// 使 padding 同时成为协作客户端之间的轻量防重放通道; 两端的时钟偏差应远小于 ReplayWindow
+	ReplayWindow time.Duration
+	// Clock 是 Schedule、RotateHeader、ReplayWindow 与 Strategy 的延迟读取时间的来源, 为 nil 时使用系统时间
+	// 测试可以设置一个可手动推进的 Clock, 使这些随时间变化的行为可以确定地复现
+	Clock Clock `json:"-"`
+	// Rechunk 不为 nil 时 (仅服务端), 响应体会被重新切分为随机大小的写入, 并可随机 Flush
+	Rechunk *RechunkOptions
+	// SSEKeepAlive 不为 nil 时 (仅服务端), text/event-stream 响应会以随机间隔发送
+	// 随机长度的注释行, 为空闲的 SSE 连接提供掩护流量
+	SSEKeepAlive *SSEKeepAliveOptions
+	// StreamPadding 不为 nil 时 (仅服务端), 媒体类型有对应 StreamFiller 的响应 (默认为 SSE 与 HTML)
+	// 会在处理函数的写入之间按随机间隔插入 filler, 长时间的流式响应在传输过程中的流量大小同样被混淆
+	StreamPadding *StreamPaddingOptions
+	// HTMLBodyPadding 不为 nil 时 (仅服务端), text/html 响应会在末尾追加一段随机长度的 HTML 注释,
+	// 长度由该 Profile 决定; 浏览器会忽略注释, 客户端无需任何剥离处理
+	HTMLBodyPadding *PaddingProfile
+	// MinTotalSize 大于 0 时 (仅服务端), 总大小 (头部 + 响应体, 含 padding) 小于该值的响应会被补足到该值,
+	// 避免空 JSON 数组、像素信标等极小响应成为最易识别的特征; 不受 Budget 与 Load 影响
+	// 响应体长度已知 (设置了 Content-Length) 时加长 padding 头部; 否则在响应结束时以空白字符补齐
+	// text/* 与 JSON 响应体, 其他类型按空响应体加长头部 (总大小会超出下限)
+	MinTotalSize int
+	// UniformErrors 不为 nil 时 (仅服务端), 错误响应 (4xx/5xx) 的总大小会被补齐到从最近成功响应中随机抽取的大小,
+	// 使 404 等错误页与真实页面的大小分布一致, 无法仅凭大小区分有效与无效路径
+	UniformErrors *UniformErrors `json:"-"`
+	// RedirectBodyPadding 不为 nil 时 (仅服务端), 重定向响应 (3xx, 如 c.Redirect 与 http.Redirect 产生的响应)
+	// 会在末尾追加一段长度由该 Profile 决定的 HTML 注释, 使这类极小且极易识别的响应在总大小上接近普通页面
+	// 未设置 Content-Type 的重定向 (如对 POST 的重定向) 会被设置为 text/html; 优先于 HTMLBodyPadding
+	RedirectBodyPadding *PaddingProfile
+	// ContentLength 决定添加响应体 padding 时如何处理已设置的 Content-Length (仅服务端), 默认移除
+	ContentLength ContentLengthMode
+	// MaxOverheadPercent 大于 0 时 (仅响应), padding (头部与响应体之和) 不超过响应大小的该百分比,
+	// 但不低于所选 Profile 的 MinLength, 小响应仍能得到最低限度的保护; 如 20 表示大响应的 padding 开销不超过 20%
+	// 只在处理函数设置了 Content-Length (反向代理为上游响应的长度) 时生效; MinTotalSize、ContentLengthBucket 与
+	// UniformErrors 的补齐是显式要求的大小, 不受其限制; 设置了 Strategy 时以 RequestInfo.Profile 传入限制后的 Profile
+	MaxOverheadPercent float64
+	// SniffBytes 大于 0 时 (仅服务端), 处理函数未设置 Content-Type 的响应会推迟写出头部, 直到缓冲了 SniffBytes 字节
+	// (超过 512 时按 512) 的响应体、处理函数调用 Flush 或处理结束, 先按 http.DetectContentType 设置 Content-Type
+	// (与 net/http 隐式嗅探的结果相同) 再做出 padding 决定, 使 ProfileByContentType 与响应体 padding 等依赖内容类型的特性
+	// 对这类响应同样生效; 缓冲期间的写入不会到达客户端
+	SniffBytes int
+	// ContentLengthBucket 大于 0 时 (仅服务端), 设置了 Content-Length 的 200 响应
+	// (如 http.ServeFile 提供的静态文件) 会在末尾追加空白字符, 使 Content-Length 对齐到该值的整数倍;
+	// 只作用于 ContentLengthBucketTypes 列出的媒体类型, Range 请求的 206 响应、已编码的响应
+	// 以及已添加 HTML 或 JSON 响应体 padding 的响应保持原样
+	ContentLengthBucket int
+	// ContentLengthBucketTypes 列出 ContentLengthBucket 作用的媒体类型, 可以是 "text/plain" 这样的精确类型,
+	// 也可以是 "text/*"; 为空时只作用于 text/html 与 JSON 响应; 追加的空白字符会改变响应体的哈希,
+	// 以 Subresource Integrity (integrity 属性) 引用的脚本与样式表 (text/javascript、text/css) 会因此无法加载,
+	// 只有确认没有被 SRI 引用时才应加入这类类型; 不能在末尾追加空白字符的类型 (如 image/*) 即使列出也保持原样
+	ContentLengthBucketTypes []string
+	// RangeQuantum 大于 0 时 (仅服务端), 请求 Range 头部中的每个区间会在交给处理函数之前向外扩展到该值的整数倍边界,
+	// 206 响应的大小只以 RangeQuantum 为粒度变化, 不再精确反映客户端请求的区间; 响应的 Content-Range
+	// 描述实际返回的区间, 遵循 RFC 9110 的客户端据此取用所需的部分
+	// 只应对由本服务提供、且客户端会检查 Content-Range 的资源启用, 例如以 ForGroup 挂载在静态文件的路由组上
+	RangeQuantum int
+	// JSONBodyPadding 不为 nil 时 (仅服务端), application/json 响应会被注入一个被忽略的字段
+	// 或在末尾追加空白字符, 在不破坏解析器的前提下随机化响应体大小
+	JSONBodyPadding *JSONPaddingOptions
+	// Trailer 不为 nil 时 (仅服务端), 在响应体结束后通过 HTTP Trailer 发送 padding,
+	// 其长度可以根据响应体的最终大小选择, 从而对流式响应实现按桶对齐
+	Trailer *TrailerOptions
+	// WebSocket 不为 nil 时 (仅服务端), 经由中间件升级的 WebSocket 连接会以随机间隔
+	// 注入随机长度的 Pong 帧, 为长连接提供大小与时间上的掩护流量
+	WebSocket *WebSocketPaddingOptions
+	// ProfileByContentType 按响应的媒体类型选择不同的 Profile (仅服务端), 在 WriteHeader 时根据
+	// 处理函数设置的 Content-Type 查找; 键可以是 "text/html" 这样的精确类型, 也可以是 "image/*" 或 "*/*"
+	// 未命中时使用 Profile
+	ProfileByContentType map[string]*PaddingProfile
+	// ProfileByStatus 按响应状态码选择不同的 Profile (仅服务端), 优先级高于 ProfileByContentType
+	// 错误与重定向响应 (如 301/404) 的大小通常很小且极易识别, 可以为其单独配置更强的 padding
+	ProfileByStatus map[int]*PaddingProfile
+	// SkipStatusCodes 列出不添加任何 padding 的响应状态码 (仅服务端)
+	SkipStatusCodes []int
+	// PadAllResponses 为 true 时不再应用 DefaultSkipMethods 与 DefaultSkipStatusCodes 默认规则,
+	// HEAD 请求以及 101/204/304 响应也会添加 padding (仅服务端与反向代理的下游响应); SkipStatusCodes 仍然生效
+	PadAllResponses bool
+	// PadConnect 为 true 时, 客户端中间件也为显式发出的 CONNECT 请求添加 padding,
+	// ConfigureProxyTransport 安装的回调也会为经 HTTP 代理建立隧道时的 CONNECT 请求添加 padding 头部
+	// 默认只填充隧道内的请求: CONNECT 请求对代理可见, 其头部中的 padding 只会暴露给代理而无法保护隧道内的流量
+	PadConnect bool
+	// CORSExposeHeaders 为 true 时 (仅服务端与反向代理的下游响应), 跨域响应 (已设置 Access-Control-Allow-Origin)
+	// 中新增的 padding 头部名称会被追加到 Access-Control-Expose-Headers, 使浏览器中的 fetch/XHR 与 gRPC-Web 客户端
+	// 能够读取它们, 长度与普通响应保持一致; CORS 中间件需要在 padding 中间件写出头部之前设置 Access-Control-Allow-Origin
+	CORSExposeHeaders bool
+	// SkipUpgrade 为 true 时, 客户端中间件与反向代理不为 Upgrade 握手请求 (如 WebSocket) 添加 padding
+	// 默认会添加: padding 只使用独立的头部, 不会改动 Connection、Upgrade 与 Sec-WebSocket-* 等握手头部,
+	// 也不会应用 QueryPadding; 但部分服务端会拒绝握手中出现的未知头部, 此时可以开启该选项
+	SkipUpgrade bool
+	// SkipWithHeaders 列出一组头部名称, 携带其中任一头部的消息 (客户端与反向代理的上游请求、服务端与反向代理的响应)
+	// 不添加 padding, 用于头部被签名或摘要整体覆盖、额外的头部会使校验失败的消息
+	SkipWithHeaders []string
+	// SkipSigned 为 true 时, 携带 Signature 或 Signature-Input 头部 (RFC 9421 HTTP 消息签名) 的消息不添加 padding,
+	// 相当于在 SkipWithHeaders 中加入这两个名称
+	SkipSigned bool
+	// StripVary 为 true 时 (仅服务端与反向代理), 从响应的 Vary 头部中移除 padding 头部名称;
+	// 请求的 padding 头部每次都不同, 处理函数或上游将其列入 Vary 会使共享缓存对每个请求都无法命中
+	StripVary bool
+	// Schedule 按每天的时间段切换默认的 Profile, 如在低流量时段使用更重的 padding、在高峰时段使用更轻的 padding
+	// 第一个包含当前时刻的条目生效, 均未命中时使用 Profile; ProfileByStatus 等更具体的选择仍然优先
+	Schedule []ScheduledProfile
+	// ScheduleLocation 是解释 Schedule 时间段所用的时区, 为 nil 时使用本地时区
+	ScheduleLocation *time.Location `json:"-"`
+	// ProfileByProtocol 按消息所在连接的协议 (HTTP/1.1、HTTP/2、HTTP/3) 选择默认的 Profile, 优先于 Schedule
+	// 服务端按入站请求的协议版本选择; 客户端只有在请求的 context 经 ContextWithProtocol 给出提示时才会命中
+	// 典型用法是为 HTTP/2 与 HTTP/3 配置更长的 padding 以抵消 HPACK/QPACK 的压缩
+	ProfileByProtocol map[Protocol]*PaddingProfile
+	// ProfileByHost 按出站请求的目标主机选择不同的 Profile (仅客户端与反向代理的上游请求)
+	// 键可以是精确的主机名, 也可以是 "*.example.com" 形式的通配符或 "*"; 未命中时使用 Profile
+	ProfileByHost map[string]*PaddingProfile
+	// Upstream 不为 nil 时 (仅客户端), 以 UpstreamContext 作为 context 的出站请求按入站请求的 padding 长度
+	// 决定自己的 padding: 保持相同的长度, 或者与之去相关, 避免网关两侧的请求按大小被对应起来
+	Upstream *UpstreamOptions
+	// Retry 决定客户端中间件对同一个逻辑请求的重试如何添加 padding (仅客户端, 见 RetryPolicy), 为空时每次重新采样
+	// 两种策略都会先撤销上一次尝试添加的 padding 头部与查询参数, 重试不会累积多份 padding
+	Retry RetryPolicy
+	// ConnPadding 不为 nil 时 (仅服务端), 同一个 HTTP/1.1 keep-alive 连接上相继的响应按其设置沿用相同的 padding 长度,
+	// 或保证相邻两个响应的长度不会相同或过于接近; 连接由 ConnContext 附加的记录识别, 未设置时按远端地址识别
+	ConnPadding *ConnPadding
+	// Diagnostics 为 true 时, padding 的生成与响应体 padding 的写出在带有方向与 Profile 名称的 pprof 标签
+	// 及 runtime/trace 区域内执行, 便于在生产环境的 profile 与执行跟踪中归因 padding 的 CPU 与分配开销
+	Diagnostics bool
+	// Seed 不为 nil 时, 未显式设置密钥的 RotateHeader、Session 与 Entity 使用由共享种子派生的密钥,
+	// 同一组网关实例对同一请求给出相同的头部名称与长度; 设置 PerURL 时长度还按 URL 确定性地派生
+	Seed *FleetSeed
+	// Preflight 不为 nil 时 (仅服务端), OPTIONS 请求 (CORS 预检) 的响应按其设置跳过、填充到固定大小或使用专门的 Profile
+	Preflight *PreflightOptions
+	// SkipPaths 列出不添加 padding 的请求路径, 以 "*" 结尾的模式按前缀匹配 (如 "/static/*")
+	SkipPaths []string
+	// SkipUserAgents 列出不添加 padding 的 User-Agent 子串 (不区分大小写), 如监控探针与健康检查:
+	// []string{"kube-probe", "UptimeRobot", "Prometheus"}; 主要用于服务端, 也作用于客户端自身的 User-Agent
+	SkipUserAgents []string
+	// SkipRequest 不为 nil 且返回 true 时跳过对本次请求 (或其响应) 的 padding, 可基于任意请求头判断
+	SkipRequest func(req *http.Request) bool `json:"-"`
+	// Probability 不为 nil 时是对单个请求 (或响应) 添加 padding 的概率, 取值 [0, 1], 0 表示从不添加;
+	// nil 表示总是添加, 超出范围的值会被截断到 [0, 1]; 运行时停止添加也可以使用 Padder.Disable
+	Probability *float64
+	// OnPadding 不为 nil 时, 每次为请求或响应决定 padding 头部后以 PaddingEvent 同步调用,
+	// 可用于自定义遥测、抽样审计或自适应调整; 回调应尽快返回且可能被并发调用
+	OnPadding func(info PaddingEvent) `json:"-"`
+	// Metrics 不为 nil 时, padding 决策、响应体 padding 与跳过都会同步报告给它,
+	// 用于通过 expvar (NewExpvarMetrics)、OpenTelemetry (otelpad 子包) 等遥测系统导出
+	Metrics Metrics `json:"-"`
+	// DryRun 为 true 时进入演练模式: 中间件照常计算 padding 决定并记录统计、OnPadding 与 Metrics,
+	// 但不修改任何请求或响应 (不添加头部、响应体 padding 与延迟), 用于在生产环境中安全地评估开销与分布
+	DryRun bool
+	// FailClosed 为 true 时, padding 生成失败 (如随机数源出错) 不再静默地发送未填充的消息:
+	// 服务端以 500 中止响应, 客户端中间件返回错误, 反向代理使请求以错误结束
+	FailClosed bool
+	// ConstantRate 不为 nil 时 (仅服务端), 响应体以固定大小的块按恒定速率发出, 末尾补足到整块;
+	// 启用后 Rechunk 不再生效
+	ConstantRate *ConstantRateOptions
+	// Load 不为 nil 时, padding 头部与响应体 padding 的长度按其报告的负载缩放, 服务饱和时自动降低开销
+	// 固定头部大小模式下头部 padding 不受影响; 每次 padding 决策都会计入其请求速率估计
+	Load *LoadController `json:"-"`
+	// Budget 不为 nil 时, padding 头部与响应体 padding 消耗其中的字节额度, 额度不足时缩短或跳过 padding
+	Budget *Budget `json:"-"`
+	// Audit 不为 nil 时, 每个响应的总大小 (头部 + 响应体, 含 padding) 都会计入其分布统计 (仅服务端),
+	// 用于发现分布坍缩或 padding 被静默跳过等配置问题
+	Audit *Auditor `json:"-"`
+	// Observe 不为 nil 时, 在其观察阶段内不添加 padding, 只记录真实响应的大小并在阶段结束后给出推荐的 Profile (仅服务端)
+	Observe *Observer `json:"-"`
+	// OverrideHeader 不为空时, 受信任的调用方可以通过该请求头按请求覆盖是否添加 padding:
+	// 值为 "off" 时跳过, 为 "on" 时无视 Disable、SkipPaths 与 Probability 强制添加
+	// 只有 TrustOverride 返回 true 的请求才会生效; 该请求头总是会被移除, 不会继续转发
+	// 反向代理中覆盖只作用于上游请求
+	OverrideHeader string
+	// TrustOverride 判断请求是否来自可信的内部调用方 (如检查来源地址或共享密钥), 为 nil 时忽略覆盖请求头
+	TrustOverride func(req *http.Request) bool `json:"-"`
+	// Decoys 不为空时启用伪装模式: 不再设置 HeaderName 头部, 而是将采样的 padding 长度分摊到
+	// 若干外观正常的头部上 (如 X-Request-Id、CDN 追踪头), 使响应看起来像普通的 CDN 流量
+	// 可以使用 DefaultDecoyHeaders; 消息中已存在的同名头部不会被覆盖; 固定头部大小模式与 WireSize 在此模式下只是近似
+	Decoys []DecoyHeader
+	// RotateHeader 不为 nil 时, padding 头部的名称按时间窗口轮换 (由密钥派生或取自列表), HeaderName 不再使用
+	// 对端可以使用 StripPadding / StripPaddingS 配合同样的配置识别并移除这些头部
+	RotateHeader *HeaderRotation
+	// StructuredField 不为空时, padding 头部的值输出为合法的 RFC 8941 结构化字段条目 (token 或字节序列),
+	// 内容限制在对应的合法字符集内, 使其能通过严格校验或规范化头部的中间设备; 此时 WireSize 不生效
+	StructuredField StructuredFieldType
+	// SelfDescribing 为 true 时, padding 头部的值使用带版本前缀、逐段标明长度的自描述格式 (见 ParseValue),
+	// 对端的 StripPadding、VerifyPaddingS 设置同样的选项后, 不依赖头部名称等配置即可识别、校验并移除 padding;
+	// StructuredField 不为空时不生效, 优先于 WireSize
+	SelfDescribing bool
+	// RandomizeHeaderCase 为 true 时, 每条消息的 padding 头部名称使用随机的大小写形式 (如 "t-PADding")
+	// net/http 在 HTTP/1.1 下按名称的字节序排列头部, 大小写变化同时会改变 padding 头部在头部块中的位置,
+	// 避免 "自定义头部总在固定位置" 的结构指纹; HTTP/2 与 HTTP/3 会统一转为小写, 此时不产生效果
+	RandomizeHeaderCase bool
+	// HeaderNames 不为空时, 每条消息为其中的每个名称各设置一个独立采样长度的 padding 头部,
+	// 组合后的总大小分布更丰富, 也能适应对单个头部大小有上限的中间设备; 优先于 HeaderName、RotateHeader 与 HeaderCount
+	HeaderNames []string
+	// HeaderCandidates 不为空且未设置 HeaderNames 时, 每条消息按权重从中随机选出一个名称作为 padding 头部,
+	// padding 在不同请求中以不同的、看似合理的名称出现 (如 X-Trace-Context、X-Client-Data); 优先于 HeaderName 与 RotateHeader
+	// 服务端的剥离、校验与 Exempt 识别全部候选, 两端应配置相同的候选集合 (权重可以不同)
+	HeaderCandidates []WeightedHeaderName
+	// HeaderCount 大于 1 且未设置 HeaderNames 时, 在 HeaderName (或轮换得到的名称) 之外
+	// 追加以 "-2"、"-3" ... 为后缀的头部, 共 HeaderCount 个; 固定头部大小与伪装模式下只使用第一个
+	HeaderCount int
+	// Rand 是采样 padding 长度与选取 padding 内容所用的随机数来源, 为 nil 时使用 crypto/rand
+	// 每秒请求数极高的网关可以使用 NewFastRand 换取更低的开销
+	Rand RandSource `json:"-"`
+	// ValueCache 不为 nil 时, padding 头部、cookie 与 Trailer 的值从其中复用已转换的字符串, 减少每个请求的内存分配
+	ValueCache *ValueCache `json:"-"`
+	// Pool 是 padding 头部与 Trailer 的值所取自的数据池, 为 nil 时使用包级别共享的默认数据池
+	// 为不同的实例设置不同的 Pool 可以使用不同的字符集、大小与刷新策略; 响应体等其他 padding 总是使用默认数据池
+	Pool *Pool `json:"-"`
+}
+
+// ErrFailClosed 在 FailClosed 模式下 padding 生成失败、响应已被中止后, 由处理函数的写入返回
+var ErrFailClosed = errors.New("padding: response aborted because padding generation failed")
+
+// Value 按照 profile 采样一个随机长度, 并返回对应长度的随机 padding 内容
+// profile 为 nil 时使用 ProfileDefault; 与 ApplyToHeader 相同, profile 先经过校验 (上下限颠倒时修正为相等,
+// 超出数据池大小时截断并记录警告); 频繁调用时可以先用 NormalizeProfile 校验一次, 避免重复记录警告
+func Value(profile *PaddingProfile) (string, error) {
+	length, err := NormalizeProfile(profile, "padding.Value").sample(defaultRandSource)
+	if err != nil {
+		return "", err
+	}
+	return string(getPaddingSlice(defaultRandSource, length)), nil
+}
+
+// NormalizeProfile 返回按中间件的规则校验后的 profile 副本: 上下限颠倒、超出数据池大小等问题会被修正,
+// 并以 logPrefix 为前缀记录警告; profile 为 nil 时返回 ProfileDefault 的副本
+// 供 grpcpad 等在构造时一次性校验 Profile, 避免每次采样时重复修正与记录
+func NormalizeProfile(profile *PaddingProfile, logPrefix string) *PaddingProfile {
+	if profile == nil {
+		profile = &ProfileDefault
+	}
+	return normalizeProfile(profile, logPrefix)
+}
+
+// ApplyToHeader 按照 opts 为 h 设置 padding 头部, 适用于自定义传输、代理或非 HTTP 协议中的头部集合
+// 配置的校验与默认值规则与中间件一致; h 中已有的 Content-Length 会用于块长度填充
+// 只有与 padding 头部相关的选项 (HeaderName、Profile、WireSize、头部大小与 Rand) 生效; 仅在随机数生成失败时返回错误
+func ApplyToHeader(h http.Header, opts PaddingOptions) error {
+	opts = normalizeOptions(opts, "padding.ApplyToHeader")
+	_, err := setPaddingHeader(h, responseContentLength(h), opts.profileAt(opts.now()), &opts)
+	return err
 }
 
 // --- 内部辅助函数 ---
 
+// normalizeOptions 校验并补全配置的默认值, logPrefix 用于区分日志来源
+// Profile 等指针字段会被复制一份, 避免修改调用方传入的 (可能是包级别共享的) 配置
+func normalizeOptions(opts PaddingOptions, logPrefix string) PaddingOptions {
+	if opts.HeaderName == "" {
+		opts.HeaderName = "T-Padding"
+	}
+	if opts.Profile == nil {
+		opts.Profile = &ProfileDefault
+	}
+	opts.Profile = normalizeProfile(opts.Profile, logPrefix)
+	if opts.Seed != nil {
+		// 需要在 RotateHeader、Session 与 Entity 生成各自的随机密钥之前填入派生的密钥
+		if opts.Seed = normalizeFleetSeed(*opts.Seed, logPrefix); opts.Seed != nil {
+			applySeed(&opts)
+		}
+	}
+	if len(opts.Schedule) > 0 {
+		opts.Schedule = normalizeSchedule(opts.Schedule, logPrefix)
+	}
+	if opts.Rand == nil {
+		opts.Rand = defaultRandSource
+	}
+
+	opts.AuthKey = slices.Clone(opts.AuthKey)
+	if opts.DeadlineMargin <= 0 {
+		opts.DeadlineMargin = 100 * time.Millisecond
+	}
+	if opts.QueryPadding != nil {
+		opts.QueryPadding = normalizeQueryPadding(*opts.QueryPadding, logPrefix)
+	}
+	if opts.CookiePadding != nil {
+		opts.CookiePadding = normalizeCookiePadding(*opts.CookiePadding, logPrefix)
+	}
+	if opts.Session != nil {
+		opts.Session = normalizeSessionPadding(*opts.Session)
+	}
+	if opts.Entity != nil {
+		opts.Entity = normalizeEntityPadding(*opts.Entity)
+	}
+	if opts.Rechunk != nil {
+		opts.Rechunk = normalizeRechunk(*opts.Rechunk)
+	}
+	if opts.SSEKeepAlive != nil {
+		opts.SSEKeepAlive = normalizeSSEKeepAlive(*opts.SSEKeepAlive, logPrefix)
+	}
+	if opts.StreamPadding != nil {
+		opts.StreamPadding = normalizeStreamPadding(*opts.StreamPadding, logPrefix)
+	}
+	if opts.HTMLBodyPadding != nil {
+		opts.HTMLBodyPadding = normalizeProfile(opts.HTMLBodyPadding, logPrefix)
+	}
+	if opts.RedirectBodyPadding != nil {
+		opts.RedirectBodyPadding = normalizeProfile(opts.RedirectBodyPadding, logPrefix)
+	}
+	if opts.JSONBodyPadding != nil {
+		opts.JSONBodyPadding = normalizeJSONPadding(*opts.JSONBodyPadding, logPrefix)
+	}
+	if opts.ConstantRate != nil {
+		opts.ConstantRate = normalizeConstantRate(*opts.ConstantRate)
+	}
+	if opts.Trailer != nil {
+		opts.Trailer = normalizeTrailer(*opts.Trailer, logPrefix)
+	}
+	if opts.WebSocket != nil {
+		opts.WebSocket = normalizeWebSocketPadding(*opts.WebSocket, logPrefix)
+	}
+	if opts.ProfileByContentType != nil {
+		opts.ProfileByContentType = normalizeContentTypeProfiles(opts.ProfileByContentType, logPrefix)
+	}
+	if opts.ProfileByStatus != nil {
+		byStatus := make(map[int]*PaddingProfile, len(opts.ProfileByStatus))
+		for code, p := range opts.ProfileByStatus {
+			if p != nil {
+				byStatus[code] = normalizeProfile(p, logPrefix)
+			}
+		}
+		opts.ProfileByStatus = byStatus
+	}
+	if opts.ProfileByProtocol != nil {
+		byProtocol := make(map[Protocol]*PaddingProfile, len(opts.ProfileByProtocol))
+		for proto, p := range opts.ProfileByProtocol {
+			if p != nil {
+				byProtocol[proto] = normalizeProfile(p, logPrefix)
+			}
+		}
+		opts.ProfileByProtocol = byProtocol
+	}
+	opts.SkipStatusCodes = slices.Clone(opts.SkipStatusCodes)
+	opts.SkipUserAgents = lowerNonEmpty(opts.SkipUserAgents)
+	opts.SkipPaths = slices.Clone(opts.SkipPaths)
+	opts.ContentLengthBucketTypes = normalizeMediaTypes(opts.ContentLengthBucketTypes)
+	switch opts.StructuredField {
+	case StructuredFieldNone, StructuredFieldToken, StructuredFieldByteSequence:
+	default:
+		log.Printf("%s: Warning - unknown StructuredField %q. Falling back to raw padding values.", logPrefix, opts.StructuredField)
+		opts.StructuredField = StructuredFieldNone
+	}
+	opts.HeaderCandidates = normalizeHeaderCandidates(opts.HeaderCandidates)
+	opts.HeaderNames = slices.DeleteFunc(slices.Clone(opts.HeaderNames), func(name string) bool { return name == "" })
+	if opts.HeaderCount > maxHeaderCount {
+		log.Printf("%s: Warning - HeaderCount (%d) exceeds %d. Clamping.", logPrefix, opts.HeaderCount, maxHeaderCount)
+		opts.HeaderCount = maxHeaderCount
+	}
+	if opts.RotateHeader != nil {
+		opts.RotateHeader = normalizeHeaderRotation(*opts.RotateHeader)
+	}
+	if opts.Decoys != nil {
+		opts.Decoys = normalizeDecoys(opts.Decoys)
+	}
+	if opts.Probability != nil {
+		// 复制一份, 调用方之后修改原值不会影响已规范化的配置
+		p := *opts.Probability
+		if p < 0 || p > 1 {
+			log.Printf("%s: Warning - Probability (%g) is outside [0, 1]. Clamping it to %g.",
+				logPrefix, p, min(max(p, 0), 1))
+			p = min(max(p, 0), 1)
+		}
+		opts.Probability = &p
+	}
+	if opts.ProfileByHost != nil {
+		opts.ProfileByHost = normalizeHostProfiles(opts.ProfileByHost, logPrefix)
+	}
+	opts.SkipWithHeaders = normalizeSkipHeaders(opts.SkipWithHeaders, opts.SkipSigned)
+	if opts.Upstream != nil {
+		opts.Upstream = normalizeUpstream(*opts.Upstream)
+	}
+	if opts.Preflight != nil {
+		opts.Preflight = normalizePreflight(*opts.Preflight, logPrefix)
+	}
+	if !opts.Retry.valid() {
+		log.Printf("%s: Warning - Unknown Retry policy %q. Padding will be resampled on retries.", logPrefix, opts.Retry)
+		opts.Retry = RetryResample
+	}
+	if opts.MaxOverheadPercent < 0 {
+		log.Printf("%s: Warning - MaxOverheadPercent (%g) is negative. Overhead will not be capped.", logPrefix, opts.MaxOverheadPercent)
+		opts.MaxOverheadPercent = 0
+	}
+	if opts.ConnPadding != nil {
+		opts.ConnPadding = normalizeConnPadding(*opts.ConnPadding)
+	}
+	return opts
+}
+
+// normalizeProfile 返回校验后的 Profile 副本, 超出数据池大小或上下限颠倒时进行修正
+func normalizeProfile(p *PaddingProfile, logPrefix string) *PaddingProfile {
+	profile := *p
+	if profile.MaxLength > maxPaddingSize {
+		log.Printf("%s: Warning - Profile.MaxLength (%d) exceeds maxPaddingSize (%d). It will be capped.",
+			logPrefix, profile.MaxLength, maxPaddingSize)
+		profile.MaxLength = maxPaddingSize
+	}
+	if profile.MinLength < 0 {
+		profile.MinLength = 0
+	}
+	if profile.MinLength > profile.MaxLength {
+		log.Printf("%s: Warning - Profile.MinLength (%d) is greater than MaxLength (%d). Adjusting to be equal.",
+			logPrefix, profile.MinLength, profile.MaxLength)
+		profile.MinLength = profile.MaxLength
+	}
+	if len(profile.Components) > 0 {
+		profile.Components = normalizeComponents(profile.Components, logPrefix)
+	}
+	profile.Distribution = normalizeDistribution(profile.Distribution, logPrefix)
+	return &profile
+}
+
+// setPaddingHeader 按照 profile 采样一个随机长度 (固定头部大小模式下则按已有头部计算),
+// 并将对应的 padding 内容写入 h; contentLen 是消息体长度, 小于 0 表示未知
+// 配置了多个 padding 头部时, 每个头部独立采样长度
+// 返回写入的 padding 总长度, 长度为 0 的头部不设置; 仅在随机数生成失败时返回错误
+func setPaddingHeader(h http.Header, contentLen int64, profile *PaddingProfile, opts *PaddingOptions) (int, error) {
+	fixed := opts.TargetHeaderSize > 0 || opts.HeaderSizeBucket > 0 || opts.AlignFirstWrite > 0
+	names := opts.emitHeaderNames(opts.now())
+	if fixed || opts.SampleHeaderSize || len(opts.Decoys) > 0 {
+		// 固定头部大小、按头部块大小采样与伪装模式都只计算一个总长度
+		names = names[:1]
+	}
+
+	total := 0
+	for _, name := range names {
+		var paddingLen int
+		if opts.AlignFirstWrite > 0 {
+			paddingLen = alignedPaddingLength(h, name, contentLen, opts.AlignFirstWrite)
+		} else if fixed {
+			paddingLen = fixedHeaderPaddingLength(h, name, opts)
+		} else {
+			var err error
+			paddingLen, err = profile.sampleFor(opts.Rand, int(contentLen))
+			if err != nil {
+				return total, err
+			}
+			if opts.SampleHeaderSize {
+				paddingLen = targetHeaderPaddingLength(h, name, paddingLen)
+			}
+			paddingLen = opts.Load.scaleLength(paddingLen)
+		}
+		paddingLen = opts.Budget.take(capHeaderPadding(h, name, paddingLen, opts))
+		if paddingLen <= 0 {
+			continue
+		}
+		total += paddingLen
+		if len(opts.Decoys) > 0 {
+			setDecoyHeaders(h, paddingLen, opts.Decoys, opts.Rand)
+			continue
+		}
+		h.Set(name, paddingValue(paddingLen, opts))
+		if opts.RandomizeHeaderCase {
+			randomizeHeaderCase(h, name, opts.Rand)
+		}
+	}
+	return total, nil
+}
+
+// paddingValue 按 StructuredField、SelfDescribing 与 WireSize 的设置生成长度为 length 的 padding 头部值
+func paddingValue(length int, opts *PaddingOptions) string {
+	switch {
+	case opts.StructuredField != StructuredFieldNone:
+		return structuredValue(opts.StructuredField, opts.Rand, length)
+	case opts.SelfDescribing:
+		return describedValue(length, opts)
+	case opts.WireSize:
+		return string(wirePaddingSlice(opts.Rand, length, opts.Pool))
+	default:
+		return paddingString(opts.Rand, length, opts.Pool, opts.ValueCache)
+	}
+}
+
 // randInt 在 [min, max] 范围内生成一个加密安全的随机整数
-func randInt(min, max int) (int, error) {
+func randInt(src RandSource, min, max int) (int, error) {
 	if min > max {
 		return 0, errors.New("min cannot be greater than max")
 	}
 	if min == max {
 		return min, nil
 	}
+	if f, ok := src.(*fastRand); ok {
+		v, err := f.intN(max - min + 1)
+		if err != nil {
+			return 0, err
+		}
+		return v + min, nil
+	}
 	n := big.NewInt(int64(max - min + 1))
-	val, err := rand.Int(rand.Reader, n)
+	val, err := rand.Int(src, n)
 	if err != nil {
 		return 0, err
 	}
 	return int(val.Int64()) + min, nil
 }
 
-// getPaddingSlice 从预计算的随机数据池中获取一个指定长度的切片
-func getPaddingSlice(length int) []byte {
-	if length <= 0 {
-		return nil
+// randFloat64 生成一个 [0, 1) 范围内加密安全的随机浮点数
+func randFloat64(src RandSource) (float64, error) {
+	const precision = 1 << 53
+	v, err := randInt(src, 0, precision-1)
+	if err != nil {
+		return 0, err
 	}
-	if length > maxPaddingSize {
-		length = maxPaddingSize
+	return float64(v) / precision, nil
+}
+
+// randChance 以概率 p 返回 true, p 小于等于 0 时总是返回 false, 大于等于 1 时总是返回 true
+func randChance(src RandSource, p float64) bool {
+	if p <= 0 {
+		return false
+	}
+	if p >= 1 {
+		return true
 	}
-	maxStart := maxPaddingSize - length
-	start, err := randInt(0, maxStart)
+	v, err := randFloat64(src)
 	if err != nil {
-		start = 0 // 保证功能可用性
+		return false
 	}
-	return precomputedPaddingData[start : start+length]
+	return v < p
+}
+
+// getPaddingSlice 从默认数据池中获取一个指定长度的切片
+func getPaddingSlice(src RandSource, length int) []byte {
+	return defaultPool().load().slice(src, length)
 }
@@ -1,6 +1,7 @@
 package padding
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 
@@ -9,50 +10,129 @@ import (
 
 // ToukaPadding 返回一个 httpc 的客户端中间件。
 // 此中间件通过在每个出站 HTTP 请求中添加一个具有随机长度和内容的头部，
+// 需要在运行时修改配置时, 使用 NewPadder(opts).Client() 并通过 Reload 替换配置
 func ToukaPadding(opts PaddingOptions) httpc.MiddlewareFunc {
 	// --- 验证和设置配置默认值 ---
-	if opts.HeaderName == "" {
-		opts.HeaderName = "T-Padding"
-	}
-	if opts.Profile == nil {
-		opts.Profile = &ProfileDefault
-	}
 	// 验证 Profile 范围的逻辑，与服务端版本一致
-	if opts.Profile.MaxLength > maxPaddingSize {
-		log.Printf("httpc.ToukaPadding: Warning - Profile.MaxLength (%d) exceeds maxPaddingSize (%d). It will be capped.",
-			opts.Profile.MaxLength, maxPaddingSize)
-		opts.Profile.MaxLength = maxPaddingSize
-	}
-	if opts.Profile.MinLength < 0 {
-		opts.Profile.MinLength = 0
-	}
-	if opts.Profile.MinLength > opts.Profile.MaxLength {
-		log.Printf("httpc.ToukaPadding: Warning - Profile.MinLength (%d) is greater than MaxLength (%d). Adjusting to be equal.",
-			opts.Profile.MinLength, opts.Profile.MaxLength)
-		opts.Profile.MinLength = opts.Profile.MaxLength
-	}
+	return newPadder(opts, "httpc.ToukaPadding").Client()
+}
 
+// Client 返回使用 p 当前配置的 httpc 客户端 padding 中间件
+func (p *Padder) Client() httpc.MiddlewareFunc {
 	// 返回中间件函数
 	return func(next http.RoundTripper) http.RoundTripper {
 		return httpc.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
-
-			// 计算随机的 padding 长度
-			paddingLen, err := randInt(opts.Profile.MinLength, opts.Profile.MaxLength)
+			opts := p.load()
+			if req.Method == http.MethodConnect && !opts.PadConnect {
+				traceSkipped(req, TraceSkipConnect)
+				return next.RoundTrip(req)
+			}
+			if p.skip(req, opts) {
+				traceSkipped(req, TraceSkipRequest)
+				return next.RoundTrip(req)
+			}
+			// 设置 padding 头部到出站请求 `req`
+			// req.Header 是一个引用，可以直接修改
+			if req.Header == nil {
+				req.Header = make(http.Header)
+			}
+			upgrade := isUpgradeRequest(req)
+			if upgrade && opts.SkipUpgrade {
+				p.recordSkip(opts)
+				traceSkipped(req, TraceSkipUpgrade)
+				return next.RoundTrip(req)
+			}
+			if skipWithHeader(req.Header, opts) {
+				p.recordSkip(opts)
+				traceSkipped(req, TraceSkipHeader)
+				return next.RoundTrip(req)
+			}
+			if opts.DryRun {
+				traceSkipped(req, TraceSkipDryRun)
+				dryRunPadding(&p.stats, req.Header, requestInfo(req, opts, 0), opts, "httpc.ToukaPadding")
+				return next.RoundTrip(req)
+			}
+			retry := retryStateFor(req)
+			attempt := retry.begin()
+			// 第一次尝试之后主机的上限被 Backoff 降低时, 不再沿用超出上限的 padding
+			pin := opts.Retry == RetryPin && retry.within(opts.Backoff.limit(backoffHost(req)))
+			if attempt > 0 && retry.restore(req, pin) && pin {
+				// 重试沿用第一次尝试的 padding, 只重新签名
+				signPaddingHeaders(req, opts)
+				p.stats.recordHeader(retry.n)
+				notifyPadding(opts, DirectionRequest, req, 0, retry.n)
+				traced := tracePadded(req, retry, attempt, true)
+				if err := sleepContext(req.Context(), retry.delay, opts); err != nil {
+					return nil, err
+				}
+				return p.roundTrip(next, traced, retry.n, opts)
+			}
+			info := requestInfo(req, opts, attempt)
+			before, query := req.Header.Clone(), rawQuery(req)
+			var handshake http.Header
+			if upgrade {
+				handshake = saveHandshakeHeaders(req.Header)
+			}
+			n, decision, err := decidePadding(req.Header, info, opts)
+			if upgrade {
+				restoreHandshakeHeaders(req.Header, handshake)
+			}
+			if opts.QueryPadding != nil && !upgrade && err == nil {
+				var qn int
+				qn, err = setPaddingQuery(req, opts.QueryPadding, opts.Rand)
+				n += qn
+			}
 			if err != nil {
+				if opts.FailClosed {
+					return nil, fmt.Errorf("httpc.ToukaPadding: failed to generate random padding length: %w", err)
+				}
 				// 随机数生成失败是一个罕见的内部错误，记录日志但不中断请求。
 				log.Printf("httpc.ToukaPadding: failed to generate random padding length: %v", err)
-			} else if paddingLen > 0 {
-				// 从预计算的池中获取随机内容
-				paddingData := getPaddingSlice(paddingLen)
-				// 设置 padding 头部到出站请求 `req`
-				// req.Header 是一个引用，可以直接修改
-				if req.Header == nil {
-					req.Header = make(http.Header)
-				}
-				req.Header.Set(opts.HeaderName, string(paddingData))
 			}
+			retry.save(req, before, query, n, decision.Delay)
+			signPaddingHeaders(req, opts)
+			p.stats.recordHeader(n)
+			notifyPadding(opts, DirectionRequest, req, 0, n)
+			traced := tracePadded(req, retry, attempt, false)
 
-			return next.RoundTrip(req)
+			if err := sleepContext(req.Context(), decision.Delay, opts); err != nil {
+				return nil, err
+			}
+			return p.roundTrip(next, traced, n, opts)
 		})
 	}
 }
+
+// roundTrip 发送携带 n 字节 padding 的请求, 并将响应状态码报告给 Backoff
+func (p *Padder) roundTrip(next http.RoundTripper, req *http.Request, n int, opts *PaddingOptions) (*http.Response, error) {
+	resp, err := next.RoundTrip(req)
+	if resp != nil && opts.Backoff != nil {
+		opts.Backoff.report(backoffHost(req), resp.StatusCode, n, opts)
+	}
+	return resp, err
+}
+
+// requestInfo 返回出站请求 req 第 attempt 次重试交给 decidePadding 的 RequestInfo
+// Backoff 对目标主机设置了长度上限时, Profile 被限制在上限以内
+func requestInfo(req *http.Request, opts *PaddingOptions, attempt int) RequestInfo {
+	profile := requestProfile(req, opts)
+	if limit, ok := opts.Backoff.limit(backoffHost(req)); ok {
+		profile = limitProfile(profile, limit)
+	}
+	return RequestInfo{
+		Direction:     DirectionRequest,
+		Request:       req,
+		Protocol:      outboundProtocol(req),
+		ContentLength: requestContentLength(req),
+		Profile:       profile,
+		Attempt:       attempt,
+	}
+}
+
+// requestContentLength 返回出站请求的消息体长度, 未知时返回 -1
+func requestContentLength(req *http.Request) int64 {
+	if req.ContentLength == 0 && req.Body != nil && req.Body != http.NoBody {
+		return -1
+	}
+	return req.ContentLength
+}
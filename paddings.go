@@ -3,6 +3,7 @@ package padding
 import (
 	"log"
 	"net/http"
+	"strconv"
 	"sync"
 
 	"github.com/infinite-iroha/touka"
@@ -13,13 +14,47 @@ import (
 type paddingResponseWriter struct {
 	touka.ResponseWriter
 	opts        *PaddingOptions
+	req         *http.Request
+	stats       *paddingStats // 所属 Padder 的统计信息
 	wroteHeader bool
 	mu          sync.Mutex // 保护 wroteHeader 标志的并发访问
+	writeMu     sync.Mutex // 串行化处理函数与后台 padding 任务对底层 ResponseWriter 的写入
+
+	sse         *sseKeepAlive // SSE 保活注释任务, 仅在启用且响应为 text/event-stream 时存在
+	stream      *streamPadder // 流式 padding 状态, 仅在启用 StreamPadding 且媒体类型有对应 filler 时存在
+	bodyPadding []byte        // 处理链结束后追加到响应体末尾的 padding, 为 nil 时不追加
+	json        *jsonInjector // 缓冲中的 JSON 响应体, 仅在 JSONPaddingField 模式下存在
+	shaper      *rateShaper   // 恒定速率整形状态, 仅在启用 ConstantRate 时存在
+	written     int64         // 已写入底层 ResponseWriter 的响应体字节数 (含 padding)
+	bodyLength  int           // 本次响应决定的响应体 padding 长度
+	fillTarget  int64         // 响应结束时需要以空白字符补齐到的总大小 (UniformErrors 与 MinTotalSize), 0 表示不补齐
+	fillMax     int           // 补齐时最多追加的字节数
+	length      *lengthRecord // 供 LengthFromContext 读取的 padding 决定
+	decision    *Decision     // Strategy 对本次响应的决定, 仅在设置了 Strategy 时存在
+	conn        *connState    // 响应所在连接的 padding 记录, 未使用 ConnContext 且未设置 ConnPadding 时为 nil
+	overhead    int           // MaxOverheadPercent 允许的剩余 padding 长度, -1 表示不限制
+
+	sniffStatus int    // 等待嗅探内容类型时推迟写出的状态码, 0 表示不在等待
+	sniffBuf    []byte // 等待嗅探期间缓冲的响应体
+	sniffed     bool   // 是否已经为本次响应启用过嗅探
+
+	trailerDeclared bool // 是否已通过 Trailer 头部声明了 padding Trailer
+	failed          bool // FailClosed 模式下 padding 生成失败, 响应已被替换为 500
+	switched        bool // 处理函数已经通过 WriteHeader 写出了 101 响应, 劫持后的连接上只有 WebSocket 帧
 }
 
 // WriteHeader 在写入 HTTP 头部之前，添加随机长度的 padding 头部
 // 这是添加 padding 的核心逻辑所在
 func (prw *paddingResponseWriter) WriteHeader(statusCode int) {
+	if informational(statusCode) {
+		// 1xx 临时响应 (如 103 Early Hints) 原样写出, 不添加 padding
+		prw.switched = prw.switched || statusCode == http.StatusSwitchingProtocols
+		prw.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	if prw.sniffing(statusCode) {
+		return
+	}
 	prw.mu.Lock()
 	if prw.wroteHeader {
 		prw.mu.Unlock()
@@ -28,21 +63,148 @@ func (prw *paddingResponseWriter) WriteHeader(statusCode int) {
 	prw.wroteHeader = true
 	prw.mu.Unlock()
 
-	paddingLen, err := randInt(prw.opts.Profile.MinLength, prw.opts.Profile.MaxLength)
+	if prw.opts.StripVary {
+		stripVary(prw.Header(), prw.opts)
+	}
+	if skipResponse(prw.req, statusCode, prw.Header(), prw.opts) {
+		// 对该状态码添加 padding 没有意义或不合适, 原样写出
+		prw.length.set(Length{})
+		prw.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+
+	if prw.opts.DryRun {
+		prw.dryRun(statusCode)
+		return
+	}
+
+	n, decision, err := decideResponsePadding(prw.Header(), prw.responseInfo(statusCode), prw.opts)
+	if prw.opts.Strategy != nil {
+		prw.decision = &decision
+	}
 	if err != nil {
 		// 随机数生成失败是一个罕见的内部错误，记录日志但不中断请求
 		log.Printf("toukaPadding: failed to generate random padding length: %v", err)
-	} else if paddingLen > 0 {
-		paddingData := getPaddingSlice(paddingLen)
-		prw.Header().Set(prw.opts.HeaderName, string(paddingData))
 	}
+	prw.stats.recordHeader(n)
+	notifyPadding(prw.opts, DirectionResponse, prw.req, statusCode, n)
+	if prw.conn != nil {
+		prw.conn.record(n, prw.opts.now())
+	}
+	if prw.overhead >= 0 {
+		prw.overhead = max(prw.overhead-n, 0)
+	}
+	if berr := prw.prepareBodyPadding(statusCode); berr != nil {
+		log.Printf("toukaPadding: failed to generate random body padding length: %v", berr)
+		err = berr
+	}
+	prw.prepareBucket(statusCode)
+	prw.startStreamPadding(statusCode)
+	prw.prepareUniformError(statusCode)
+	prw.prepareMinTotal(statusCode)
+	if err != nil && prw.opts.FailClosed {
+		prw.abort()
+		return
+	}
+	prw.length.set(Length{Header: n, Body: prw.bodyLength})
+	if prw.opts.ConstantRate != nil {
+		prw.startShaping(statusCode)
+	}
+	prw.declareTrailer(statusCode)
 
+	if prw.decision != nil {
+		_ = sleepContext(prw.req.Context(), prw.decision.Delay, prw.opts)
+	}
 	prw.ResponseWriter.WriteHeader(statusCode)
+
+	if prw.opts.SSEKeepAlive != nil && mediaType(prw.Header()) == "text/event-stream" {
+		prw.startSSEKeepAlive()
+	}
+}
+
+// abort 在 FailClosed 模式下 padding 生成失败时调用, 丢弃处理函数设置的头部并以 500 响应,
+// 之后处理函数的写入都会返回 ErrFailClosed
+func (prw *paddingResponseWriter) abort() {
+	prw.failed = true
+	h := prw.Header()
+	clear(h)
+	h.Set("Content-Type", "text/plain; charset=utf-8")
+	h.Set("X-Content-Type-Options", "nosniff")
+	prw.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+	_, _ = prw.ResponseWriter.Write([]byte(http.StatusText(http.StatusInternalServerError) + "\n"))
+}
+
+// selectProfile 为当前响应选择 Profile, 优先级依次为: Preflight 对 OPTIONS 响应的设置、
+// Entity 派生的固定长度、ProfileByStatus 中的状态码、ProfileByContentType 中的媒体类型、默认的 Profile;
+// 后三者再按 ConnPadding 与连接上之前的响应调整
+func (prw *paddingResponseWriter) selectProfile(statusCode int) *PaddingProfile {
+	if p := preflightProfile(prw.req, prw.Header(), prw.opts); p != nil {
+		return p
+	}
+	if p := entityProfile(prw.req, prw.Header(), prw.opts.protocolProfile(protocolOf(prw.req), prw.opts.now()), "header", prw.opts); p != nil {
+		return p
+	}
+	if p, ok := prw.opts.ProfileByStatus[statusCode]; ok {
+		return connProfile(prw.conn, sessionProfile(prw.req, p, prw.opts), prw.opts)
+	}
+	if p := profileForContentType(prw.opts.ProfileByContentType, mediaType(prw.Header())); p != nil {
+		return connProfile(prw.conn, sessionProfile(prw.req, p, prw.opts), prw.opts)
+	}
+	return connProfile(prw.conn, sessionProfile(prw.req, prw.opts.protocolProfile(protocolOf(prw.req), prw.opts.now()), prw.opts), prw.opts)
+}
+
+// responseInfo 返回本次响应交给 decidePadding 的 RequestInfo
+func (prw *paddingResponseWriter) responseInfo(statusCode int) RequestInfo {
+	prw.conn = connStateFor(prw.req, prw.opts)
+	info := RequestInfo{
+		Direction:     DirectionResponse,
+		Request:       prw.req,
+		StatusCode:    statusCode,
+		Protocol:      protocolOf(prw.req),
+		ContentLength: responseContentLength(prw.Header()),
+		Profile:       prw.selectProfile(statusCode),
+	}
+	if prw.conn != nil {
+		info.ConnResponses, _, info.PreviousLength = prw.conn.snapshot()
+	}
+	info.Profile, prw.overhead = overheadProfile(info.Profile, info.ContentLength, prw.opts)
+	return info
+}
+
+// responseContentLength 解析处理函数设置的 Content-Length, 未设置或无效时返回 -1
+func responseContentLength(h http.Header) int64 {
+	cl, err := strconv.ParseInt(h.Get("Content-Length"), 10, 64)
+	if err != nil || cl < 0 {
+		return -1
+	}
+	return cl
 }
 
 // Write 确保在第一次写入数据前头部（包括 padding）已被发送
 // 如果 WriteHeader 尚未被调用，它会隐式地以 200 OK 状态调用它
 func (prw *paddingResponseWriter) Write(data []byte) (int, error) {
+	prw.ensureHeader()
+	if prw.sniffStatus != 0 {
+		return prw.bufferSniff(data)
+	}
+	if prw.failed {
+		return 0, ErrFailClosed
+	}
+
+	prw.writeMu.Lock()
+	defer prw.writeMu.Unlock()
+	if prw.json != nil {
+		return prw.bufferJSON(data)
+	}
+	n, err := prw.writeBody(data)
+	if err == nil && prw.stream != nil {
+		err = prw.writeStreamFiller(data)
+	}
+	return n, err
+}
+
+// ensureHeader 在头部尚未写出时以 200 OK 写出
+func (prw *paddingResponseWriter) ensureHeader() {
 	// 使用双重检查锁定模式来减少锁的竞争开销
 	if !prw.wroteHeader {
 		prw.mu.Lock()
@@ -54,46 +216,139 @@ func (prw *paddingResponseWriter) Write(data []byte) (int, error) {
 			prw.mu.Unlock()
 		}
 	}
-	return prw.ResponseWriter.Write(data)
+}
+
+// writeBody 将响应体数据写入底层 ResponseWriter, 调用方需持有 writeMu
+func (prw *paddingResponseWriter) writeBody(data []byte) (int, error) {
+	var (
+		n   int
+		err error
+	)
+	switch {
+	case prw.shaper != nil:
+		n, err = prw.writeShaped(data)
+	case prw.opts.Rechunk != nil:
+		n, err = prw.writeRechunked(data)
+	default:
+		n, err = prw.ResponseWriter.Write(data)
+	}
+	prw.written += int64(n)
+	if prw.sse != nil {
+		prw.sse.observe(data[:n])
+	}
+	return n, err
+}
+
+// Flush 与后台 padding 任务的写入互斥, 避免并发操作底层 ResponseWriter
+func (prw *paddingResponseWriter) Flush() {
+	if err := prw.releaseSniff(); err != nil {
+		log.Printf("toukaPadding: failed to write buffered body: %v", err)
+	}
+	prw.writeMu.Lock()
+	defer prw.writeMu.Unlock()
+	if prw.json != nil {
+		// 处理函数需要流式输出, 放弃缓冲注入
+		if err := prw.abandonJSON(); err != nil {
+			log.Printf("toukaPadding: failed to write buffered JSON body: %v", err)
+		}
+	}
+	prw.ResponseWriter.Flush()
+}
+
+// finish 在处理链执行完毕后调用, 追加响应体 padding, 停止所有仍在运行的后台 padding 任务,
+// 最后根据响应体的最终大小设置 padding Trailer
+func (prw *paddingResponseWriter) finish() {
+	if err := prw.releaseSniff(); err != nil {
+		log.Printf("toukaPadding: failed to write buffered body: %v", err)
+	}
+	diagnose(RequestInfo{Direction: DirectionResponse, Request: prw.req}, "padding.body", prw.opts, prw.writeBodyPadding)
+	prw.finishShaping()
+	if prw.sse != nil {
+		prw.sse.shutdown()
+	}
+	if err := prw.writeTrailer(); err != nil {
+		log.Printf("toukaPadding: failed to generate random trailer padding length: %v", err)
+	}
+	if prw.opts.Audit != nil && prw.wroteHeader && !prw.failed {
+		l, _ := prw.length.get()
+		prw.opts.Audit.record(int64(headerSize(prw.Header(), ""))+prw.written, l.Header > 0 || l.Body > 0)
+	}
+	if prw.opts.UniformErrors != nil && prw.wroteHeader && !prw.failed {
+		if status := prw.ResponseWriter.Status(); status >= 200 && status < 300 {
+			prw.opts.UniformErrors.record(int64(headerSize(prw.Header(), "")) + prw.written)
+		}
+	}
 }
 
 // ToukaPaddingS 返回一个 HTTP Padding 中间件
 // 此中间件通过在 HTTP 响应头中添加一个具有随机长度和内容的头部（默认为 "T-Padding"），
 // 来改变每个响应的加密后总长度这旨在干扰基于流量大小的审查和指纹识别系统
+// 需要在运行时修改配置时, 使用 NewPadder(opts).Server() 并通过 Reload 替换配置
 func ToukaPaddingS(opts PaddingOptions) touka.HandlerFunc {
 	// --- 验证和设置配置默认值 ---
-	if opts.HeaderName == "" {
-		opts.HeaderName = "T-Padding"
-	}
-	if opts.Profile == nil {
-		opts.Profile = &ProfileDefault
-	}
-	if opts.Profile.MaxLength > maxPaddingSize {
-		log.Printf("toukaPadding: Warning - Profile.MaxLength (%d) exceeds maxPaddingSize (%d). It will be capped.",
-			opts.Profile.MaxLength, maxPaddingSize)
-		opts.Profile.MaxLength = maxPaddingSize
+	return newPadder(opts, "toukaPadding").Server()
+}
+
+// Server 返回使用 p 当前配置的服务端 padding 中间件
+func (p *Padder) Server() touka.HandlerFunc {
+	return p.serve
+}
+
+// serve 是 Padder 服务端中间件的处理函数
+func (p *Padder) serve(c *touka.Context) {
+	p.serveRecover(c, nil)
+}
+
+// serveRecover 与 serve 相同, recovery 不为 nil 时还会在 padding 写入器之内恢复处理链中的 panic
+func (p *Padder) serveRecover(c *touka.Context, recovery touka.PanicHandlerFunc) {
+	// 每个请求使用一份配置快照, 处理期间的 Reload 不会影响本次响应
+	opts := p.load()
+	length := &lengthRecord{}
+	c.Set(lengthKey, length)
+	recordInbound(c, opts)
+	if p.skip(c.Request, opts) {
+		length.set(Length{})
+		opts.Audit.record(-1, false)
+		next(c, recovery)
+		return
 	}
-	if opts.Profile.MinLength < 0 {
-		opts.Profile.MinLength = 0
+	if opts.Observe.observing() {
+		// 观察阶段不添加 padding, 只记录真实的响应大小
+		length.set(Length{})
+		next(c, recovery)
+		if c.Writer.Written() && !c.Writer.IsHijacked() {
+			opts.Observe.record(headerSize(c.Writer.Header(), ""), c.Writer.Size())
+		}
+		return
 	}
-	if opts.Profile.MinLength > opts.Profile.MaxLength {
-		log.Printf("toukaPadding: Warning - Profile.MinLength (%d) is greater than MaxLength (%d). Adjusting to be equal.",
-			opts.Profile.MinLength, opts.Profile.MaxLength)
-		opts.Profile.MinLength = opts.Profile.MaxLength
+	quantizeRange(c.Request.Header, opts.RangeQuantum)
+	originalWriter := c.Writer
+	prw := &paddingResponseWriter{
+		ResponseWriter: originalWriter,
+		opts:           opts,
+		req:            c.Request,
+		stats:          &p.stats,
+		length:         length,
 	}
+	c.Writer = prw
 
-	return func(c *touka.Context) {
-		originalWriter := c.Writer
-		prw := &paddingResponseWriter{
-			ResponseWriter: originalWriter,
-			opts:           &opts,
-		}
-		c.Writer = prw
+	// 不需要 defer 恢复 c.Writer，因为 c.Writer 是请求作用域的
+	// Touka 框架的 Context.reset 会在下一个请求中处理 ResponseWriter 的重置或替换
+	defer prw.finish()
+	next(c, recovery)
+}
 
-		// 不需要 defer 恢复 c.Writer，因为 c.Writer 是请求作用域的
-		// Touka 框架的 Context.reset 会在下一个请求中处理 ResponseWriter 的重置或替换
-		c.Next()
+// next 执行后续处理链; recovery 不为 nil 时在返回前恢复 panic 并交给它写出错误响应,
+// 此时响应仍经过 padding 写入器, 且发生在响应体 padding 与 Trailer 写出之前
+func next(c *touka.Context, recovery touka.PanicHandlerFunc) {
+	if recovery != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				recovery(c, r)
+			}
+		}()
 	}
+	c.Next()
 }
 
 // 确保 paddingResponseWriter 实现了 Touka 的 ResponseWriter 接口
@@ -0,0 +1,86 @@
+package paddingtest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/fenthope/padding"
+)
+
+// PaddingHeaders 返回 h 中按 opts 可识别的 padding 头部名称到其值的映射 (不含伪装头部)
+func PaddingHeaders(h http.Header, opts padding.PaddingOptions) map[string]string {
+	found := map[string]string{}
+	for _, name := range padding.HeaderNames(opts) {
+		if v := h.Get(name); v != "" {
+			found[name] = v
+		}
+	}
+	return found
+}
+
+// AssertPadded 断言 resp 携带了按 opts 配置的 padding
+// 启用伪装模式时要求至少一个伪装头部存在; 否则要求至少一个 padding 头部存在,
+// 且在长度直接由 Profile 采样的配置下, 每个头部的长度都落在 Profile 的范围内
+func AssertPadded(t testing.TB, resp *http.Response, opts padding.PaddingOptions) {
+	t.Helper()
+	if len(opts.Decoys) > 0 {
+		for _, d := range opts.Decoys {
+			if resp.Header.Get(d.Name) != "" {
+				return
+			}
+		}
+		t.Errorf("paddingtest: response has none of the %d decoy headers", len(opts.Decoys))
+		return
+	}
+	found := PaddingHeaders(resp.Header, opts)
+	if len(found) == 0 {
+		t.Errorf("paddingtest: response has no padding header (want one of %v)", padding.HeaderNames(opts))
+		return
+	}
+	if !rawProfileLengths(opts) {
+		return
+	}
+	p := opts.Profile
+	if p == nil {
+		p = &padding.ProfileDefault
+	}
+	for name, v := range found {
+		if len(v) < p.MinLength || len(v) > p.MaxLength {
+			t.Errorf("paddingtest: %s has length %d, want within [%d, %d]", name, len(v), p.MinLength, p.MaxLength)
+		}
+	}
+}
+
+// AssertNotPadded 断言 resp 没有携带任何可识别的 padding 头部
+func AssertNotPadded(t testing.TB, resp *http.Response, opts padding.PaddingOptions) {
+	t.Helper()
+	if found := PaddingHeaders(resp.Header, opts); len(found) > 0 {
+		t.Errorf("paddingtest: response unexpectedly has padding headers %v", keys(found))
+	}
+}
+
+// rawProfileLengths 报告 padding 头部的长度是否直接由 Profile 采样而来, 从而可以按其范围校验
+func rawProfileLengths(opts padding.PaddingOptions) bool {
+	if opts.TargetHeaderSize > 0 || opts.HeaderSizeBucket > 0 || opts.AlignFirstWrite > 0 || opts.MinTotalSize > 0 {
+		return false
+	}
+	if opts.WireSize || opts.StructuredField != padding.StructuredFieldNone || len(opts.AuthKey) > 0 {
+		return false
+	}
+	if opts.Load != nil || opts.Budget != nil {
+		return false
+	}
+	if len(opts.ProfileByStatus) > 0 || len(opts.ProfileByContentType) > 0 || len(opts.ProfileByHost) > 0 {
+		return false
+	}
+	return opts.Profile == nil || (len(opts.Profile.Components) == 0 && opts.Profile.BlockSize == 0)
+}
+
+// keys 返回 m 的键
+func keys(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package paddingtest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fenthope/padding"
+	"github.com/infinite-iroha/touka"
+)
+
+// 以下函数是可复用的基准与分配量检查, 供下游项目在自己的 _test.go 中针对实际配置调用, 例如:
+//
+//	func BenchmarkServerPadding(b *testing.B) { paddingtest.BenchmarkServerPadding(b, myOpts) }
+//
+//	func TestPaddingAllocs(t *testing.T) {
+//		if n := paddingtest.ServerAllocs(myOpts, 100); n > 40 {
+//			t.Errorf("server padding allocs = %v, want <= 40", n)
+//		}
+//	}
+
+// benchBody 是基准中处理函数写出的响应体
+const benchBody = `{"message":"ok"}`
+
+// newBenchServer 返回安装了 padding 中间件、对 GET / 返回小型 JSON 响应的 touka 引擎
+func newBenchServer(opts padding.PaddingOptions) *touka.Engine {
+	r := touka.New()
+	r.Use(padding.ToukaPaddingS(opts))
+	r.GET("/", func(c *touka.Context) {
+		c.Writer.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(c.Writer, benchBody)
+	})
+	return r
+}
+
+// nopTransport 是不发出任何网络请求的 RoundTripper
+type nopTransport struct{}
+
+// RoundTrip 返回一个空的 204 响应
+func (nopTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody, Request: req}, nil
+}
+
+// BenchmarkServerPadding 测量服务端中间件处理一个小型响应的开销
+func BenchmarkServerPadding(b *testing.B, opts padding.PaddingOptions) {
+	r := newBenchServer(opts)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	b.ReportAllocs()
+	for b.Loop() {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+// BenchmarkClientPadding 测量客户端中间件为一个出站请求添加 padding 的开销
+func BenchmarkClientPadding(b *testing.B, opts padding.PaddingOptions) {
+	rt := padding.ToukaPadding(opts)(nopTransport{})
+	b.ReportAllocs()
+	for b.Loop() {
+		req, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+		if _, err := rt.RoundTrip(req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkValue 测量按 profile 采样并生成一个 padding 值 (数据池切片与字符串转换) 的开销
+func BenchmarkValue(b *testing.B, profile *padding.PaddingProfile) {
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := padding.Value(profile); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// ServerAllocs 返回服务端中间件处理一个小型响应平均的内存分配次数 (含 touka 与 httptest 自身的分配)
+func ServerAllocs(opts padding.PaddingOptions, runs int) float64 {
+	r := newBenchServer(opts)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	return testing.AllocsPerRun(runs, func() {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	})
+}
+
+// ClientAllocs 返回客户端中间件为一个出站请求添加 padding 平均的内存分配次数 (含请求本身的分配)
+func ClientAllocs(opts padding.PaddingOptions, runs int) float64 {
+	rt := padding.ToukaPadding(opts)(nopTransport{})
+	return testing.AllocsPerRun(runs, func() {
+		req, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+		_, _ = rt.RoundTrip(req)
+	})
+}
@@ -0,0 +1,49 @@
+package paddingtest
+
+import (
+	"testing"
+
+	"github.com/fenthope/padding"
+)
+
+// allocCases 是基准与分配量检查覆盖的配置; server 与 client 是各自的分配次数上限,
+// 在当前实现的基础上留有少量余地, 超出说明热路径上引入了新的分配
+var allocCases = []struct {
+	name           string
+	opts           padding.PaddingOptions
+	server, client float64
+}{
+	{"default", padding.PaddingOptions{}, 34, 26},
+	{"self-describing", padding.PaddingOptions{SelfDescribing: true}, 40, 32},
+	{"signed", padding.PaddingOptions{AuthKey: []byte("bench-key")}, 34, 42},
+	{"skipped", padding.PaddingOptions{SkipPaths: []string{"/"}}, 18, 8},
+}
+
+func TestAllocs(t *testing.T) {
+	for _, c := range allocCases {
+		t.Run(c.name, func(t *testing.T) {
+			if n := ServerAllocs(c.opts, 200); n > c.server {
+				t.Errorf("server padding allocs = %v, want <= %v", n, c.server)
+			}
+			if n := ClientAllocs(c.opts, 200); n > c.client {
+				t.Errorf("client padding allocs = %v, want <= %v", n, c.client)
+			}
+		})
+	}
+}
+
+func BenchmarkServer(b *testing.B) {
+	for _, c := range allocCases {
+		b.Run(c.name, func(b *testing.B) { BenchmarkServerPadding(b, c.opts) })
+	}
+}
+
+func BenchmarkClient(b *testing.B) {
+	for _, c := range allocCases {
+		b.Run(c.name, func(b *testing.B) { BenchmarkClientPadding(b, c.opts) })
+	}
+}
+
+func BenchmarkValueDefault(b *testing.B) {
+	BenchmarkValue(b, &padding.ProfileDefault)
+}
@@ -0,0 +1,231 @@
+package paddingtest
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	mrand "math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fenthope/padding"
+	"github.com/infinite-iroha/touka"
+	"golang.org/x/net/http/httpguts"
+)
+
+// 以下函数是可复用的模糊测试目标, 供下游项目在自己的 _test.go 中调用, 例如:
+//
+//	func FuzzProfile(f *testing.F) { paddingtest.FuzzProfile(f) }
+//
+// 再以 go test -fuzz=FuzzProfile 运行; 未指定 -fuzz 时只执行内置的种子输入, 可以作为普通测试放在 CI 中
+// 模糊输入同时被用作 RandSource, 长度采样与数据池偏移的每一个随机数都由输入决定,
+// 从而覆盖随机数取到边界值时的采样与切片逻辑
+
+// fuzzRand 将模糊输入用作 RandSource: 先按顺序输出输入中的字节, 用尽后改为输出由输入播种的 ChaCha8 流
+// 输入决定最初的每一个随机数, 覆盖取到边界值的情况; 之后的伪随机流使拒绝采样总能在有限次读取后结束
+type fuzzRand struct {
+	data []byte
+	gen  *mrand.ChaCha8
+}
+
+// newFuzzRand 以模糊输入 data 创建 fuzzRand
+func newFuzzRand(data []byte) *fuzzRand {
+	return &fuzzRand{data: data, gen: mrand.NewChaCha8(sha256.Sum256(data))}
+}
+
+// Read 总是返回 len(p), nil
+func (r *fuzzRand) Read(p []byte) (int, error) {
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	_, _ = r.gen.Read(p[n:])
+	return len(p), nil
+}
+
+// maxValueLength 是单个 padding 值的最大长度 (数据池的最小大小)
+const maxValueLength = 4096
+
+// fuzzRange 将任意的 lo、hi 映射为 [0, maxValueLength] 内的合法范围
+func fuzzRange(lo, hi int) (int, int) {
+	lo = ((lo % (maxValueLength + 1)) + maxValueLength + 1) % (maxValueLength + 1)
+	hi = ((hi % (maxValueLength + 1)) + maxValueLength + 1) % (maxValueLength + 1)
+	return min(lo, hi), max(lo, hi)
+}
+
+// fuzzDistributions 是 FuzzProfile 按输入选择的分布
+var fuzzDistributions = []padding.Distribution{
+	padding.DistributionUniform,
+	padding.DistributionNormal,
+	padding.DistributionExponential,
+}
+
+// checkValues 断言 h 中每个 padding 头部的值都是合法的头部值, 长度落在 [lo, hi] 内, 返回找到的值
+func checkValues(t *testing.T, h http.Header, opts padding.PaddingOptions, lo, hi int) []string {
+	t.Helper()
+	var values []string
+	for name, v := range PaddingHeaders(h, opts) {
+		if !httpguts.ValidHeaderFieldValue(v) || strings.TrimSpace(v) != v {
+			t.Fatalf("%s has a value that is not a legal header value: %q", name, v)
+		}
+		if len(v) < lo || len(v) > hi {
+			t.Fatalf("%s has length %d, want within [%d, %d]", name, len(v), lo, hi)
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// FuzzProfile 以任意的范围、分布与双分量组合采样 padding 头部, 断言长度总在 [MinLength, MaxLength] 内
+// (组合策略为各分量范围的并集), 且值只包含头部中合法的字节
+func FuzzProfile(f *testing.F) {
+	f.Add([]byte{}, 0, 0, 0, 0, 0, uint8(0))
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 96, 1024, 0, 0, 0, uint8(1))
+	f.Add([]byte{0x80, 0x00, 0x01}, 0, maxValueLength, 32, 64, 1, uint8(2))
+	f.Add([]byte{0x7f}, maxValueLength, maxValueLength, 0, 1, 3, uint8(1))
+	f.Fuzz(func(t *testing.T, seed []byte, lo, hi, lo2, hi2, weight int, dist uint8) {
+		lo, hi = fuzzRange(lo, hi)
+		profile := &padding.PaddingProfile{
+			MinLength:    lo,
+			MaxLength:    hi,
+			Distribution: fuzzDistributions[int(dist)%len(fuzzDistributions)],
+		}
+		if weight%4 != 0 {
+			lo2, hi2 = fuzzRange(lo2, hi2)
+			second := &padding.PaddingProfile{MinLength: lo2, MaxLength: hi2}
+			profile = padding.CompositeProfile(
+				padding.WeightedProfile{Profile: profile, Weight: 1},
+				padding.WeightedProfile{Profile: second, Weight: float64(weight % 4)},
+			)
+			lo, hi = min(lo, lo2), max(hi, hi2)
+		}
+		opts := padding.PaddingOptions{Profile: profile, Rand: newFuzzRand(seed)}
+		h := http.Header{}
+		if err := padding.ApplyToHeader(h, opts); err != nil {
+			t.Fatalf("ApplyToHeader: %v", err)
+		}
+		values := checkValues(t, h, opts, lo, hi)
+		if len(values) == 0 && lo > 0 {
+			t.Fatalf("no padding header set for a profile with MinLength %d", lo)
+		}
+	})
+}
+
+// fuzzEncoders 是 FuzzPool 按输入选择的编码器, nil 表示使用字符集
+var fuzzEncoders = []padding.PoolEncoder{
+	nil,
+	base64.RawURLEncoding,
+	base64.StdEncoding,
+	base32.StdEncoding,
+}
+
+// FuzzPool 以任意的字符集与编码器创建数据池并生成固定长度的 padding 头部
+// 断言 NewPoolStrict 只接受合法的字符集, 生成的值长度准确、只包含字符集 (或编码器输出) 中的字节
+func FuzzPool(f *testing.F) {
+	f.Add("", uint8(0), []byte{}, 16)
+	f.Add(padding.CharsetToken, uint8(0), []byte{0xff, 0x00}, maxValueLength)
+	f.Add("ab", uint8(0), []byte{0x01}, 1)
+	f.Add("a a", uint8(0), []byte{}, 8)
+	f.Add("", uint8(3), []byte{0xfe}, 4095)
+	f.Fuzz(func(t *testing.T, charset string, encoder uint8, seed []byte, length int) {
+		enc := fuzzEncoders[int(encoder)%len(fuzzEncoders)]
+		pool, err := padding.NewPoolStrict(padding.PoolOptions{Charset: charset, Encoder: enc})
+		if err != nil {
+			return
+		}
+		for i := 0; i < len(charset); i++ {
+			c := charset[i]
+			if c <= ' ' || c >= 0x7f || strings.IndexByte(charset[i+1:], c) >= 0 {
+				t.Fatalf("NewPoolStrict accepted charset %q with byte 0x%02x at offset %d", charset, c, i)
+			}
+		}
+		length, _ = fuzzRange(length, length)
+		opts := padding.PaddingOptions{
+			Profile: &padding.PaddingProfile{MinLength: length, MaxLength: length},
+			Pool:    pool,
+			Rand:    newFuzzRand(seed),
+		}
+		h := http.Header{}
+		if err := padding.ApplyToHeader(h, opts); err != nil {
+			t.Fatalf("ApplyToHeader: %v", err)
+		}
+		for _, v := range checkValues(t, h, opts, length, length) {
+			if charset == "" || enc != nil {
+				continue
+			}
+			if i := strings.IndexFunc(v, func(r rune) bool { return !strings.ContainsRune(charset, r) }); i >= 0 {
+				t.Fatalf("value has byte 0x%02x at offset %d outside charset %q", v[i], i, charset)
+			}
+		}
+	})
+}
+
+// FuzzValue 以任意输入检查自描述值的解析, 并以任意长度生成自描述值
+// 断言 ParseValue 不会 panic, 接受的值中各段数据都位于值之内; 生成的值能被解析, 且 (不小于最短值时) 长度准确
+func FuzzValue(f *testing.F) {
+	f.Add("p1.r4:abcd", 10)
+	f.Add("p1.r0004:abcd.s3:xyz", 6)
+	f.Add("p1.", 0)
+	f.Add("p1.r9999:", maxValueLength)
+	f.Fuzz(func(t *testing.T, value string, length int) {
+		segments, err := padding.ParseValue(value)
+		if (err == nil) != padding.IsPaddingValue(value) {
+			t.Fatalf("ParseValue and IsPaddingValue disagree on %q", value)
+		}
+		if err == nil {
+			total := 0
+			for _, s := range segments {
+				total += len(s.Data)
+			}
+			if total > len(value) {
+				t.Fatalf("segments of %q hold %d bytes, more than the value itself", value, total)
+			}
+		}
+
+		length, _ = fuzzRange(length, length)
+		opts := padding.PaddingOptions{
+			Profile:        &padding.PaddingProfile{MinLength: length, MaxLength: length},
+			SelfDescribing: true,
+		}
+		h := http.Header{}
+		if err := padding.ApplyToHeader(h, opts); err != nil {
+			t.Fatalf("ApplyToHeader: %v", err)
+		}
+		for _, v := range checkValues(t, h, opts, 0, max(length, 6)) {
+			if !padding.IsPaddingValue(v) {
+				t.Fatalf("generated value %q is not a valid self-describing value", v)
+			}
+			if length >= 6 && len(v) != length {
+				t.Fatalf("generated value has length %d, want %d", len(v), length)
+			}
+		}
+	})
+}
+
+// FuzzStrategy 让 Strategy 返回任意的头部长度 (包括负数与超过上限的值), 经服务端中间件写出响应
+// 断言每个 padding 头部的长度都在 [1, 4096] 内且是合法的头部值, 中间件不会 panic
+func FuzzStrategy(f *testing.F) {
+	f.Add(0, 0, []byte{})
+	f.Add(-1, 1<<20, []byte{0xff})
+	f.Add(maxValueLength, maxValueLength+1, []byte{0x00, 0x80})
+	f.Fuzz(func(t *testing.T, first, second int, seed []byte) {
+		opts := padding.PaddingOptions{
+			Rand: newFuzzRand(seed),
+			Strategy: padding.StrategyFunc(func(padding.RequestInfo) padding.Decision {
+				return padding.Decision{Headers: []padding.HeaderPadding{
+					{Length: first},
+					{Name: "X-Fuzz-Padding", Length: second},
+				}}
+			}),
+		}
+		r := touka.New()
+		r.Use(padding.ToukaPaddingS(opts))
+		r.GET("/", func(c *touka.Context) { c.String(http.StatusOK, "ok") })
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		checkValues(t, w.Header(), opts, 1, maxValueLength)
+		if v := w.Header().Get("X-Fuzz-Padding"); len(v) > maxValueLength || !httpguts.ValidHeaderFieldValue(v) {
+			t.Fatalf("X-Fuzz-Padding has length %d or illegal bytes", len(v))
+		}
+	})
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 Infinite-Iroha. All rights reserved.
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+// Package paddingtest 提供用于测试 padding 配置的工具: 记录收到的 padding 头部的测试服务器、
+// 针对响应的断言函数, 以及可复现的确定性随机数来源
+package paddingtest
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/fenthope/padding"
+)
+
+// Received 是测试服务器收到的一个请求中的 padding 信息
+type Received struct {
+	Method string
+	// Host 是请求的目标主机; 经代理的请求与 CONNECT 中为最终目标, 而不是代理本身
+	Host string
+	Path string
+	// Headers 是请求中可识别的 padding 头部名称到其值的映射
+	Headers map[string]string
+}
+
+// Server 是一个基于 httptest 的测试服务器, 记录每个请求中携带的 padding 头部
+// 可用于验证客户端中间件 (ToukaPadding) 的配置是否生效
+type Server struct {
+	*httptest.Server
+
+	opts     padding.PaddingOptions
+	mu       sync.Mutex
+	received []Received
+}
+
+// NewServer 启动一个测试服务器, opts 应与被测客户端使用的配置一致, 用于识别 padding 头部名称
+// handler 为 nil 时对所有请求返回 204; 调用方负责调用 Close
+func NewServer(opts padding.PaddingOptions, handler http.Handler) *Server {
+	s := &Server{opts: opts}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.record(r)
+		if handler == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	return s
+}
+
+// record 记录请求中的 padding 头部
+func (s *Server) record(r *http.Request) {
+	rec := Received{Method: r.Method, Host: r.Host, Path: r.URL.Path, Headers: map[string]string{}}
+	for _, name := range padding.HeaderNames(s.opts) {
+		if v := r.Header.Get(name); v != "" {
+			rec.Headers[name] = v
+		}
+	}
+	s.mu.Lock()
+	s.received = append(s.received, rec)
+	s.mu.Unlock()
+}
+
+// Received 返回目前为止收到的所有请求的记录副本
+func (s *Server) Received() []Received {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Received(nil), s.received...)
+}
+
+// Reset 清空已记录的请求
+func (s *Server) Reset() {
+	s.mu.Lock()
+	s.received = nil
+	s.mu.Unlock()
+}
+
+// lockedRand 是并发安全的确定性随机数来源
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.ChaCha8
+}
+
+// Read 实现 padding.RandSource
+func (l *lockedRand) Read(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Read(p)
+}
+
+// NewRand 返回一个由 seed 决定的确定性随机数来源, 可设置为 PaddingOptions.Rand 以复现 padding 序列
+// 返回值可被并发使用, 但只有串行的请求序列才能被精确复现
+func NewRand(seed uint64) padding.RandSource {
+	var key [32]byte
+	for i := range 4 {
+		for j := range 8 {
+			key[i*8+j] = byte(seed >> (8 * j))
+		}
+	}
+	return &lockedRand{r: rand.NewChaCha8(key)}
+}
@@ -0,0 +1,75 @@
+package paddingtest
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/fenthope/padding"
+)
+
+// NewProxy 启动一个记录 padding 头部的 HTTP 正向代理, 可设置为 http.Transport.Proxy 验证客户端经代理时的行为:
+// 以绝对 URI 发来的 http:// 请求被记录后转发给目标; https:// 目标使用的 CONNECT 请求被记录后建立 TCP 隧道,
+// 隧道内的流量不可见, 因此只有 CONNECT 自身的头部会被记录 (Method 为 "CONNECT", Host 为目标地址)
+// opts 应与被测客户端使用的配置一致; 调用方负责调用 Close
+func NewProxy(opts padding.PaddingOptions) *Server {
+	s := &Server{opts: opts}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.record(r)
+		if r.Method == http.MethodConnect {
+			tunnel(w, r)
+			return
+		}
+		forward(w, r)
+	}))
+	return s
+}
+
+// tunnel 连接 CONNECT 请求的目标, 并在客户端连接与目标连接之间双向转发数据
+func tunnel(w http.ResponseWriter, r *http.Request) {
+	upstream, err := net.Dial("tcp", r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, buf, err := hj.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	if _, err := io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+		upstream.Close()
+		conn.Close()
+		return
+	}
+	go func() {
+		_, _ = io.Copy(upstream, buf)
+		upstream.Close()
+	}()
+	_, _ = io.Copy(conn, upstream)
+	conn.Close()
+}
+
+// forward 将以绝对 URI 发来的请求转发给目标并回写响应
+func forward(w http.ResponseWriter, r *http.Request) {
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	resp, err := http.DefaultTransport.RoundTrip(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for k, vv := range resp.Header {
+		w.Header()[k] = vv
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
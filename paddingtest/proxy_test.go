@@ -0,0 +1,101 @@
+package paddingtest
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/fenthope/padding"
+)
+
+// proxyOptions 返回总是添加 padding 的配置, pad 决定是否同时填充 CONNECT
+func proxyOptions(pad bool) padding.PaddingOptions {
+	return padding.PaddingOptions{
+		Profile:    &padding.PaddingProfile{MinLength: 32, MaxLength: 64},
+		PadConnect: pad,
+	}
+}
+
+// proxyClient 返回经 proxy 发出请求、安装了 p 的客户端中间件的 http.Client
+func proxyClient(t *testing.T, p *padding.Padder, proxy *Server) *http.Client {
+	t.Helper()
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := &http.Transport{
+		Proxy:           http.ProxyURL(proxyURL),
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	t.Cleanup(tr.CloseIdleConnections)
+	if !p.ConfigureProxyTransport(tr) {
+		t.Fatal("ConfigureProxyTransport refused a fresh Transport")
+	}
+	return &http.Client{Transport: p.Client()(tr)}
+}
+
+func TestProxyHTTPTarget(t *testing.T) {
+	for _, pad := range []bool{false, true} {
+		opts := proxyOptions(pad)
+		target := NewServer(opts, nil)
+		defer target.Close()
+		proxy := NewProxy(opts)
+		defer proxy.Close()
+
+		resp, err := proxyClient(t, padding.NewPadder(opts), proxy).Get(target.URL + "/plain")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		// 绝对 URI 形式的请求对代理可见, 无论 PadConnect 如何都携带 padding
+		for name, s := range map[string]*Server{"proxy": proxy, "target": target} {
+			got := s.Received()
+			if len(got) != 1 || got[0].Method != http.MethodGet || len(got[0].Headers) == 0 {
+				t.Errorf("PadConnect=%v: %s received %+v, want one padded GET", pad, name, got)
+			}
+		}
+	}
+}
+
+func TestProxyConnectTunnel(t *testing.T) {
+	for _, pad := range []bool{false, true} {
+		opts := proxyOptions(pad)
+		var (
+			mu    sync.Mutex
+			inner []map[string]string
+		)
+		target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			inner = append(inner, PaddingHeaders(r.Header, opts))
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer target.Close()
+		proxy := NewProxy(opts)
+		defer proxy.Close()
+
+		resp, err := proxyClient(t, padding.NewPadder(opts), proxy).Get(target.URL + "/secure")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		got := proxy.Received()
+		if len(got) != 1 || got[0].Method != http.MethodConnect {
+			t.Fatalf("PadConnect=%v: proxy received %+v, want one CONNECT", pad, got)
+		}
+		if padded := len(got[0].Headers) > 0; padded != pad {
+			t.Errorf("PadConnect=%v: CONNECT padding headers = %v", pad, got[0].Headers)
+		}
+		// 隧道内的请求总是携带 padding
+		mu.Lock()
+		if len(inner) != 1 || len(inner[0]) == 0 {
+			t.Errorf("PadConnect=%v: tunneled request padding = %v, want padded", pad, inner)
+		}
+		mu.Unlock()
+	}
+}
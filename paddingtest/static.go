@@ -0,0 +1,114 @@
+package paddingtest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/fenthope/padding"
+)
+
+// AssertStaticFile 对提供静态文件的 url 发出完整请求与 Range 请求, 断言:
+//   - 两个响应都携带了按 opts 配置的 padding 头部
+//   - 完整响应的 Content-Length 与实际长度一致, 以 content 开头, 多出的部分只有空白字符;
+//     设置了 ContentLengthBucket 且追加了空白字符时 (媒体类型在 ContentLengthBucketTypes 的作用范围内) 长度为其整数倍
+//   - Range 请求与 If-Range 的处理符合 AssertRange
+//
+// content 是文件的原始内容, 长度至少为 3 字节; 处理函数需要为响应设置 ETag 或 Last-Modified, If-Range 才会生效
+func AssertStaticFile(t testing.TB, url string, content []byte, opts padding.PaddingOptions) {
+	t.Helper()
+	resp, body := get(t, url, "", "")
+	if resp == nil {
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("paddingtest: full request: status %d, want 200", resp.StatusCode)
+		return
+	}
+	AssertPadded(t, resp, opts)
+	if cl := resp.Header.Get("Content-Length"); cl != strconv.Itoa(len(body)) {
+		t.Errorf("paddingtest: full request: Content-Length %q does not match body length %d", cl, len(body))
+	}
+	if !bytes.HasPrefix(body, content) {
+		t.Errorf("paddingtest: full request: body does not start with the file content")
+	} else if extra := body[len(content):]; len(bytes.TrimSpace(extra)) != 0 {
+		t.Errorf("paddingtest: full request: %d trailing bytes are not whitespace", len(extra))
+	}
+	if b := opts.ContentLengthBucket; b > 0 && len(body) > len(content) && len(body)%b != 0 {
+		t.Errorf("paddingtest: full request: body length %d is not a multiple of ContentLengthBucket (%d)", len(body), b)
+	}
+
+	AssertRange(t, url, content, 1, len(content)-2, opts)
+}
+
+// AssertRange 以 Range 请求 content 中 [first, last] 区间的字节, 断言响应为携带 padding 头部的 206,
+// Content-Range 描述的区间覆盖了请求的区间 (设置 RangeQuantum 时可以更大), 且响应体恰好是该区间的原始字节;
+// 随后以不匹配的 If-Range 重复请求, 断言得到完整内容的 200 响应
+func AssertRange(t testing.TB, url string, content []byte, first, last int, opts padding.PaddingOptions) {
+	t.Helper()
+	resp, body := get(t, url, fmt.Sprintf("bytes=%d-%d", first, last), "")
+	if resp == nil {
+		return
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Errorf("paddingtest: range request: status %d, want 206", resp.StatusCode)
+		return
+	}
+	AssertPadded(t, resp, opts)
+	var start, end, size int
+	if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &size); err != nil {
+		t.Errorf("paddingtest: range request: malformed Content-Range %q", resp.Header.Get("Content-Range"))
+		return
+	}
+	if start > first || end < last || end >= len(content) || size != len(content) {
+		t.Errorf("paddingtest: range request: Content-Range %q does not cover bytes %d-%d", resp.Header.Get("Content-Range"), first, last)
+		return
+	}
+	if !bytes.Equal(body, content[start:end+1]) {
+		t.Errorf("paddingtest: range request: got %d bytes, want the %d bytes described by Content-Range", len(body), end-start+1)
+	}
+	if q := opts.RangeQuantum; q > 0 && start%q != 0 {
+		t.Errorf("paddingtest: range request: range start %d is not a multiple of RangeQuantum (%d)", start, q)
+	}
+
+	resp, body = get(t, url, fmt.Sprintf("bytes=%d-%d", first, last), `"paddingtest-stale"`)
+	if resp == nil {
+		return
+	}
+	if resp.StatusCode != http.StatusOK || !bytes.HasPrefix(body, content) {
+		t.Errorf("paddingtest: range request with stale If-Range: status %d, want 200 with the full content", resp.StatusCode)
+	}
+}
+
+// get 以可选的 Range 与 If-Range 头部请求 url, 返回响应与读取的响应体; 失败时报告错误并返回 nil
+func get(t testing.TB, url, rangeHeader, ifRange string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Errorf("paddingtest: %v", err)
+		return nil, nil
+	}
+	if rangeHeader != "" {
+		req.Header.Set("Range", rangeHeader)
+	}
+	if ifRange != "" {
+		req.Header.Set("If-Range", ifRange)
+	}
+	// 关闭透明压缩, 使响应体与文件内容可以逐字节比较
+	req.Header.Set("Accept-Encoding", "identity")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Errorf("paddingtest: %v", err)
+		return nil, nil
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Errorf("paddingtest: reading body: %v", err)
+		return nil, nil
+	}
+	return resp, body
+}
@@ -0,0 +1,88 @@
+package paddingtest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/fenthope/padding"
+	"github.com/infinite-iroha/touka"
+)
+
+// StressReload 以 workers 个 goroutine 并发地经 p 的服务端中间件处理 HTML 与 JSON 响应、经客户端中间件发出请求,
+// 同时由另一个 goroutine 依次对 p 调用 Reload (configs 中的配置循环使用) 并修改 Options 返回的副本,
+// 每个 worker 处理 rounds 轮; 应在 go test -race 下运行, 用于检查某份配置在并发请求与热重载下没有数据竞争, 例如:
+//
+//	func TestReloadRace(t *testing.T) {
+//		p := padding.NewPadder(myOpts)
+//		paddingtest.StressReload(t, p, []padding.PaddingOptions{myOpts, otherOpts}, 8, 200)
+//	}
+func StressReload(t testing.TB, p *padding.Padder, configs []padding.PaddingOptions, workers, rounds int) {
+	t.Helper()
+	r := touka.New()
+	r.Use(p.Server())
+	r.GET("/html", func(c *touka.Context) {
+		c.Writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = io.WriteString(c.Writer, "<p>ok</p>")
+	})
+	r.GET("/json", func(c *touka.Context) {
+		c.Writer.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(c.Writer, benchBody)
+	})
+	rt := p.Client()(nopTransport{})
+
+	done := make(chan struct{})
+	var reloader sync.WaitGroup
+	reloader.Add(1)
+	go func() {
+		defer reloader.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if len(configs) > 0 {
+				p.Reload(configs[i%len(configs)])
+			}
+			// Options 返回的副本可以随意修改, 不得影响处理中的请求
+			opts := p.Options()
+			opts.Profile.MinLength, opts.Profile.MaxLength = 0, 0
+			opts.SkipPaths = append(opts.SkipPaths, "/never")
+		}
+	}()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rounds {
+				path := "/html"
+				if i%2 == 1 {
+					path = "/json"
+				}
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+				req, err := http.NewRequest(http.MethodGet, "http://example.com"+path, nil)
+				if err == nil {
+					_, err = rt.RoundTrip(req)
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	reloader.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("paddingtest: request failed during reload: %v", err)
+	}
+}
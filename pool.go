@@ -0,0 +1,200 @@
+package padding
+
+import (
+	"crypto/rand"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PoolOptions 配置一个 padding 数据池
+type PoolOptions struct {
+	// Charset 是生成数据池所用的字符集, 为空时使用默认的 64 字符集; 可以使用 CharsetToken 等预设
+	// 只能包含不重复的可见 ASCII 字符, 见 NewPoolStrict
+	Charset string
+	// Size 是数据池的字节数, 小于 4096 (单个 padding 值的最大长度) 时为 4096
+	// 更大的数据池提供更多不同的起始偏移, 相同长度的 padding 值更不容易重复
+	Size int
+	// Refresh 大于 0 时, 数据池生成 Refresh 之后会在下一次取值时于后台重新生成, 限制同一份数据的使用时长
+	Refresh time.Duration
+	// Encoder 不为 nil 时, 数据池保存原始的随机字节, 每个 padding 值在取出时才以 Encoder 编码,
+	// 如 base64.RawURLEncoding、base64.StdEncoding 或 base32.StdEncoding; 编码的输出截断为所需的长度, 不含填充字符
+	// 随机字节经过编码后每个字符的分布完全均匀, 也使值看起来像常见的令牌; 与 Charset 不能同时设置
+	Encoder PoolEncoder
+}
+
+// PoolEncoder 是将原始随机字节编码为头部值的编码器, *base64.Encoding 与 *base32.Encoding 均实现了该接口
+type PoolEncoder interface {
+	Encode(dst, src []byte)
+	EncodedLen(n int) int
+}
+
+// Pool 是预生成的随机 padding 数据池, padding 值取自其中随机偏移处的切片
+// 通过 PaddingOptions.Pool 为不同的中间件实例使用各自的字符集、大小与刷新策略, 互不影响;
+// 未设置时使用包级别共享的默认数据池 (默认字符集, 4096 字节, 不刷新)
+// 同一个 Pool 可以被多个实例并发使用
+type Pool struct {
+	charset string
+	size    int
+	refresh time.Duration
+	encoder PoolEncoder
+
+	data       atomic.Pointer[poolData]
+	refreshing atomic.Bool
+}
+
+// poolData 是数据池的一份生成结果, 生成后只读
+type poolData struct {
+	b       []byte
+	enc     PoolEncoder // 不为 nil 时 b 是原始随机字节, 取出时编码
+	expires time.Time   // 零值表示不过期
+}
+
+// defaultPool 返回未设置 PaddingOptions.Pool 时使用的共享数据池, 也用于不经过 PaddingOptions 的 padding
+// 数据池在第一次使用时才生成, 只是间接导入本包而从不使用 padding 的程序不承担生成的开销
+var defaultPool = sync.OnceValue(func() *Pool {
+	return newPool(PoolOptions{})
+})
+
+// Warmup 立即生成默认数据池, 希望将这部分开销放在启动阶段而不是第一个请求上时, 可以在启动时调用
+// 重复调用是安全的, 只有第一次调用会生成数据
+func Warmup() {
+	defaultPool()
+}
+
+// NewPool 按 opts 创建并立即生成一个数据池
+// Charset 不合法时记录日志并使用默认字符集; 需要在配置错误时得到错误的调用方使用 NewPoolStrict
+func NewPool(opts PoolOptions) *Pool {
+	if opts.Charset != "" && opts.Encoder != nil {
+		log.Printf("padding.Pool: Warning - Charset is ignored when Encoder is set.")
+		opts.Charset = ""
+	}
+	if opts.Charset != "" {
+		if err := validateCharset(opts.Charset); err != nil {
+			log.Printf("padding.Pool: Warning - %v. The default charset will be used.", err)
+			opts.Charset = ""
+		}
+	}
+	return newPool(opts)
+}
+
+// NewPoolStrict 与 NewPool 相同, 但 Charset 包含控制字符、空白、非 ASCII 或重复的字符,
+// 或同时设置了 Charset 与 Encoder 时返回错误
+func NewPoolStrict(opts PoolOptions) (*Pool, error) {
+	if opts.Charset != "" && opts.Encoder != nil {
+		return nil, errors.New("padding: Charset and Encoder are mutually exclusive")
+	}
+	if opts.Charset != "" {
+		if err := validateCharset(opts.Charset); err != nil {
+			return nil, err
+		}
+	}
+	return newPool(opts), nil
+}
+
+// newPool 创建数据池, opts.Charset 已通过校验
+func newPool(opts PoolOptions) *Pool {
+	if opts.Charset == "" && opts.Encoder == nil {
+		opts.Charset = paddingCharset
+	}
+	if opts.Size < maxPaddingSize {
+		if opts.Size > 0 {
+			log.Printf("padding.Pool: Warning - Size (%d) is smaller than maxPaddingSize (%d). It will be raised.",
+				opts.Size, maxPaddingSize)
+		}
+		opts.Size = maxPaddingSize
+	}
+	p := &Pool{charset: opts.Charset, size: opts.Size, refresh: max(opts.Refresh, 0), encoder: opts.Encoder}
+	p.data.Store(p.generate())
+	return p
+}
+
+// Regenerate 立即重新生成数据池, 之后取得的 padding 值来自新的数据
+func (p *Pool) Regenerate() {
+	p.data.Store(p.generate())
+}
+
+// generate 生成一份新的数据
+// 一次读取整块随机字节再映射到字符集, 而不是为每个字节单独调用 rand.Int;
+// 丢弃大于等于字符集长度整数倍的字节, 使每个字符的出现概率相同; 设置了 Encoder 时直接保存随机字节
+func (p *Pool) generate() *poolData {
+	b := make([]byte, p.size)
+	if p.encoder != nil {
+		if _, err := rand.Read(b); err != nil {
+			panic("padding.Pool: failed to generate padding data: " + err.Error())
+		}
+		return p.newData(b)
+	}
+	n := len(p.charset)
+	limit := 256 - 256%n // 接受的随机字节上限 (不含)
+	raw := make([]byte, p.size)
+	for i := 0; i < len(b); {
+		if _, err := rand.Read(raw[:len(b)-i]); err != nil {
+			// 无法生成随机数据是一个严重错误, 应立即 panic
+			panic("padding.Pool: failed to generate padding data: " + err.Error())
+		}
+		for _, r := range raw[:len(b)-i] {
+			if int(r) < limit {
+				b[i] = p.charset[int(r)%n]
+				i++
+			}
+		}
+	}
+	return p.newData(b)
+}
+
+// newData 以生成的数据 b 创建 poolData
+func (p *Pool) newData(b []byte) *poolData {
+	d := &poolData{b: b, enc: p.encoder}
+	if p.refresh > 0 {
+		d.expires = time.Now().Add(p.refresh)
+	}
+	return d
+}
+
+// load 返回当前的数据, nil 的 Pool 表示默认数据池
+// 数据已过期时在后台重新生成, 本次仍返回旧数据, 不阻塞请求
+func (p *Pool) load() *poolData {
+	if p == nil {
+		p = defaultPool()
+	}
+	d := p.data.Load()
+	if !d.expires.IsZero() && time.Now().After(d.expires) && p.refreshing.CompareAndSwap(false, true) {
+		go func() {
+			p.data.Store(p.generate())
+			p.refreshing.Store(false)
+		}()
+	}
+	return d
+}
+
+// slice 从数据中随机选取一个长度为 length (不超过 maxPaddingSize) 的切片
+func (d *poolData) slice(src RandSource, length int) []byte {
+	if length <= 0 {
+		return nil
+	}
+	length = min(length, maxPaddingSize)
+	return d.text(d.offset(src, length), length)
+}
+
+// text 返回从偏移 start 开始、长度为 length 的 padding 内容
+// 设置了 Encoder 时编码 length 个原始字节并截取前 length 个字符: 编码结果总是长于输入, 截取的部分不含填充字符
+func (d *poolData) text(start, length int) []byte {
+	if d.enc == nil {
+		return d.b[start : start+length]
+	}
+	buf := make([]byte, d.enc.EncodedLen(length))
+	d.enc.Encode(buf, d.b[start:start+length])
+	return buf[:length]
+}
+
+// offset 为长度为 length (不超过 maxPaddingSize) 的 padding 随机选取起始偏移
+func (d *poolData) offset(src RandSource, length int) int {
+	start, err := randInt(src, 0, len(d.b)-length)
+	if err != nil {
+		return 0 // 保证功能可用性
+	}
+	return start
+}
@@ -0,0 +1,43 @@
+package padding
+
+import "net/http"
+
+// PreflightOptions 配置 OPTIONS 请求 (主要是 CORS 预检) 的响应的 padding (仅服务端)
+// 预检响应很小且出现频繁, 默认与普通响应一样处理: 状态为 204 时按 DefaultSkipStatusCodes 跳过, 200 时按普通响应填充
+// 设置后 OPTIONS 响应只按这里的规则处理, 不再受 DefaultSkipStatusCodes 影响 (SkipStatusCodes 仍然生效)
+type PreflightOptions struct {
+	// Skip 为 true 时不为 OPTIONS 响应添加 padding, 它们也不再消耗 Budget 的额度
+	Skip bool
+	// TargetSize 大于 0 时, padding 头部的长度使响应头部的总大小恰好达到该值 (已超过时不添加),
+	// 所有预检响应的大小相同, 不会因请求的方法与头部列表不同而有差异
+	TargetSize int
+	// Profile 不为 nil 时按它采样 padding 长度, 使预检响应的大小分布接近普通响应; 优先级低于 TargetSize
+	// 两者都未设置时使用默认的 Profile
+	Profile *PaddingProfile
+}
+
+// normalizePreflight 返回 Profile 经过校验的 PreflightOptions 副本
+func normalizePreflight(p PreflightOptions, logPrefix string) *PreflightOptions {
+	if p.Profile != nil {
+		p.Profile = normalizeProfile(p.Profile, logPrefix)
+	}
+	return &p
+}
+
+// preflight 报告 opts 是否配置了对 req 的 OPTIONS 响应的专门处理
+func preflight(req *http.Request, opts *PaddingOptions) bool {
+	return opts.Preflight != nil && req != nil && req.Method == http.MethodOptions
+}
+
+// preflightProfile 返回 OPTIONS 响应使用的 Profile, 未配置专门处理时返回 nil
+func preflightProfile(req *http.Request, h http.Header, opts *PaddingOptions) *PaddingProfile {
+	if !preflight(req, opts) {
+		return nil
+	}
+	p := opts.Preflight
+	if p.TargetSize > 0 {
+		n := targetHeaderPaddingLength(h, opts.headerName(opts.now()), p.TargetSize)
+		return &PaddingProfile{MinLength: n, MaxLength: n}
+	}
+	return p.Profile
+}
@@ -0,0 +1,98 @@
+package padding
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Protocol 是消息所在连接的 HTTP 协议版本
+// 同样的头部在不同协议下的线上大小差别很大: HTTP/1.1 以明文发送, HTTP/2 与 HTTP/3 经 HPACK/QPACK 压缩,
+// 重复出现的头部只占几个字节; 因此 padding 的合适长度也因协议而异
+type Protocol int
+
+const (
+	// ProtocolUnknown 表示协议未知, 如客户端在连接建立之前无法确定 Transport 最终协商的协议
+	ProtocolUnknown Protocol = iota
+	// ProtocolHTTP1 表示 HTTP/1.x
+	ProtocolHTTP1
+	// ProtocolHTTP2 表示 HTTP/2
+	ProtocolHTTP2
+	// ProtocolHTTP3 表示 HTTP/3
+	ProtocolHTTP3
+)
+
+// String 返回协议的 ALPN 风格名称: "http/1.1"、"h2"、"h3", 未知时为 "unknown"
+func (p Protocol) String() string {
+	switch p {
+	case ProtocolHTTP1:
+		return "http/1.1"
+	case ProtocolHTTP2:
+		return "h2"
+	case ProtocolHTTP3:
+		return "h3"
+	}
+	return "unknown"
+}
+
+// MarshalText 实现 encoding.TextMarshaler, 使 ProfileByProtocol 在 JSON 中以协议名称为键
+func (p Protocol) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler, 接受 String 返回的名称
+func (p *Protocol) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "http/1.1", "http/1.0":
+		*p = ProtocolHTTP1
+	case "h2":
+		*p = ProtocolHTTP2
+	case "h3":
+		*p = ProtocolHTTP3
+	case "unknown":
+		*p = ProtocolUnknown
+	default:
+		return fmt.Errorf("padding: unknown protocol %q", text)
+	}
+	return nil
+}
+
+// protocolOf 按入站请求的协议版本号返回其协议 (服务端与反向代理的下游响应)
+func protocolOf(req *http.Request) Protocol {
+	if req == nil {
+		return ProtocolUnknown
+	}
+	switch req.ProtoMajor {
+	case 1:
+		return ProtocolHTTP1
+	case 2:
+		return ProtocolHTTP2
+	case 3:
+		return ProtocolHTTP3
+	}
+	return ProtocolUnknown
+}
+
+// protocolKey 是 ContextWithProtocol 使用的 context 键
+type protocolKey struct{}
+
+// ContextWithProtocol 返回携带协议提示的 ctx 副本, 告知客户端中间件与反向代理出站请求将使用的协议
+// 出站请求的协议由 Transport 在建立连接时才确定, 已知目标只支持 (或强制使用) 某个协议时可以通过该提示启用 ProfileByProtocol
+func ContextWithProtocol(ctx context.Context, p Protocol) context.Context {
+	return context.WithValue(ctx, protocolKey{}, p)
+}
+
+// outboundProtocol 返回出站请求通过 ContextWithProtocol 给出的协议提示, 未给出时为 ProtocolUnknown
+func outboundProtocol(req *http.Request) Protocol {
+	p, _ := req.Context().Value(protocolKey{}).(Protocol)
+	return p
+}
+
+// protocolProfile 返回 proto 下生效的默认 Profile: 优先使用 ProfileByProtocol, 否则按 Schedule 与 Profile 选择
+func (opts *PaddingOptions) protocolProfile(proto Protocol, now time.Time) *PaddingProfile {
+	if p, ok := opts.ProfileByProtocol[proto]; ok {
+		return p
+	}
+	return opts.profileAt(now)
+}
@@ -0,0 +1,75 @@
+package padding
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+)
+
+// 客户端经 HTTP 代理发出请求时有两种情况:
+//   - 目标为 http:// 时, 完整的请求 (含客户端中间件添加的 padding 头部) 以绝对 URI 形式发送给代理
+//   - 目标为 https:// 时, http.Transport 先向代理发送 CONNECT 建立隧道, 再在隧道内以 TLS 发送请求;
+//     CONNECT 由 Transport 内部发出, 不经过 RoundTripper 中间件, 中间件填充的只是隧道内的请求
+//
+// 需要让 CONNECT 本身的大小也不可区分时, 设置 PadConnect 并使用 ConfigureProxyTransport
+
+// ProxyConnectHeader 返回可设置为 http.Transport.GetProxyConnectHeader 的函数,
+// 为经代理建立隧道时的 CONNECT 请求生成 padding 头部; 未设置 PadConnect 时只返回 static 的副本
+// static 通常是 Transport 原有的 ProxyConnectHeader (设置 GetProxyConnectHeader 后它不再生效), 可以为 nil
+func (p *Padder) ProxyConnectHeader(static http.Header) func(ctx context.Context, proxyURL *url.URL, target string) (http.Header, error) {
+	return func(ctx context.Context, proxyURL *url.URL, target string) (http.Header, error) {
+		h := static.Clone()
+		opts := p.load()
+		if !opts.PadConnect {
+			return h, nil
+		}
+		if h == nil {
+			h = make(http.Header)
+		}
+		req := (&http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Host: target},
+			Host:   target,
+			Header: h,
+		}).WithContext(ctx)
+		if p.skip(req, opts) {
+			return h, nil
+		}
+		info := RequestInfo{
+			Direction: DirectionRequest,
+			Request:   req,
+			Protocol:  ProtocolHTTP1, // http.Transport 总是以 HTTP/1.1 发送 CONNECT
+			Profile:   requestProfile(req, opts),
+		}
+		if opts.DryRun {
+			dryRunPadding(&p.stats, h, info, opts, "padding.ProxyConnectHeader")
+			return h, nil
+		}
+		n, decision, err := decidePadding(h, info, opts)
+		if err != nil {
+			if opts.FailClosed {
+				return nil, fmt.Errorf("padding.ProxyConnectHeader: failed to generate random padding length: %w", err)
+			}
+			log.Printf("padding.ProxyConnectHeader: failed to generate random padding length: %v", err)
+		}
+		p.stats.recordHeader(n)
+		notifyPadding(opts, DirectionRequest, req, 0, n)
+		if err := sleepContext(ctx, decision.Delay, opts); err != nil {
+			return nil, err
+		}
+		return h, nil
+	}
+}
+
+// ConfigureProxyTransport 为 t 安装 ProxyConnectHeader 回调, 原有的 ProxyConnectHeader 会并入回调的结果
+// t 已设置 GetProxyConnectHeader 时不做修改并返回 false
+// 回调在每次建立隧道时读取 p 的当前配置, 因此之后通过 Reload 开关 PadConnect 也会生效
+func (p *Padder) ConfigureProxyTransport(t *http.Transport) bool {
+	if t.GetProxyConnectHeader != nil {
+		return false
+	}
+	t.GetProxyConnectHeader = p.ProxyConnectHeader(t.ProxyConnectHeader)
+	return true
+}
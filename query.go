@@ -0,0 +1,75 @@
+package padding
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// DefaultQueryNames 是 QueryPadding 默认使用的参数名池, 均为常见的防缓存参数名
+var DefaultQueryNames = []string{"_", "t", "v", "ts", "cb", "r", "rnd", "nocache"}
+
+// QueryPaddingOptions 配置出站请求的查询参数 padding (仅客户端)
+// 部分中间设备会移除未知的头部但保留 URL, 此时可以在 URL 中追加一个随机命名、随机长度的查询参数
+type QueryPaddingOptions struct {
+	// Names 是参数名池, 每个请求从中随机选取一个; 为空时使用 DefaultQueryNames
+	// 请求中已存在的同名参数不会被覆盖, 此时跳过查询参数 padding
+	Names []string
+	// Profile 决定参数值的长度, 为 nil 时使用 ProfileShort
+	Profile *PaddingProfile
+	// Only 为 true 时只添加查询参数, 不再添加 padding 头部
+	Only bool
+	// MaxURLLength 是追加参数后 URL 的最大长度, 超出时缩短参数值; 小于等于 0 时为 2048
+	MaxURLLength int
+}
+
+// normalizeQueryPadding 返回补全默认值后的 QueryPaddingOptions 副本
+func normalizeQueryPadding(q QueryPaddingOptions, logPrefix string) *QueryPaddingOptions {
+	q.Names = slices.DeleteFunc(slices.Clone(q.Names), func(name string) bool { return name == "" })
+	if len(q.Names) == 0 {
+		q.Names = slices.Clone(DefaultQueryNames)
+	}
+	if q.Profile == nil {
+		q.Profile = &ProfileShort
+	}
+	q.Profile = normalizeProfile(q.Profile, logPrefix)
+	if q.MaxURLLength <= 0 {
+		q.MaxURLLength = 2048
+	}
+	return &q
+}
+
+// setPaddingQuery 在 req 的 URL 末尾追加一个随机长度的 padding 查询参数, 返回参数值的长度
+// URL 会被复制后替换, 不会修改调用方持有的 *url.URL; 仅在随机数生成失败时返回错误
+func setPaddingQuery(req *http.Request, q *QueryPaddingOptions, src RandSource) (int, error) {
+	if req.URL == nil {
+		return 0, nil
+	}
+	i, err := randInt(src, 0, len(q.Names)-1)
+	if err != nil {
+		return 0, err
+	}
+	name := q.Names[i]
+	query := req.URL.Query()
+	if query.Has(name) {
+		return 0, nil
+	}
+	length, err := q.Profile.sample(src)
+	if err != nil {
+		return 0, err
+	}
+	u := *req.URL
+	sep := "&"
+	if u.RawQuery == "" {
+		sep = ""
+	}
+	prefix := url.QueryEscape(name) + "="
+	// 数据池字符集中的字符均为 URL 非保留字符, 参数值无需转义
+	length = min(length, q.MaxURLLength-len(u.String())-len(sep)-len(prefix)-1)
+	if length <= 0 {
+		return 0, nil
+	}
+	u.RawQuery += sep + prefix + string(getPaddingSlice(src, length))
+	req.URL = &u
+	return length, nil
+}
@@ -0,0 +1,63 @@
+package quicpad
+
+import (
+	"context"
+	"encoding/binary"
+)
+
+// datagramHeaderSize 是 DATAGRAM 中原始负载长度前缀的字节数
+const datagramHeaderSize = 2
+
+// Datagrammer 是支持 QUIC DATAGRAM 扩展 (RFC 9221) 的连接, quic-go 的连接类型满足该接口
+type Datagrammer interface {
+	SendDatagram(payload []byte) error
+	ReceiveDatagram(ctx context.Context) ([]byte, error)
+}
+
+// Conn 包装一个 Datagrammer, 发送时将每个 DATAGRAM 填充到桶大小, 接收时移除对端添加的 padding
+// 填充后的格式为: 2 字节大端原始长度 + 原始负载 + padding; padding 为全零字节,
+// 其内容受 QUIC 的包保护加密, 只有长度对观察者可见
+type Conn struct {
+	conn Datagrammer
+	opts Options
+}
+
+// NewConn 返回包装 conn 的 Conn, 两端都需要使用它收发 DATAGRAM
+func NewConn(conn Datagrammer, opts Options) *Conn {
+	return &Conn{conn: conn, opts: opts.normalize()}
+}
+
+// SendDatagram 发送填充后的 payload; payload 超过 65535 字节时返回 ErrTooLarge
+// 填充后的大小不超过 MaxDatagramSize, 但原始负载本身已超出时仍按原样发送, 由底层连接决定是否拒绝
+func (c *Conn) SendDatagram(payload []byte) error {
+	if len(payload) > 0xFFFF {
+		return ErrTooLarge
+	}
+	n := datagramHeaderSize + len(payload)
+	pad := min(c.opts.paddingLength(n), max(c.opts.MaxDatagramSize-n, 0))
+	buf := make([]byte, n+pad)
+	binary.BigEndian.PutUint16(buf, uint16(len(payload)))
+	copy(buf[datagramHeaderSize:], payload)
+	return c.conn.SendDatagram(buf)
+}
+
+// ReceiveDatagram 接收一个 DATAGRAM 并返回移除 padding 后的原始负载
+func (c *Conn) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	buf, err := c.conn.ReceiveDatagram(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return unpadDatagram(buf)
+}
+
+// unpadDatagram 解析填充后的 DATAGRAM, 返回原始负载
+func unpadDatagram(buf []byte) ([]byte, error) {
+	if len(buf) < datagramHeaderSize {
+		return nil, ErrMalformed
+	}
+	n := int(binary.BigEndian.Uint16(buf))
+	if datagramHeaderSize+n > len(buf) {
+		return nil, ErrMalformed
+	}
+	return buf[datagramHeaderSize : datagramHeaderSize+n], nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 Infinite-Iroha. All rights reserved.
+// Use of this source code is governed by a license that can be found in the LICENSE file.
+
+// Package quicpad 为基于 QUIC (如 quic-go) 的服务提供头部之下的 padding:
+// 将 QUIC DATAGRAM 与流上的写入填充到桶大小, 使 HTTP/3 等部署在传输层也获得与 padding 包相当的大小混淆
+// padding 的长度沿用 padding 包的 Strategy 与 PaddingProfile (含 Distribution 与 BlockSize) 机制决定;
+// 两端都需要使用本包解析收到的数据, 不能与未包装的对端互通
+package quicpad
+
+import (
+	"errors"
+
+	"github.com/fenthope/padding"
+)
+
+// DefaultBucketSize 是未设置 Strategy 时对齐的桶大小 (字节)
+const DefaultBucketSize = 128
+
+// DefaultMaxDatagramSize 是未设置 MaxDatagramSize 时单个 DATAGRAM 负载的上限
+// 与 QUIC 保证可用的最小路径 MTU (1200 字节) 扣除包头与帧头后的空间相当
+const DefaultMaxDatagramSize = 1150
+
+var (
+	// ErrMalformed 表示收到的数据不是本包生成的格式
+	ErrMalformed = errors.New("quicpad: malformed padded payload")
+	// ErrTooLarge 表示 DATAGRAM 的原始负载超过长度前缀可表示的 65535 字节
+	ErrTooLarge = errors.New("quicpad: datagram payload too large")
+)
+
+// Options 配置 DATAGRAM 与流写入的 padding
+type Options struct {
+	// Strategy 决定每个 DATAGRAM 或流记录的 padding 长度, Decision 中所有 Headers 的 Length 之和即为 padding 字节数
+	// RequestInfo 的 ContentLength 为本次的原始长度 (含本包的长度前缀), Direction 为 DirectionRequest
+	// 为 nil 时使用 BlockSize 为 DefaultBucketSize 的 PaddingProfile, 即对齐到 128 字节的整数倍
+	Strategy padding.Strategy
+	// MaxDatagramSize 是填充后单个 DATAGRAM 的上限, 超出时 padding 被截短; 小于等于 0 时为 DefaultMaxDatagramSize
+	// 应不大于对端通告的 max_datagram_frame_size 与路径 MTU 允许的大小
+	MaxDatagramSize int
+	// Rand 传递给 Strategy 的随机数来源, 为 nil 时使用 crypto/rand
+	Rand padding.RandSource
+}
+
+// normalize 补全默认值
+func (o Options) normalize() Options {
+	if o.Strategy == nil {
+		o.Strategy = &padding.PaddingProfile{BlockSize: DefaultBucketSize}
+	}
+	if o.MaxDatagramSize <= 0 {
+		o.MaxDatagramSize = DefaultMaxDatagramSize
+	}
+	return o
+}
+
+// paddingLength 按 Strategy 决定原始长度为 n 时的 padding 字节数
+func (o Options) paddingLength(n int) int {
+	d := o.Strategy.Decide(padding.RequestInfo{
+		Direction:     padding.DirectionRequest,
+		ContentLength: int64(n),
+		Protocol:      padding.ProtocolHTTP3,
+		Rand:          o.Rand,
+	})
+	total := 0
+	for _, h := range d.Headers {
+		total += max(h.Length, 0)
+	}
+	return total
+}
@@ -0,0 +1,112 @@
+package quicpad
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+const (
+	// recordHeaderSize 是流记录头部的字节数: 2 字节原始长度 + 2 字节 padding 长度
+	recordHeaderSize = 4
+	// maxRecordPayload 是单个流记录承载的最大原始字节数, 更大的写入会被拆分为多个记录
+	maxRecordPayload = 16 * 1024
+	// maxRecordPadding 是单个流记录的最大 padding 字节数
+	maxRecordPadding = 0xFFFF
+)
+
+// Writer 将每次写入封装为一个或多个填充到桶大小的记录后写入底层流 (如 quic-go 的 Stream)
+// 记录格式为: 2 字节大端原始长度 + 2 字节大端 padding 长度 + 原始数据 + padding (全零字节)
+// 每次 Write 至少产生一个记录, 调用方应按消息而不是逐字节写入; 对端使用 Reader 读取
+type Writer struct {
+	w    io.Writer
+	opts Options
+	buf  []byte
+}
+
+// NewWriter 返回向 w 写入填充记录的 Writer
+func NewWriter(w io.Writer, opts Options) *Writer {
+	return &Writer{w: w, opts: opts.normalize()}
+}
+
+// Write 将 p 封装为填充记录写入底层流, 返回写入的原始字节数
+func (w *Writer) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p[:min(len(p), maxRecordPayload)]
+		if err := w.writeRecord(chunk); err != nil {
+			return written, err
+		}
+		written += len(chunk)
+		p = p[len(chunk):]
+	}
+	return written, nil
+}
+
+// writeRecord 写入一个承载 chunk 的记录
+func (w *Writer) writeRecord(chunk []byte) error {
+	n := recordHeaderSize + len(chunk)
+	pad := min(w.opts.paddingLength(n), maxRecordPadding)
+	size := n + pad
+	if cap(w.buf) < size {
+		w.buf = make([]byte, size)
+	}
+	buf := w.buf[:size]
+	binary.BigEndian.PutUint16(buf, uint16(len(chunk)))
+	binary.BigEndian.PutUint16(buf[2:], uint16(pad))
+	copy(buf[recordHeaderSize:], chunk)
+	clear(buf[n:])
+	_, err := w.w.Write(buf)
+	return err
+}
+
+// Reader 从 Writer 写出的流中读取记录并移除 padding
+type Reader struct {
+	r       io.Reader
+	header  [recordHeaderSize]byte
+	pending int // 当前记录尚未读出的原始字节数
+	pad     int // 当前记录在原始数据之后的 padding 字节数
+}
+
+// NewReader 返回从 r 读取填充记录的 Reader
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+// Read 读取移除 padding 后的原始数据
+func (r *Reader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for r.pending == 0 {
+		if err := r.discardPadding(); err != nil {
+			return 0, err
+		}
+		if _, err := io.ReadFull(r.r, r.header[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return 0, ErrMalformed
+			}
+			return 0, err
+		}
+		r.pending = int(binary.BigEndian.Uint16(r.header[:]))
+		r.pad = int(binary.BigEndian.Uint16(r.header[2:]))
+	}
+	n, err := r.r.Read(p[:min(len(p), r.pending)])
+	r.pending -= n
+	if err == io.EOF && r.pending > 0 {
+		err = ErrMalformed
+	}
+	return n, err
+}
+
+// discardPadding 跳过上一个记录末尾的 padding
+func (r *Reader) discardPadding() error {
+	if r.pad == 0 {
+		return nil
+	}
+	_, err := io.CopyN(io.Discard, r.r, int64(r.pad))
+	r.pad = 0
+	if err == io.EOF {
+		return ErrMalformed
+	}
+	return err
+}
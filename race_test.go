@@ -0,0 +1,6 @@
+//go:build race
+
+package padding
+
+// raceEnabled 报告测试是否以 -race 构建; 此时 sync.Pool 会随机丢弃放回的对象, 分配次数不再稳定
+const raceEnabled = true
@@ -0,0 +1,101 @@
+package padding
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	mrand "math/rand/v2"
+	"sync"
+	"time"
+)
+
+// RandSource 是 padding 长度与内容选择所使用的随机数来源, 语义与 io.Reader 相同
+// 默认使用 crypto/rand.Reader; 测试中可以传入确定性的实现 (如 math/rand/v2 的 *ChaCha8)
+// 以复现完全相同的 padding 序列, 特殊部署也可以接入硬件随机数发生器或 DRBG
+// 中间件会在多个请求之间并发调用 Read, 非并发安全的实现需要自行加锁;
+// 并发请求的调度顺序会影响取数顺序, 只有串行的请求序列才能被精确复现
+type RandSource interface {
+	Read(p []byte) (n int, err error)
+}
+
+// defaultRandSource 是未设置 PaddingOptions.Rand 时使用的随机数来源
+var defaultRandSource RandSource = rand.Reader
+
+// fastRand 是由一组 ChaCha8 生成器组成的 RandSource, 每个生成器由 crypto/rand 播种并定期重新播种
+type fastRand struct {
+	pool   sync.Pool
+	reseed time.Duration
+	seeder io.Reader // 播种使用的熵源, 即 crypto/rand.Reader
+}
+
+// chachaState 是 fastRand 池中的一个生成器
+// rng 包装 gen 并随其一同放回池中, intN 无需每次调用都分配新的 *mrand.Rand
+type chachaState struct {
+	gen    mrand.ChaCha8
+	rng    *mrand.Rand
+	seeded time.Time
+}
+
+// NewFastRand 返回一个高性能的 RandSource: 长度与数据池偏移由 math/rand/v2 的 ChaCha8 生成,
+// 生成器从 crypto/rand 播种, 并在使用超过 reseed 后重新播种 (小于等于 0 时为 1 分钟)
+// 这是为每秒请求数极高、逐请求读取 crypto/rand 的开销已可测量的网关提供的权衡:
+// ChaCha8 的输出在密码学上仍然难以预测, 但两次播种之间的状态若被泄露 (如内存转储), 该区间内的 padding 序列可被重现;
+// 生成器按 sync.Pool 分布在各个 P 上, 并发调用无需加锁
+func NewFastRand(reseed time.Duration) RandSource {
+	return newFastRand(reseed, rand.Reader)
+}
+
+// newFastRand 返回从 seeder 播种的 fastRand
+func newFastRand(reseed time.Duration, seeder io.Reader) *fastRand {
+	if reseed <= 0 {
+		reseed = time.Minute
+	}
+	f := &fastRand{reseed: reseed, seeder: seeder}
+	f.pool.New = func() any {
+		s := new(chachaState)
+		s.rng = mrand.New(&s.gen)
+		return s
+	}
+	return f
+}
+
+// get 从池中取出一个生成器, 使用超过 reseed 时从 crypto/rand 重新播种
+// 重新播种失败时继续使用旧的状态; 首次播种失败时把生成器放回池中并返回错误,
+// 不会以全零的种子输出可预测的序列
+func (f *fastRand) get() (*chachaState, error) {
+	s := f.pool.Get().(*chachaState)
+	if s.seeded.IsZero() || time.Since(s.seeded) > f.reseed {
+		var seed [32]byte
+		if _, err := io.ReadFull(f.seeder, seed[:]); err != nil {
+			if s.seeded.IsZero() {
+				f.pool.Put(s)
+				return nil, fmt.Errorf("padding: failed to seed fast random source: %w", err)
+			}
+		} else {
+			s.gen.Seed(seed)
+			s.seeded = time.Now()
+		}
+	}
+	return s, nil
+}
+
+// Read 用 ChaCha8 的输出填满 p; 只有生成器从未成功播种时才返回错误
+func (f *fastRand) Read(p []byte) (int, error) {
+	s, err := f.get()
+	if err != nil {
+		return 0, err
+	}
+	defer f.pool.Put(s)
+	return s.gen.Read(p)
+}
+
+// intN 返回 [0, n) 内的均匀随机整数, 是 randInt 的快速路径, 避免经由 Read 与 big.Int 的开销
+func (f *fastRand) intN(n int) (int, error) {
+	s, err := f.get()
+	if err != nil {
+		return 0, err
+	}
+	v := s.rng.IntN(n)
+	f.pool.Put(s)
+	return v, nil
+}
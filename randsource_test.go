@@ -0,0 +1,42 @@
+package padding
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// failingReader 的每次读取都返回错误, 模拟 crypto/rand 不可用
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("entropy unavailable") }
+
+func TestFastRandSeedFailure(t *testing.T) {
+	f := newFastRand(time.Minute, failingReader{})
+	if _, err := f.Read(make([]byte, 16)); err == nil {
+		t.Fatal("Read succeeded without a seed, want error")
+	}
+	if _, err := randInt(f, 0, 100); err == nil {
+		t.Fatal("randInt succeeded without a seed, want error")
+	}
+}
+
+func TestFastRandIntN(t *testing.T) {
+	f := NewFastRand(0)
+	for range 1000 {
+		v, err := randInt(f, 10, 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v < 10 || v > 20 {
+			t.Fatalf("randInt(10, 20) = %d, out of range", v)
+		}
+	}
+	// 生成器与 *mrand.Rand 都随池复用, 预热后不应再分配
+	if raceEnabled {
+		return
+	}
+	if n := testing.AllocsPerRun(100, func() { randInt(f, 0, 100) }); n != 0 {
+		t.Errorf("randInt with NewFastRand allocates %v times per call, want 0", n)
+	}
+}
@@ -0,0 +1,118 @@
+package padding
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Range 请求的响应 (206 Partial Content) 的大小由请求的 Range 决定, 客户端按 Content-Range 拼接分段;
+// 在响应体中追加任何数据都会使分段错位, 因此 206 响应只添加头部 padding,
+// 响应体 padding、StreamPadding、MinTotalSize 的末尾补齐与 ConstantRate 的末块补足都不会作用于它
+// If-Range 不匹配时处理函数返回完整的 200 响应, 此时照常按 200 处理
+//
+// 对由本服务提供的资源, 可以设置 RangeQuantum 使 206 响应的大小本身不再精确反映请求的区间
+
+// partialContent 报告响应是否为 Range 请求的部分响应, 其响应体不能追加 padding
+func partialContent(statusCode int) bool {
+	return statusCode == http.StatusPartialContent
+}
+
+// byteRange 是 Range 头部中的一个区间, end 为 -1 表示到资源末尾; suffix 为 true 时表示最后 start 个字节
+type byteRange struct {
+	start, end int64
+	suffix     bool
+}
+
+// quantizeRange 将 h 中 Range 头部的每个区间向外扩展到 quantum 的整数倍边界, 并合并扩展后重叠或相邻的区间
+// Range 头部不存在、不是 bytes 单位或无法解析时保持原样, 交给处理函数按原值处理
+func quantizeRange(h http.Header, quantum int) {
+	v := h.Get("Range")
+	if quantum <= 0 || v == "" {
+		return
+	}
+	ranges, ok := parseByteRanges(v)
+	if !ok {
+		return
+	}
+	q := int64(quantum)
+	var explicit, suffixes []byteRange
+	for _, r := range ranges {
+		switch {
+		case r.suffix:
+			r.start = roundUp64(r.start, q)
+			suffixes = append(suffixes, r)
+			continue
+		case r.end >= 0:
+			r.end = (r.end/q+1)*q - 1
+		}
+		r.start = r.start / q * q
+		explicit = append(explicit, r)
+	}
+	// 扩展后的区间可能重叠, net/http 在区间总长超过资源大小时会忽略整个 Range 返回 200, 因此先合并
+	slices.SortFunc(explicit, func(a, b byteRange) int { return cmp.Compare(a.start, b.start) })
+	var merged []byteRange
+	for _, r := range explicit {
+		if n := len(merged); n > 0 && (merged[n-1].end < 0 || r.start <= merged[n-1].end+1) {
+			if merged[n-1].end >= 0 && (r.end < 0 || r.end > merged[n-1].end) {
+				merged[n-1].end = r.end
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	parts := make([]string, 0, len(merged)+len(suffixes))
+	for _, r := range append(merged, suffixes...) {
+		switch {
+		case r.suffix:
+			parts = append(parts, "-"+strconv.FormatInt(r.start, 10))
+		case r.end < 0:
+			parts = append(parts, strconv.FormatInt(r.start, 10)+"-")
+		default:
+			parts = append(parts, strconv.FormatInt(r.start, 10)+"-"+strconv.FormatInt(r.end, 10))
+		}
+	}
+	h.Set("Range", "bytes="+strings.Join(parts, ","))
+}
+
+// parseByteRanges 解析 bytes 单位的 Range 头部
+func parseByteRanges(v string) ([]byteRange, bool) {
+	unit, spec, ok := strings.Cut(v, "=")
+	if !ok || !strings.EqualFold(strings.TrimSpace(unit), "bytes") {
+		return nil, false
+	}
+	var ranges []byteRange
+	for part := range strings.SplitSeq(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, false
+		}
+		first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+		if first == "" {
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, false
+			}
+			ranges = append(ranges, byteRange{start: n, suffix: true})
+			continue
+		}
+		start, err := strconv.ParseInt(first, 10, 64)
+		if err != nil || start < 0 {
+			return nil, false
+		}
+		end := int64(-1)
+		if last != "" {
+			if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+				return nil, false
+			}
+		}
+		ranges = append(ranges, byteRange{start: start, end: end})
+	}
+	return ranges, len(ranges) > 0
+}
@@ -0,0 +1,135 @@
+package padding_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fenthope/padding"
+	"github.com/fenthope/padding/paddingtest"
+	"github.com/infinite-iroha/touka"
+)
+
+// rangeServer 以 opts 启动一个经 ToukaPaddingS 提供 name 文件的服务器, 返回文件的 URL 与内容
+// 文件长度 (1008) 不是 512 的整数倍, 使 ContentLengthBucket 对完整响应必然生效
+func rangeServer(t *testing.T, opts padding.PaddingOptions, name string) (string, []byte) {
+	t.Helper()
+	content := []byte(strings.Repeat("0123456789abcdef", 63))
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := touka.New()
+	r.Use(padding.ToukaPaddingS(opts))
+	r.GET("/"+name, func(c *touka.Context) {
+		http.ServeFile(c.Writer, c.Request, path)
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv.URL + "/" + name, content
+}
+
+// rangeGet 以 Range 与可选的 If-Range 请求 url, 返回响应与响应体
+func rangeGet(t *testing.T, url, rangeHeader, ifRange string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Range", rangeHeader)
+	if ifRange != "" {
+		req.Header.Set("If-Range", ifRange)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, body
+}
+
+func TestRangeHeaderOnly(t *testing.T) {
+	// 这些特性都会改写 200 响应的响应体, 对 206 响应不得生效
+	opts := padding.PaddingOptions{
+		Profile:             &padding.PaddingProfile{MinLength: 16, MaxLength: 64},
+		HTMLBodyPadding:     &padding.PaddingProfile{MinLength: 64, MaxLength: 128},
+		StreamPadding:       &padding.StreamPaddingOptions{},
+		MinTotalSize:        4096,
+		ContentLengthBucket: 512,
+	}
+	url, content := rangeServer(t, opts, "page.html")
+	paddingtest.AssertRange(t, url, content, 100, 199, opts)
+
+	resp, body := rangeGet(t, url, "bytes=100-199", "")
+	if resp.StatusCode != http.StatusPartialContent || string(body) != string(content[100:200]) {
+		t.Fatalf("status %d with %d bytes, want 206 with exactly the requested 100 bytes", resp.StatusCode, len(body))
+	}
+	if resp.ContentLength != 100 {
+		t.Errorf("Content-Length = %d, want 100", resp.ContentLength)
+	}
+	if len(paddingtest.PaddingHeaders(resp.Header, opts)) == 0 {
+		t.Errorf("206 response has no padding headers")
+	}
+}
+
+func TestRangeQuantum(t *testing.T) {
+	opts := padding.PaddingOptions{
+		Profile:      &padding.PaddingProfile{MinLength: 16, MaxLength: 64},
+		RangeQuantum: 64,
+	}
+	url, content := rangeServer(t, opts, "data.txt")
+	paddingtest.AssertRange(t, url, content, 70, 80, opts)
+
+	for _, c := range []struct {
+		rangeHeader string
+		start, end  int
+	}{
+		{"bytes=70-80", 64, 127},
+		{"bytes=0-0", 0, 63},
+		{"bytes=127-128", 64, 191},
+		{"bytes=1000-", 960, len(content) - 1},
+	} {
+		resp, body := rangeGet(t, url, c.rangeHeader, "")
+		want := fmt.Sprintf("bytes %d-%d/%d", c.start, c.end, len(content))
+		if resp.StatusCode != http.StatusPartialContent || resp.Header.Get("Content-Range") != want {
+			t.Errorf("%s: status %d, Content-Range %q, want 206 with %q", c.rangeHeader, resp.StatusCode, resp.Header.Get("Content-Range"), want)
+			continue
+		}
+		if string(body) != string(content[c.start:c.end+1]) {
+			t.Errorf("%s: body does not match the quantized range", c.rangeHeader)
+		}
+	}
+}
+
+func TestRangeIfRangeMismatch(t *testing.T) {
+	opts := padding.PaddingOptions{
+		Profile:                  &padding.PaddingProfile{MinLength: 16, MaxLength: 64},
+		ContentLengthBucket:      512,
+		ContentLengthBucketTypes: []string{"text/plain"},
+		RangeQuantum:             64,
+	}
+	url, content := rangeServer(t, opts, "data.txt")
+
+	resp, body := rangeGet(t, url, "bytes=10-20", `"stale-etag"`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want 200 for a stale If-Range", resp.StatusCode)
+	}
+	if !strings.HasPrefix(string(body), string(content)) || strings.TrimSpace(string(body[len(content):])) != "" {
+		t.Errorf("200 body is not the full content followed by whitespace")
+	}
+	if len(body) != 1024 {
+		t.Errorf("200 body length %d, want the file padded to the 1024-byte bucket", len(body))
+	}
+	if len(paddingtest.PaddingHeaders(resp.Header, opts)) == 0 {
+		t.Errorf("200 response has no padding headers")
+	}
+}
@@ -0,0 +1,47 @@
+package padding
+
+// RechunkOptions 配置响应体的随机分块写入
+// 流式响应中每次写入的边界会反映到 TLS 记录与数据包大小上, 即使总大小经过 padding 也会泄露结构
+type RechunkOptions struct {
+	MinChunkSize int // 单次写入的最小字节数, 小于等于 0 时为 1
+	MaxChunkSize int // 单次写入的最大字节数, 小于等于 0 时为 4096
+	// FlushProbability 是每次分块写入后立即 Flush 的概率, 取值 [0, 1]
+	// 0 表示不额外 Flush, 由底层缓冲决定何时发送
+	FlushProbability float64
+}
+
+// normalizeRechunk 返回补全默认值后的 RechunkOptions 副本
+func normalizeRechunk(r RechunkOptions) *RechunkOptions {
+	if r.MinChunkSize <= 0 {
+		r.MinChunkSize = 1
+	}
+	if r.MaxChunkSize <= 0 {
+		r.MaxChunkSize = 4096
+	}
+	if r.MinChunkSize > r.MaxChunkSize {
+		r.MinChunkSize = r.MaxChunkSize
+	}
+	return &r
+}
+
+// writeRechunked 将 data 切分为随机大小的块依次写入, 并按概率在块之间 Flush
+func (prw *paddingResponseWriter) writeRechunked(data []byte) (int, error) {
+	r := prw.opts.Rechunk
+	written := 0
+	for written < len(data) {
+		size, err := randInt(prw.opts.Rand, r.MinChunkSize, r.MaxChunkSize)
+		if err != nil {
+			size = r.MaxChunkSize
+		}
+		end := min(written+size, len(data))
+		n, err := prw.ResponseWriter.Write(data[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if randChance(prw.opts.Rand, r.FlushProbability) {
+			prw.ResponseWriter.Flush()
+		}
+	}
+	return written, nil
+}
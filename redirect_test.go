@@ -0,0 +1,82 @@
+package padding_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/fenthope/padding"
+	"github.com/fenthope/padding/paddingtest"
+	"github.com/infinite-iroha/touka"
+)
+
+// redirectEngine 返回以两种方式发出重定向的 touka 引擎: /touka 使用 c.Redirect, /http 直接调用 http.Redirect
+func redirectEngine(opts padding.PaddingOptions) *touka.Engine {
+	r := touka.New()
+	r.Use(padding.ToukaPaddingS(opts))
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		r.Handle(method, "/touka", func(c *touka.Context) {
+			c.Redirect(http.StatusFound, "/target")
+		})
+		r.Handle(method, "/http", func(c *touka.Context) {
+			http.Redirect(c.Writer, c.Request, "/target", http.StatusMovedPermanently)
+		})
+	}
+	return r
+}
+
+func TestRedirectHeaderPadding(t *testing.T) {
+	opts := padding.PaddingOptions{Profile: &padding.PaddingProfile{MinLength: 16, MaxLength: 64}}
+	r := redirectEngine(opts)
+	for _, path := range []string{"/touka", "/http"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code/100 != 3 || w.Header().Get("Location") != "/target" {
+			t.Errorf("%s: status %d, Location %q, want a redirect to /target", path, w.Code, w.Header().Get("Location"))
+		}
+		if len(paddingtest.PaddingHeaders(w.Header(), opts)) == 0 {
+			t.Errorf("%s: redirect has no padding headers", path)
+		}
+	}
+
+	// 经 Padder.Handler 挂载的 net/http 处理函数同样适用
+	h := padding.NewPadder(opts).Handler(http.RedirectHandler("/target", http.StatusSeeOther))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusSeeOther || len(paddingtest.PaddingHeaders(w.Header(), opts)) == 0 {
+		t.Errorf("Padder.Handler redirect: status %d, padding headers %v", w.Code, paddingtest.PaddingHeaders(w.Header(), opts))
+	}
+}
+
+func TestRedirectBodyFiller(t *testing.T) {
+	const filler = 512
+	base := padding.PaddingOptions{Profile: &padding.PaddingProfile{MinLength: 16, MaxLength: 64}}
+	opts := base
+	opts.RedirectBodyPadding = &padding.PaddingProfile{MinLength: filler, MaxLength: filler}
+
+	plain, padded := redirectEngine(base), redirectEngine(opts)
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		for _, path := range []string{"/touka", "/http"} {
+			want := httptest.NewRecorder()
+			plain.ServeHTTP(want, httptest.NewRequest(method, path, nil))
+			w := httptest.NewRecorder()
+			padded.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+
+			body := w.Body.String()
+			if !strings.HasPrefix(body, want.Body.String()) {
+				t.Errorf("%s %s: padded body does not start with the original redirect body", method, path)
+			}
+			if got, target := len(body), want.Body.Len()+filler; got != target {
+				t.Errorf("%s %s: body is %d bytes, want %d (original %d + filler %d)", method, path, got, target, want.Body.Len(), filler)
+			}
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+				t.Errorf("%s %s: Content-Type %q, want text/html", method, path, ct)
+			}
+			if cl := w.Header().Get("Content-Length"); cl != "" && cl != strconv.Itoa(len(body)) {
+				t.Errorf("%s %s: Content-Length %s does not match body length %d", method, path, cl, len(body))
+			}
+		}
+	}
+}
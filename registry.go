@@ -0,0 +1,55 @@
+package padding
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// profileRegistry 保存按名称注册的 Profile, 名称不区分大小写
+var (
+	profileRegistryMu sync.RWMutex
+	profileRegistry   = map[string]*PaddingProfile{
+		"default":      &ProfileDefault,
+		"short":        &ProfileShort,
+		"long":         &ProfileLong,
+		"doh-query":    &ProfileDoHQuery,
+		"doh-response": &ProfileDoHResponse,
+	}
+)
+
+// RegisterProfile 以 name 注册一个 Profile, 使其可以在配置文件中按名称引用
+// 内置策略已以 "default"、"short"、"long"、"doh-query"、"doh-response" 注册
+// 重复注册同一名称会覆盖之前的 Profile; name 为空或 p 为 nil 时 panic
+func RegisterProfile(name string, p *PaddingProfile) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		panic("padding: RegisterProfile called with empty name")
+	}
+	if p == nil {
+		panic("padding: RegisterProfile called with nil profile for " + name)
+	}
+	profileRegistryMu.Lock()
+	defer profileRegistryMu.Unlock()
+	profileRegistry[name] = p
+}
+
+// ProfileByName 返回以 name 注册的 Profile
+func ProfileByName(name string) (*PaddingProfile, bool) {
+	profileRegistryMu.RLock()
+	defer profileRegistryMu.RUnlock()
+	p, ok := profileRegistry[strings.ToLower(strings.TrimSpace(name))]
+	return p, ok
+}
+
+// ProfileNames 返回所有已注册的 Profile 名称, 按字典序排列, 可用于管理与诊断接口
+func ProfileNames() []string {
+	profileRegistryMu.RLock()
+	defer profileRegistryMu.RUnlock()
+	names := make([]string, 0, len(profileRegistry))
+	for name := range profileRegistry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,76 @@
+package padding
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// nonceSize 是带时间戳的 nonce 的长度: 8 个十六进制字符的 Unix 秒数加 16 个随机字符
+const nonceSize = 24
+
+// newNonce 生成一个在时刻 now 签发的 nonce
+// 随机部分由 opts.Rand 读取的 8 个独立字节按十六进制编码, 而不是数据池中的一段: 数据池只有约 4000 个偏移,
+// 同一秒内的大量请求会重复得到相同的 nonce 而被误判为重放; Rand 读取失败时改用 crypto/rand
+func newNonce(now time.Time, opts *PaddingOptions) string {
+	var b [(nonceSize - 8) / 2]byte
+	if _, err := io.ReadFull(opts.Rand, b[:]); err != nil {
+		_, _ = rand.Read(b[:])
+	}
+	return fmt.Sprintf("%08x", uint32(now.Unix())) + hex.EncodeToString(b[:])
+}
+
+// nonceTime 返回 nonce 的签发时刻
+func nonceTime(nonce string) (time.Time, bool) {
+	if len(nonce) != nonceSize {
+		return time.Time{}, false
+	}
+	sec, err := strconv.ParseUint(nonce[:8], 16, 32)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(sec), 0), true
+}
+
+// replayCache 记录时间窗口内已经接受过的 nonce, 用于拒绝重放的请求
+type replayCache struct {
+	window time.Duration
+
+	mu        sync.Mutex
+	seen      map[string]time.Time // nonce 到其过期时刻
+	lastSweep time.Time
+}
+
+// newReplayCache 创建一个只接受签发时刻与当前时间相差不超过 window 的 nonce 的 replayCache
+func newReplayCache(window time.Duration) *replayCache {
+	return &replayCache{window: window, seen: make(map[string]time.Time)}
+}
+
+// accept 报告 nonce 是否在时间窗口内且未被使用过, 接受时将其记录为已使用
+// 过期的记录每隔一个窗口清理一次, 内存占用与一个窗口内的请求数成正比
+func (c *replayCache) accept(nonce string, now time.Time) bool {
+	issued, ok := nonceTime(nonce)
+	if !ok || issued.Before(now.Add(-c.window)) || issued.After(now.Add(c.window)) {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.Sub(c.lastSweep) >= c.window {
+		for n, expires := range c.seen {
+			if now.After(expires) {
+				delete(c.seen, n)
+			}
+		}
+		c.lastSweep = now
+	}
+	if _, ok := c.seen[nonce]; ok {
+		return false
+	}
+	// 时间戳精确到秒, 多保留一秒使窗口边界上的 nonce 不会在过期前被清理
+	c.seen[nonce] = issued.Add(c.window + time.Second)
+	return true
+}
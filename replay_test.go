@@ -0,0 +1,65 @@
+package padding
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/infinite-iroha/touka"
+)
+
+// fixedClock 是停在固定时刻的 Clock, 使所有请求的 nonce 落在同一秒内
+type fixedClock struct{ t time.Time }
+
+func (c fixedClock) Now() time.Time { return c.t }
+
+func (c fixedClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- c.t.Add(d)
+	return ch
+}
+
+// roundTripFunc 将普通函数适配为 http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// handlerTransport 返回一个直接交给 h 处理请求的 RoundTripper
+func handlerTransport(h http.Handler) http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Result(), nil
+	})
+}
+
+func TestReplayWindowSameSecond(t *testing.T) {
+	const requests = 5000
+	for _, selfDescribing := range []bool{false, true} {
+		opts := PaddingOptions{
+			AuthKey:        []byte("replay-test-key"),
+			ReplayWindow:   time.Minute,
+			SelfDescribing: selfDescribing,
+			Clock:          fixedClock{time.Unix(1700000000, 0)},
+		}
+		r := touka.New()
+		r.Use(VerifyPaddingS(VerifyOptions{Padding: opts}))
+		r.GET("/", func(c *touka.Context) { c.String(http.StatusOK, "ok") })
+		rt := ToukaPadding(opts)(handlerTransport(r))
+
+		rejected := 0
+		for range requests {
+			resp, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != http.StatusOK {
+				rejected++
+			}
+		}
+		if rejected > 0 {
+			t.Errorf("SelfDescribing=%v: %d of %d honest same-second requests were rejected as replays", selfDescribing, rejected, requests)
+		}
+	}
+}
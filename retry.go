@@ -0,0 +1,137 @@
+package padding
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// RetryPolicy 决定客户端中间件如何为同一个逻辑请求的多次尝试 (如 httpc 的重试) 添加 padding
+// 重试时每次重新采样, 同一请求的各次尝试大小不同, 这本身就是一个可观察的特征; 沿用第一次的决定则使各次尝试大小一致
+type RetryPolicy string
+
+const (
+	// RetryResample 每次尝试都撤销上一次添加的 padding 后重新决定 (默认)
+	RetryResample RetryPolicy = "resample"
+	// RetryPin 之后的尝试沿用第一次尝试的 padding 头部、查询参数与延迟, 只重新计算签名
+	RetryPin RetryPolicy = "pin"
+)
+
+// valid 报告 r 是否为已知的重试策略, 空值表示默认的 RetryResample
+func (r RetryPolicy) valid() bool {
+	switch r {
+	case "", RetryResample, RetryPin:
+		return true
+	}
+	return false
+}
+
+// retryContextKey 是 RetryContext 在 context 中记录 retryState 所用的键
+type retryContextKey struct{}
+
+// retryState 记录一个逻辑请求第一次尝试的 padding, 供之后的尝试沿用或撤销
+type retryState struct {
+	mu       sync.Mutex
+	attempts int
+	saved    bool
+	orig     http.Header // padding 修改过的头部在添加 padding 之前的值, nil 表示原本不存在
+	padded   http.Header // 同一组头部在添加 padding 之后、签名之前的值
+	query    string      // 添加 padding 之前的 RawQuery
+	padQuery string      // 添加 padding 之后的 RawQuery
+	n        int
+	delay    time.Duration
+}
+
+// RetryContext 返回 ctx 的一个派生, 以它为 context 的请求 (及其 Clone) 被客户端中间件视为同一个逻辑请求的多次尝试
+// httpc 的重试复用同一个 *http.Request, 客户端中间件会自动标记, 无需调用它;
+// 自行实现重试且每次从未经过中间件的模板重新构造请求时, 以它作为模板的 context
+func RetryContext(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(retryContextKey{}).(*retryState); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, retryContextKey{}, &retryState{})
+}
+
+// retryStateFor 返回 req 所属逻辑请求的 retryState, 不存在时将其附加到 req 的 context 上
+// 原地替换 *req, 使复用同一个请求 (或在之后 Clone 它) 的重试能找到第一次尝试的记录
+func retryStateFor(req *http.Request) *retryState {
+	if s, ok := req.Context().Value(retryContextKey{}).(*retryState); ok {
+		return s
+	}
+	*req = *req.WithContext(RetryContext(req.Context()))
+	return req.Context().Value(retryContextKey{}).(*retryState)
+}
+
+// begin 开始一次尝试, 返回此前的尝试次数
+func (s *retryState) begin() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	return s.attempts - 1
+}
+
+// save 以添加 padding 之前的头部 before 与 RawQuery 为参照, 记录本次尝试对 req 所做的修改
+func (s *retryState) save(req *http.Request, before http.Header, query string, n int, delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orig, s.padded = make(http.Header), make(http.Header)
+	for key, values := range req.Header {
+		if !slices.Equal(before[key], values) {
+			s.orig[key] = before[key]
+			s.padded[key] = slices.Clone(values)
+		}
+	}
+	for key, values := range before {
+		if _, ok := req.Header[key]; !ok {
+			s.orig[key] = values
+			s.padded[key] = nil
+		}
+	}
+	s.query, s.padQuery = query, rawQuery(req)
+	s.n, s.delay = n, delay
+	s.saved = true
+}
+
+// restore 撤销上一次尝试添加的 padding; pin 为 true 时改为重新应用第一次尝试的 padding
+// 请求上其他的头部 (如重试之间刷新的凭据) 保持不变; 没有记录时返回 false
+func (s *retryState) restore(req *http.Request, pin bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.saved {
+		return false
+	}
+	values, query := s.orig, s.query
+	if pin {
+		values, query = s.padded, s.padQuery
+	}
+	for key, v := range values {
+		if v == nil {
+			delete(req.Header, key)
+			continue
+		}
+		req.Header[key] = slices.Clone(v)
+	}
+	if req.URL != nil && req.URL.RawQuery != query {
+		u := *req.URL
+		u.RawQuery = query
+		req.URL = &u
+	}
+	return true
+}
+
+// within 报告记录的 padding 长度是否不超过上限 limit; ok 为 false 表示没有上限
+func (s *retryState) within(limit int, ok bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !ok || s.n <= limit
+}
+
+// rawQuery 返回请求 URL 的 RawQuery, URL 为 nil 时返回空字符串
+func rawQuery(req *http.Request) string {
+	if req.URL == nil {
+		return ""
+	}
+	return req.URL.RawQuery
+}
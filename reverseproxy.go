@@ -0,0 +1,177 @@
+package padding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httputil"
+)
+
+// ReverseProxyPadding 为 httputil.ReverseProxy 提供双向的 padding 钩子
+// 上游请求 (Director/Rewrite) 与下游响应 (ModifyResponse) 共享同一个 Padder 的配置
+type ReverseProxyPadding struct {
+	padder *Padder
+}
+
+// NewReverseProxyPadding 创建一个 ReverseProxyPadding
+// 配置的校验与默认值规则与 ToukaPadding / ToukaPaddingS 一致
+func NewReverseProxyPadding(opts PaddingOptions) *ReverseProxyPadding {
+	return newPadder(opts, "padding.ReverseProxy").ReverseProxy()
+}
+
+// ReverseProxy 返回使用 p 当前配置的反向代理 padding 钩子
+func (p *Padder) ReverseProxy() *ReverseProxyPadding {
+	return &ReverseProxyPadding{padder: p}
+}
+
+// Director 包装一个 ReverseProxy.Director, 在原有逻辑执行之后为上游请求添加 padding 头部
+// next 可以为 nil
+func (rp *ReverseProxyPadding) Director(next func(*http.Request)) func(*http.Request) {
+	return func(req *http.Request) {
+		if next != nil {
+			next(req)
+		}
+		if err := rp.padRequest(req, isUpgradeRequest(req)); err != nil {
+			*req = *req.WithContext(failedContext(req.Context(), err))
+		}
+	}
+}
+
+// Rewrite 包装一个 ReverseProxy.Rewrite, 在原有逻辑执行之后为 pr.Out 添加 padding 头部
+// next 可以为 nil
+func (rp *ReverseProxyPadding) Rewrite(next func(*httputil.ProxyRequest)) func(*httputil.ProxyRequest) {
+	return func(pr *httputil.ProxyRequest) {
+		if next != nil {
+			next(pr)
+		}
+		// pr.Out 中的逐跳头部已被移除, 需要从 pr.In 判断是否为 Upgrade 握手
+		if err := rp.padRequest(pr.Out, isUpgradeRequest(pr.In)); err != nil {
+			pr.Out = pr.Out.WithContext(failedContext(pr.Out.Context(), err))
+		}
+	}
+}
+
+// ModifyResponse 包装一个 ReverseProxy.ModifyResponse, 为下游响应添加 padding 头部
+// 上游返回的同名头部会被覆盖, 避免其长度信息被原样透传; next 可以为 nil
+func (rp *ReverseProxyPadding) ModifyResponse(next func(*http.Response) error) func(*http.Response) error {
+	return func(resp *http.Response) error {
+		if next != nil {
+			if err := next(resp); err != nil {
+				return err
+			}
+		}
+		opts := rp.padder.load()
+		if opts.StripVary && resp.Header != nil {
+			stripVary(resp.Header, opts)
+		}
+		if resp.Request != nil && rp.padder.skip(resp.Request, opts) {
+			return nil
+		}
+		if resp.Header == nil {
+			resp.Header = make(http.Header)
+		}
+		if skipResponse(resp.Request, resp.StatusCode, resp.Header, opts) {
+			return nil
+		}
+		info := RequestInfo{
+			Direction:     DirectionResponse,
+			Request:       resp.Request,
+			StatusCode:    resp.StatusCode,
+			Protocol:      protocolOf(resp.Request),
+			ContentLength: resp.ContentLength,
+			Profile:       sessionProfile(resp.Request, opts.protocolProfile(protocolOf(resp.Request), opts.now()), opts),
+		}
+		if p := entityProfile(resp.Request, resp.Header, opts.protocolProfile(protocolOf(resp.Request), opts.now()), "header", opts); p != nil {
+			info.Profile = p
+		}
+		info.Profile, _ = overheadProfile(info.Profile, info.ContentLength, opts)
+		if opts.DryRun {
+			dryRunPadding(&rp.padder.stats, resp.Header, info, opts, "padding.ReverseProxy")
+			return nil
+		}
+		n, decision, err := decideResponsePadding(resp.Header, info, opts)
+		if err != nil {
+			if opts.FailClosed {
+				return fmt.Errorf("padding.ReverseProxy: failed to generate random padding length: %w", err)
+			}
+			log.Printf("padding.ReverseProxy: failed to generate random padding length: %v", err)
+		}
+		rp.padder.stats.recordHeader(n)
+		notifyPadding(opts, DirectionResponse, resp.Request, resp.StatusCode, n)
+		if resp.Request != nil {
+			return sleepContext(resp.Request.Context(), decision.Delay, opts)
+		}
+		return nil
+	}
+}
+
+// Apply 将双向 padding 钩子安装到 proxy 上
+// 若 proxy 使用 Rewrite 则包装 Rewrite, 否则包装 Director; 二者均未设置时 proxy 无从得知上游地址,
+// 此时返回错误且不修改 proxy, 应先设置 Rewrite (如调用 SetURL) 或 Director 再调用 Apply
+func (rp *ReverseProxyPadding) Apply(proxy *httputil.ReverseProxy) error {
+	switch {
+	case proxy.Rewrite != nil:
+		proxy.Rewrite = rp.Rewrite(proxy.Rewrite)
+	case proxy.Director != nil:
+		proxy.Director = rp.Director(proxy.Director)
+	default:
+		return errors.New("padding.ReverseProxy: proxy has neither Rewrite nor Director; set one before calling Apply")
+	}
+	proxy.ModifyResponse = rp.ModifyResponse(proxy.ModifyResponse)
+	return nil
+}
+
+// padRequest 为即将发往上游的请求添加 padding 头部
+// upgrade 表示原始请求是否为 Upgrade 握手; 仅在 FailClosed 模式下 padding 生成失败时返回错误
+func (rp *ReverseProxyPadding) padRequest(req *http.Request, upgrade bool) error {
+	opts := rp.padder.load()
+	if rp.padder.skip(req, opts) {
+		return nil
+	}
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	if upgrade && opts.SkipUpgrade || skipWithHeader(req.Header, opts) {
+		rp.padder.recordSkip(opts)
+		return nil
+	}
+	info := RequestInfo{
+		Direction:     DirectionRequest,
+		Request:       req,
+		Protocol:      outboundProtocol(req),
+		ContentLength: requestContentLength(req),
+		Profile:       requestProfile(req, opts),
+	}
+	if opts.DryRun {
+		dryRunPadding(&rp.padder.stats, req.Header, info, opts, "padding.ReverseProxy")
+		return nil
+	}
+	var handshake http.Header
+	if upgrade {
+		handshake = saveHandshakeHeaders(req.Header)
+	}
+	n, decision, err := decidePadding(req.Header, info, opts)
+	if upgrade {
+		restoreHandshakeHeaders(req.Header, handshake)
+	}
+	if err != nil {
+		if opts.FailClosed {
+			return fmt.Errorf("padding.ReverseProxy: failed to generate random padding length: %w", err)
+		}
+		log.Printf("padding.ReverseProxy: failed to generate random padding length: %v", err)
+	}
+	signPaddingHeaders(req, opts)
+	rp.padder.stats.recordHeader(n)
+	notifyPadding(opts, DirectionRequest, req, 0, n)
+	return sleepContext(req.Context(), decision.Delay, opts)
+}
+
+// failedContext 返回一个已以 err 取消的 parent 子 context
+// Director/Rewrite 无法直接返回错误, 换上该 context 后 Transport 会立即失败, 由 ErrorHandler 处理
+func failedContext(parent context.Context, err error) context.Context {
+	ctx, cancel := context.WithCancelCause(parent)
+	cancel(err)
+	return ctx
+}
@@ -0,0 +1,66 @@
+package padding_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+
+	"github.com/fenthope/padding"
+	"github.com/fenthope/padding/paddingtest"
+)
+
+// proxyOptions 是反向代理测试使用的配置, 总是添加头部 padding
+var proxyOptions = padding.PaddingOptions{
+	Profile: &padding.PaddingProfile{MinLength: 16, MaxLength: 64},
+}
+
+func TestReverseProxyApply(t *testing.T) {
+	var upstreamPadded bool
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamPadded = len(paddingtest.PaddingHeaders(r.Header, proxyOptions)) > 0
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+	target, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name  string
+		proxy *httputil.ReverseProxy
+	}{
+		{"rewrite", &httputil.ReverseProxy{Rewrite: func(pr *httputil.ProxyRequest) { pr.SetURL(target) }}},
+		{"director", httputil.NewSingleHostReverseProxy(target)},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			upstreamPadded = false
+			if err := padding.NewReverseProxyPadding(proxyOptions).Apply(c.proxy); err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			w := httptest.NewRecorder()
+			c.proxy.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if w.Code != http.StatusOK || w.Body.String() != "ok" {
+				t.Fatalf("status %d, body %q", w.Code, w.Body.String())
+			}
+			if !upstreamPadded {
+				t.Errorf("upstream request has no padding headers")
+			}
+			if len(paddingtest.PaddingHeaders(w.Header(), proxyOptions)) == 0 {
+				t.Errorf("downstream response has no padding headers")
+			}
+		})
+	}
+}
+
+func TestReverseProxyApplyWithoutRoute(t *testing.T) {
+	proxy := &httputil.ReverseProxy{}
+	if err := padding.NewReverseProxyPadding(proxyOptions).Apply(proxy); err == nil {
+		t.Fatal("Apply succeeded on a proxy without Rewrite or Director, want error")
+	}
+	if proxy.Rewrite != nil || proxy.Director != nil || proxy.ModifyResponse != nil {
+		t.Errorf("Apply modified the proxy despite returning an error")
+	}
+}
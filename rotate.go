@@ -0,0 +1,134 @@
+package padding
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// HeaderRotation 配置按时间窗口轮换的 padding 头部名称, 避免固定的自定义头部名称成为静态指纹
+// 设置 Secret 时, 名称为 Prefix 加上 HMAC-SHA256(Secret, 窗口序号) 的前 12 个十六进制字符;
+// 否则按窗口序号依次轮换使用 Names 中的名称
+type HeaderRotation struct {
+	Secret []byte `json:"-"`
+	Names  []string
+	// Window 是一个时间窗口的长度, 小于等于 0 时为 1 小时
+	Window time.Duration
+	// Prefix 是派生名称的前缀, 为空时为 "X-"
+	Prefix string
+}
+
+// normalizeHeaderRotation 返回补全默认值后的 HeaderRotation 副本
+// Secret 与 Names 均为空时返回 nil, 此时使用固定的 HeaderName
+func normalizeHeaderRotation(r HeaderRotation) *HeaderRotation {
+	var names []string
+	for _, name := range r.Names {
+		if name != "" {
+			names = append(names, http.CanonicalHeaderKey(name))
+		}
+	}
+	r.Names = names
+	if len(r.Secret) == 0 && len(r.Names) == 0 {
+		return nil
+	}
+	r.Secret = append([]byte(nil), r.Secret...)
+	if r.Window <= 0 {
+		r.Window = time.Hour
+	}
+	if r.Prefix == "" {
+		r.Prefix = "X-"
+	}
+	return &r
+}
+
+// nameAt 返回第 window 个时间窗口使用的头部名称
+func (r *HeaderRotation) nameAt(window int64) string {
+	if len(r.Secret) > 0 {
+		var msg [8]byte
+		binary.BigEndian.PutUint64(msg[:], uint64(window))
+		mac := hmac.New(sha256.New, r.Secret)
+		mac.Write(msg[:])
+		return http.CanonicalHeaderKey(r.Prefix + hex.EncodeToString(mac.Sum(nil))[:12])
+	}
+	n := int64(len(r.Names))
+	return r.Names[(window%n+n)%n]
+}
+
+// headerName 返回在时刻 now 应使用的 padding 头部名称
+func (opts *PaddingOptions) headerName(now time.Time) string {
+	if opts.RotateHeader == nil {
+		return opts.HeaderName
+	}
+	return opts.RotateHeader.nameAt(now.UnixNano() / int64(opts.RotateHeader.Window))
+}
+
+// maxHeaderCount 是 HeaderCount 的上限
+const maxHeaderCount = 16
+
+// emitHeaderNames 返回在时刻 now 每条消息要设置的 padding 头部名称
+func (opts *PaddingOptions) emitHeaderNames(now time.Time) []string {
+	if len(opts.HeaderNames) > 0 {
+		return opts.HeaderNames
+	}
+	return withCountSuffixes([]string{opts.pickHeaderName(now)}, opts.HeaderCount)
+}
+
+// withCountSuffixes 为每个基础名称追加 "-2" 到 "-count" 后缀的名称, count 小于等于 1 时原样返回
+func withCountSuffixes(bases []string, count int) []string {
+	if count <= 1 {
+		return bases
+	}
+	names := make([]string, 0, len(bases)*count)
+	for _, base := range bases {
+		names = append(names, base)
+		for i := 2; i <= count; i++ {
+			names = append(names, base+"-"+strconv.Itoa(i))
+		}
+	}
+	return names
+}
+
+// headerNames 返回在时刻 now 应被识别为 padding 的所有头部名称, 设置了 HeaderCandidates 时为全部候选
+// HMAC 派生的名称包括前后相邻的窗口, 以容忍两端的时钟偏差与跨窗口的请求
+func (opts *PaddingOptions) headerNames(now time.Time) []string {
+	if len(opts.HeaderNames) > 0 {
+		return opts.HeaderNames
+	}
+	r := opts.RotateHeader
+	var bases []string
+	switch {
+	case len(opts.HeaderCandidates) > 0:
+		bases = candidateNames(opts.HeaderCandidates)
+	case r == nil:
+		bases = []string{opts.HeaderName}
+	case len(r.Secret) == 0:
+		bases = r.Names
+	default:
+		window := now.UnixNano() / int64(r.Window)
+		bases = []string{r.nameAt(window - 1), r.nameAt(window), r.nameAt(window + 1)}
+	}
+	return withCountSuffixes(bases, opts.HeaderCount)
+}
+
+// HeaderNames 返回按 opts 配置当前应被识别为 padding 的所有头部名称 (不含伪装头部)
+// 启用 RotateHeader 时包括相邻时间窗口的名称, 可用于自定义的剥离、校验或测试代码
+func HeaderNames(opts PaddingOptions) []string {
+	opts = normalizeOptions(opts, "padding.HeaderNames")
+	return slices.Clone(opts.headerNames(opts.now()))
+}
+
+// stripPaddingHeaders 从 h 中移除所有当前可被识别的 padding 头部
+// SelfDescribing 时还会移除任何名称下值为自描述 padding 值的头部, 不受两端头部名称配置不一致的影响
+func stripPaddingHeaders(h http.Header, opts *PaddingOptions) {
+	for _, name := range opts.headerNames(opts.now()) {
+		h.Del(name)
+	}
+	if opts.SelfDescribing {
+		stripDescribedHeaders(h)
+	}
+}
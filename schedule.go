@@ -0,0 +1,74 @@
+package padding
+
+import (
+	"time"
+)
+
+// ScheduledProfile 在每天的 [Start, End) 时间段内代替 Profile 作为默认的 Profile
+// Start 与 End 是自当天 0 点起的偏移; End 小于等于 Start 表示跨越午夜 (如 22:00 到次日 06:00)
+type ScheduledProfile struct {
+	Start   time.Duration
+	End     time.Duration
+	Profile *PaddingProfile
+}
+
+// HourRange 构造一个从 startHour 点到 endHour 点 (不含) 的 ScheduledProfile
+// 例如 HourRange(1, 7, &ProfileLong) 在凌晨低流量时段使用更重的 padding
+func HourRange(startHour, endHour int, p *PaddingProfile) ScheduledProfile {
+	return ScheduledProfile{
+		Start:   time.Duration(startHour) * time.Hour,
+		End:     time.Duration(endHour) * time.Hour,
+		Profile: p,
+	}
+}
+
+// contains 报告当天的时间偏移 offset 是否落在该时间段内
+func (s ScheduledProfile) contains(offset time.Duration) bool {
+	if s.Start < s.End {
+		return offset >= s.Start && offset < s.End
+	}
+	return offset >= s.Start || offset < s.End
+}
+
+// normalizeSchedule 校验时间表, 移除 Profile 为 nil 的条目并将偏移规整到一天之内
+func normalizeSchedule(schedule []ScheduledProfile, logPrefix string) []ScheduledProfile {
+	out := make([]ScheduledProfile, 0, len(schedule))
+	for _, s := range schedule {
+		if s.Profile == nil {
+			continue
+		}
+		out = append(out, ScheduledProfile{
+			Start:   wrapDay(s.Start),
+			End:     wrapDay(s.End),
+			Profile: normalizeProfile(s.Profile, logPrefix),
+		})
+	}
+	return out
+}
+
+// wrapDay 将 d 规整到 [0, 24h)
+func wrapDay(d time.Duration) time.Duration {
+	d %= 24 * time.Hour
+	if d < 0 {
+		d += 24 * time.Hour
+	}
+	return d
+}
+
+// profileAt 返回 now 时刻生效的默认 Profile: 第一个包含当前时刻的 Schedule 条目, 否则为 Profile
+func (opts *PaddingOptions) profileAt(now time.Time) *PaddingProfile {
+	if len(opts.Schedule) == 0 {
+		return opts.Profile
+	}
+	if opts.ScheduleLocation != nil {
+		now = now.In(opts.ScheduleLocation)
+	}
+	year, month, day := now.Date()
+	offset := now.Sub(time.Date(year, month, day, 0, 0, 0, 0, now.Location()))
+	for _, s := range opts.Schedule {
+		if s.contains(offset) {
+			return s.Profile
+		}
+	}
+	return opts.Profile
+}
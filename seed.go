@@ -0,0 +1,86 @@
+package padding
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"log"
+	mrand "math/rand/v2"
+	"net/http"
+)
+
+// FleetSeed 是同一域名后的一组网关实例共享的种子
+// 负载均衡的多个副本若各自随机生成密钥, 轮换的头部名称与按会话、实体派生的长度在不同副本上互不相同,
+// 观察者据此就能分辨出每个请求由哪个实例处理; 由共享种子派生这些参数后, 所有实例对同一请求给出相同的决定
+type FleetSeed struct {
+	// Secret 是所有实例共享的种子, 应通过配置分发或密钥管理服务交换, 为空时不生效
+	Secret []byte `json:"-"`
+	// Epoch 是种子的轮次, 参与所有派生; 各实例同时切换到新的 Epoch (如通过 Reload) 即可整体轮换派生的参数
+	Epoch uint64
+	// PerURL 为 true 时, 未启用 Session 的响应与出站请求的 padding 长度由方法、主机与路径确定性地派生,
+	// 同一 URL 在所有实例上得到相同的长度; 长度按所选的 Profile (包括其分布与分量) 采样
+	PerURL bool
+}
+
+// normalizeFleetSeed 返回 FleetSeed 的副本, Secret 为空时记录警告并返回 nil
+func normalizeFleetSeed(s FleetSeed, logPrefix string) *FleetSeed {
+	if len(s.Secret) == 0 {
+		log.Printf("%s: Warning - Seed has an empty Secret and will be ignored.", logPrefix)
+		return nil
+	}
+	s.Secret = append([]byte(nil), s.Secret...)
+	return &s
+}
+
+// derive 返回种子在当前轮次下为 purpose 派生的 32 字节密钥, 不同用途的密钥互相独立
+func (s *FleetSeed) derive(purpose string) []byte {
+	mac := hmac.New(sha256.New, s.Secret)
+	var epoch [8]byte
+	binary.BigEndian.PutUint64(epoch[:], s.Epoch)
+	mac.Write(epoch[:])
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// applySeed 为未显式设置密钥的 RotateHeader、Session 与 Entity 填入由种子派生的密钥
+// 在各自的 normalize 之前调用, 使它们不再随机生成仅在当前进程内有效的密钥
+func applySeed(opts *PaddingOptions) {
+	s := opts.Seed
+	if opts.RotateHeader != nil && len(opts.RotateHeader.Secret) == 0 && len(opts.RotateHeader.Names) == 0 {
+		r := *opts.RotateHeader
+		r.Secret = s.derive("rotate")
+		opts.RotateHeader = &r
+	}
+	if opts.Session != nil && len(opts.Session.Secret) == 0 {
+		sp := *opts.Session
+		sp.Secret = s.derive("session")
+		opts.Session = &sp
+	}
+	if opts.Entity != nil && len(opts.Entity.Secret) == 0 {
+		e := *opts.Entity
+		e.Secret = s.derive("entity")
+		opts.Entity = &e
+	}
+}
+
+// urlProfile 在 Seed.PerURL 时返回一个长度固定的 Profile, 长度以由种子与请求的方法、主机、路径播种的生成器
+// 按 profile 采样; 未启用或采样失败时原样返回 profile
+func urlProfile(req *http.Request, profile *PaddingProfile, opts *PaddingOptions) *PaddingProfile {
+	s := opts.Seed
+	if s == nil || !s.PerURL || req == nil || req.URL == nil {
+		return profile
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	mac := hmac.New(sha256.New, s.derive("url"))
+	mac.Write([]byte(req.Method + "\n" + host + "\n" + req.URL.Path))
+	var seed [32]byte
+	copy(seed[:], mac.Sum(nil))
+	n, err := profile.sample(mrand.NewChaCha8(seed))
+	if err != nil {
+		return profile
+	}
+	return &PaddingProfile{MinLength: n, MaxLength: n}
+}
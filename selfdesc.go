@@ -0,0 +1,163 @@
+package padding
+
+import (
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 自描述的 padding 值格式 (SelfDescribing):
+//
+//	value   = "p1" 1*( "." segment )
+//	segment = kind length ":" data
+//
+// kind 是单个小写字母, length 是 data 的十进制字节数 (可以带前导零)
+// 每一段都带有自身的长度, 剥离与校验组件不需要知道对端的 Profile、头部名称或启用了哪些特性,
+// 就能识别一个值是否为 padding 并逐段解析; 未知的 kind 会被原样解析, 以便之后的版本加入新的段
+
+// valueVersion 是自描述 padding 值的版本前缀
+const valueVersion = "p1"
+
+// 自描述 padding 值中的段类型
+const (
+	// SegmentFiller 是随机填充
+	SegmentFiller byte = 'r'
+	// SegmentSignature 是 AuthKey 的 HMAC, 对值中位于它之前的全部内容计算
+	SegmentSignature byte = 's'
+	// SegmentNonce 是带时间戳的 nonce, 见 PaddingOptions.ReplayWindow
+	SegmentNonce byte = 'n'
+)
+
+// Segment 是自描述 padding 值中的一段
+type Segment struct {
+	Kind byte
+	Data string
+}
+
+// ErrNotPaddingValue 表示一个值不是合法的自描述 padding 值
+var ErrNotPaddingValue = errors.New("padding: not a self-describing padding value")
+
+// ParseValue 解析自描述格式的 padding 值, 格式不正确时返回包装了 ErrNotPaddingValue 的错误
+func ParseValue(value string) ([]Segment, error) {
+	segments, _, err := parseValue(value)
+	return segments, err
+}
+
+// IsPaddingValue 报告 value 是否是合法的自描述 padding 值
+func IsPaddingValue(value string) bool {
+	_, _, err := parseValue(value)
+	return err == nil
+}
+
+// parseValue 解析 value, 同时返回每一段 (包括其前面的 '.') 在 value 中的起始偏移
+func parseValue(value string) ([]Segment, []int, error) {
+	rest, ok := strings.CutPrefix(value, valueVersion)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: missing %q prefix", ErrNotPaddingValue, valueVersion)
+	}
+	var segments []Segment
+	var offsets []int
+	for rest != "" {
+		offset := len(value) - len(rest)
+		if len(rest) < 4 || rest[0] != '.' || rest[1] < 'a' || rest[1] > 'z' {
+			return nil, nil, fmt.Errorf("%w: malformed segment at offset %d", ErrNotPaddingValue, offset)
+		}
+		kind := rest[1]
+		digits, data, ok := strings.Cut(rest[2:], ":")
+		if !ok || digits == "" || len(digits) > 4 || strings.Trim(digits, "0123456789") != "" {
+			return nil, nil, fmt.Errorf("%w: malformed segment length at offset %d", ErrNotPaddingValue, offset)
+		}
+		n, _ := strconv.Atoi(digits)
+		if n > len(data) {
+			return nil, nil, fmt.Errorf("%w: segment at offset %d is truncated", ErrNotPaddingValue, offset)
+		}
+		segments = append(segments, Segment{Kind: kind, Data: data[:n]})
+		offsets = append(offsets, offset)
+		rest = data[n:]
+	}
+	if len(segments) == 0 {
+		return nil, nil, fmt.Errorf("%w: no segments", ErrNotPaddingValue)
+	}
+	return segments, offsets, nil
+}
+
+// segmentPrefix 返回编码后总长度恰好为 n 的段的前缀 ("." kind length ":") 与 data 的长度
+// n 小于最短的段 (4 字节) 时按 4 计算; 长度字段在需要时补前导零, 使任意 n 都能精确达到
+func segmentPrefix(kind byte, n int) (string, int) {
+	n = max(n, 4)
+	for width := 1; ; width++ {
+		dataLen := n - 3 - width
+		if len(strconv.Itoa(max(dataLen, 0))) <= width {
+			return fmt.Sprintf(".%c%0*d:", kind, width, max(dataLen, 0)), max(dataLen, 0)
+		}
+	}
+}
+
+// describedValue 生成总长度为 length 的自描述 padding 值, 只包含一个随机填充段
+// length 小于最短的合法值 (6 字节) 时返回最短的值
+func describedValue(length int, opts *PaddingOptions) string {
+	return valueVersion + fillerSegment(length-len(valueVersion), opts)
+}
+
+// fillerSegment 生成编码后总长度为 n 的随机填充段
+func fillerSegment(n int, opts *PaddingOptions) string {
+	prefix, dataLen := segmentPrefix(SegmentFiller, n)
+	return prefix + paddingString(opts.Rand, dataLen, opts.Pool, opts.ValueCache)
+}
+
+// signDescribedValue 将自描述的 padding 值改写为长度不变、以签名段结尾的值
+// ReplayWindow 大于 0 时在填充段之前加入在时刻 now 签发的 nonce 段
+func signDescribedValue(req *http.Request, value string, now time.Time, opts *PaddingOptions) string {
+	sigPrefix := fmt.Sprintf(".%c%d:", SegmentSignature, padMACSize)
+	body := valueVersion
+	if opts.ReplayWindow > 0 {
+		body += fmt.Sprintf(".%c%d:", SegmentNonce, nonceSize) + newNonce(now, opts)
+	}
+	body += fillerSegment(len(value)-len(body)-len(sigPrefix)-padMACSize, opts)
+	return body + sigPrefix + padMAC(opts.AuthKey, req, body)
+}
+
+// verifyDescribedValue 报告自描述的 padding 值是否以正确的签名段结尾, 同时返回其中的 nonce (没有时为空)
+func verifyDescribedValue(req *http.Request, value string, opts *PaddingOptions) (string, bool) {
+	segments, offsets, err := parseValue(value)
+	if err != nil {
+		return "", false
+	}
+	last := len(segments) - 1
+	if segments[last].Kind != SegmentSignature {
+		return "", false
+	}
+	body := value[:offsets[last]]
+	if !hmac.Equal([]byte(segments[last].Data), []byte(padMAC(opts.AuthKey, req, body))) {
+		return "", false
+	}
+	for _, seg := range segments[:last] {
+		if seg.Kind == SegmentNonce {
+			return seg.Data, true
+		}
+	}
+	return "", true
+}
+
+// stripDescribedHeaders 移除 h 中所有值均为自描述 padding 值的头部, 不论其名称
+func stripDescribedHeaders(h http.Header) {
+	for key, values := range h {
+		if len(values) == 0 {
+			continue
+		}
+		all := true
+		for _, v := range values {
+			if !IsPaddingValue(v) {
+				all = false
+				break
+			}
+		}
+		if all {
+			delete(h, key)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package padding
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	mrand "math/rand/v2"
+	"net/http"
+)
+
+// SessionPadding 配置会话一致的 padding: 同一会话内所有消息使用由会话键派生的相同 padding 长度,
+// 观察者无法通过比较同一会话内的多个响应推断真实大小的差异, 不同会话之间的偏移又各不相同;
+// 长度只取决于会话而不取决于 URL, 不会形成按 URL 固定的指纹
+type SessionPadding struct {
+	// Secret 是派生长度所用的 HMAC 密钥; 为空时在配置校验时随机生成, 仅在当前进程内保持一致
+	// 多个实例需要对同一会话给出相同长度时应显式设置
+	Secret []byte `json:"-"`
+	// Cookie 是会话 cookie 的名称; 请求中存在该 cookie 时以其值作为会话键
+	Cookie string
+	// Key 不为 nil 时用于从请求中提取会话键, 优先于 Cookie; 返回空字符串表示没有会话
+	Key func(req *http.Request) string `json:"-"`
+}
+
+// normalizeSessionPadding 返回补全默认值后的 SessionPadding 副本
+func normalizeSessionPadding(s SessionPadding) *SessionPadding {
+	if len(s.Secret) == 0 {
+		s.Secret = make([]byte, 32)
+		if _, err := rand.Read(s.Secret); err != nil {
+			panic("toukaPadding: failed to generate session padding secret: " + err.Error())
+		}
+	} else {
+		s.Secret = append([]byte(nil), s.Secret...)
+	}
+	return &s
+}
+
+// sessionKey 返回请求的会话键: 依次尝试 Key、Cookie, 最后退化为连接的远端地址 (每个连接一个会话)
+func (s *SessionPadding) sessionKey(req *http.Request) string {
+	if s.Key != nil {
+		return s.Key(req)
+	}
+	if s.Cookie != "" {
+		if c, err := req.Cookie(s.Cookie); err == nil && c.Value != "" {
+			return c.Value
+		}
+	}
+	return req.RemoteAddr
+}
+
+// sessionProfile 在启用会话一致 padding 时返回一个长度固定为会话派生值的 Profile,
+// 派生值按 profile 采样 (遵循其分布与组合分量); 没有会话键时原样返回 profile,
+// 未启用时交给 urlProfile 按 Seed 处理
+func sessionProfile(req *http.Request, profile *PaddingProfile, opts *PaddingOptions) *PaddingProfile {
+	s := opts.Session
+	if s == nil || req == nil {
+		return urlProfile(req, profile, opts)
+	}
+	key := s.sessionKey(req)
+	if key == "" {
+		return profile
+	}
+	return derivedProfile(s.Secret, key, profile)
+}
+
+// derivedProfile 返回一个长度固定的 Profile, 长度以由 secret 对 key 的 HMAC 播种的生成器按 profile 采样,
+// 与 urlProfile 相同, profile 的 Distribution 与 Components 照常生效; 采样失败时原样返回 profile
+func derivedProfile(secret []byte, key string, profile *PaddingProfile) *PaddingProfile {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(key))
+	var seed [32]byte
+	copy(seed[:], mac.Sum(nil))
+	n, err := profile.sample(mrand.NewChaCha8(seed))
+	if err != nil {
+		return profile
+	}
+	return &PaddingProfile{MinLength: n, MaxLength: n}
+}
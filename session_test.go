@@ -0,0 +1,45 @@
+package padding
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestDerivedProfile(t *testing.T) {
+	secret := []byte("session-secret")
+	composite := CompositeProfile(
+		WeightedProfile{Profile: &PaddingProfile{MinLength: 10, MaxLength: 10}, Weight: 1},
+		WeightedProfile{Profile: &PaddingProfile{MinLength: 1000, MaxLength: 1000}, Weight: 1},
+	)
+	seen := map[int]int{}
+	for i := range 200 {
+		key := "session-" + strconv.Itoa(i)
+		p := derivedProfile(secret, key, composite)
+		if p.MinLength != p.MaxLength {
+			t.Fatalf("derived profile %d-%d is not fixed", p.MinLength, p.MaxLength)
+		}
+		// 组合 Profile 本身没有范围, 长度只能来自其分量
+		if p.MinLength != 10 && p.MinLength != 1000 {
+			t.Fatalf("derived length %d is not produced by any component", p.MinLength)
+		}
+		if q := derivedProfile(secret, key, composite); q.MinLength != p.MinLength {
+			t.Fatalf("key %q derived %d then %d", key, p.MinLength, q.MinLength)
+		}
+		seen[p.MinLength]++
+	}
+	if len(seen) != 2 {
+		t.Errorf("derived lengths %v do not cover both components", seen)
+	}
+
+	// 指数分布集中在 MinLength 附近
+	exp := &PaddingProfile{MinLength: 0, MaxLength: 4000, Distribution: DistributionExponential}
+	low := 0
+	for i := range 200 {
+		if derivedProfile(secret, strconv.Itoa(i), exp).MinLength < 2000 {
+			low++
+		}
+	}
+	if low < 150 {
+		t.Errorf("%d of 200 derived lengths below the midpoint, want the exponential distribution to be respected", low)
+	}
+}
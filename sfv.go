@@ -0,0 +1,35 @@
+package padding
+
+// StructuredFieldType 指定 padding 头部值采用的 RFC 8941 结构化字段条目类型
+type StructuredFieldType string
+
+const (
+	// StructuredFieldNone 不做结构化约束, 使用数据池中的原始内容 (默认)
+	StructuredFieldNone StructuredFieldType = ""
+	// StructuredFieldToken 将值输出为 sf-token: 以字母开头, 其余为 tchar、":" 或 "/"
+	StructuredFieldToken StructuredFieldType = "token"
+	// StructuredFieldByteSequence 将值输出为 sf-binary: 以 ":" 包围的 base64 内容
+	StructuredFieldByteSequence StructuredFieldType = "byte-sequence"
+)
+
+const (
+	alphaCharset    = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	sfTokenCharset  = alphaCharset + "0123456789!#$%&'*+-.^_`|~:/"
+	sfBase64Charset = alphaCharset + "0123456789+/"
+)
+
+// structuredValue 生成长度为 n 的合法结构化字段值
+// 字节序列的 base64 内容长度必须是 4 的整数倍, 因此实际长度可能比 n 少至多 3 个字节
+func structuredValue(typ StructuredFieldType, src RandSource, n int) string {
+	switch typ {
+	case StructuredFieldToken:
+		return randomString(src, alphaCharset, 1) + randomString(src, sfTokenCharset, n-1)
+	case StructuredFieldByteSequence:
+		inner := (n - 2) / 4 * 4
+		if inner <= 0 {
+			return "::"
+		}
+		return ":" + randomString(src, sfBase64Charset, inner) + ":"
+	}
+	return string(getPaddingSlice(src, n))
+}
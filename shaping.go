@@ -0,0 +1,150 @@
+package padding
+
+import (
+	"log"
+	"time"
+)
+
+// ConstantRateOptions 配置恒定速率的响应整形 (仅服务端)
+// 响应体以固定大小的写入、按恒定字节速率发出, 末尾不足一块的部分会补足到整块,
+// 从而同时规整响应的大小与时间特征; 补足只对 text/html 与 JSON 响应进行, 其它类型的最后一块保持原样
+type ConstantRateOptions struct {
+	// Rate 是发送速率 (字节/秒), 小于等于 0 时为 64 KiB/s
+	Rate int
+	// ChunkSize 是每次写入的固定字节数, 小于等于 0 时为 1024
+	ChunkSize int
+	// MaxLatency 是整形为单个响应增加的最大延迟, 超过后剩余数据不再整形而直接写出;
+	// 小于等于 0 时为 2 秒
+	MaxLatency time.Duration
+}
+
+// normalizeConstantRate 返回补全默认值后的 ConstantRateOptions 副本
+func normalizeConstantRate(c ConstantRateOptions) *ConstantRateOptions {
+	if c.Rate <= 0 {
+		c.Rate = 64 << 10
+	}
+	if c.ChunkSize <= 0 {
+		c.ChunkSize = 1024
+	}
+	if c.MaxLatency <= 0 {
+		c.MaxLatency = 2 * time.Second
+	}
+	return &c
+}
+
+// rateShaper 保存单个响应的整形状态
+type rateShaper struct {
+	opts     *ConstantRateOptions
+	interval time.Duration      // 相邻两次写入之间的间隔
+	filler   func(n int) []byte // 生成末尾补足数据, 为 nil 时不补足
+	buf      []byte
+	next     time.Time     // 下一次写入的计划时间
+	delayed  time.Duration // 已经增加的延迟
+	bypass   bool          // 延迟超过上限后不再整形
+}
+
+// startShaping 在 WriteHeader 中调用, 为允许携带响应体的响应启用恒定速率整形
+// SSE 响应依赖及时送达且有自己的保活 padding, 不做整形; 响应会被补足时移除 Content-Length,
+// 因为补足的数据会使其失效
+func (prw *paddingResponseWriter) startShaping(statusCode int) {
+	if !bodyAllowed(prw.req.Method, statusCode) || mediaType(prw.Header()) == "text/event-stream" {
+		return
+	}
+	c := prw.opts.ConstantRate
+	rs := &rateShaper{
+		opts:     c,
+		interval: time.Duration(c.ChunkSize) * time.Second / time.Duration(c.Rate),
+	}
+	switch mt := mediaType(prw.Header()); {
+	case encoded(prw.Header()), partialContent(statusCode):
+	case mt == "text/html":
+		rs.filler = func(n int) []byte { return htmlCommentFiller(prw.opts.Rand, n) }
+	case isJSONMediaType(mt):
+		rs.filler = whitespaceFiller
+	}
+	if rs.filler != nil {
+		prw.Header().Del("Content-Length")
+	}
+	prw.shaper = rs
+}
+
+// writeShaped 缓冲 data 并按固定大小、恒定速率写出完整的块, 调用方需持有 writeMu
+func (prw *paddingResponseWriter) writeShaped(data []byte) (int, error) {
+	rs := prw.shaper
+	if rs.bypass {
+		return prw.ResponseWriter.Write(data)
+	}
+	rs.buf = append(rs.buf, data...)
+	for len(rs.buf) >= rs.opts.ChunkSize {
+		if err := prw.emitShaped(rs.buf[:rs.opts.ChunkSize]); err != nil {
+			return 0, err
+		}
+		rs.buf = rs.buf[rs.opts.ChunkSize:]
+		if rs.bypass {
+			// 超过延迟上限, 剩余数据直接写出
+			_, err := prw.ResponseWriter.Write(rs.buf)
+			rs.buf = nil
+			return len(data), err
+		}
+	}
+	return len(data), nil
+}
+
+// finishShaping 在处理链执行完毕后写出整形器中剩余的数据
+func (prw *paddingResponseWriter) finishShaping() {
+	if prw.shaper == nil || prw.ResponseWriter.IsHijacked() {
+		return
+	}
+	prw.writeMu.Lock()
+	defer prw.writeMu.Unlock()
+	fill, err := prw.flushShaped()
+	prw.written += int64(fill)
+	if err != nil {
+		log.Printf("toukaPadding: failed to write shaped response body: %v", err)
+	}
+}
+
+// flushShaped 在响应结束时将剩余数据补足为整块后写出, 返回补足的字节数, 调用方需持有 writeMu
+func (prw *paddingResponseWriter) flushShaped() (int, error) {
+	rs := prw.shaper
+	if len(rs.buf) == 0 {
+		return 0, nil
+	}
+	fill := 0
+	if rs.filler != nil && !rs.bypass {
+		fill = rs.opts.ChunkSize - len(rs.buf)
+		rs.buf = append(rs.buf, rs.filler(fill)...)
+	}
+	err := prw.emitShaped(rs.buf)
+	rs.buf = nil
+	return fill, err
+}
+
+// emitShaped 等待到下一次计划写入的时间后写出 chunk 并立即 Flush
+// 累计延迟将超过 MaxLatency, 或等待会越过请求 context 的截止时间 (留出 DeadlineMargin) 时不再等待, 并将整形器切换为直通
+func (prw *paddingResponseWriter) emitShaped(chunk []byte) error {
+	rs := prw.shaper
+	now := time.Now()
+	if rs.next.IsZero() {
+		rs.next = now
+	}
+	if wait := rs.next.Sub(now); wait > 0 {
+		if rs.delayed+wait > rs.opts.MaxLatency || clampDelay(prw.req.Context(), wait, prw.opts.DeadlineMargin) < wait {
+			// 超过延迟上限, 或继续等待会把请求推过截止时间
+			rs.bypass = true
+			if dm, ok := prw.opts.Metrics.(DelayMetrics); ok {
+				dm.RecordDelay(wait, 0)
+			}
+		} else {
+			time.Sleep(wait)
+			rs.delayed += wait
+			now = rs.next
+		}
+	}
+	rs.next = now.Add(rs.interval)
+	if _, err := prw.ResponseWriter.Write(chunk); err != nil {
+		return err
+	}
+	prw.ResponseWriter.Flush()
+	return nil
+}
@@ -0,0 +1,130 @@
+package padding
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// matchPath 报告 path 是否匹配 SkipPaths 中的某个模式
+// 模式以 "*" 结尾时按前缀匹配 (如 "/static/*"), 否则要求完全相等
+func matchPath(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if path == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// matchUserAgent 报告 ua 是否包含 patterns 中的某个子串 (不区分大小写)
+func matchUserAgent(patterns []string, ua string) bool {
+	if ua == "" {
+		return false
+	}
+	ua = strings.ToLower(ua)
+	for _, pattern := range patterns {
+		if strings.Contains(ua, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// lowerNonEmpty 返回 patterns 中非空项的小写副本
+func lowerNonEmpty(patterns []string) []string {
+	out := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		if p != "" {
+			out = append(out, strings.ToLower(p))
+		}
+	}
+	return out
+}
+
+// skipRequest 报告是否应跳过对本次请求 (或其响应) 的 padding
+// 路径命中 SkipPaths、User-Agent 命中 SkipUserAgents、SkipRequest 返回 true, 或未通过 Probability 抽样时返回 true
+func skipRequest(req *http.Request, opts *PaddingOptions) bool {
+	if req.URL != nil && matchPath(opts.SkipPaths, req.URL.Path) {
+		return true
+	}
+	if len(opts.SkipUserAgents) > 0 && matchUserAgent(opts.SkipUserAgents, req.UserAgent()) {
+		return true
+	}
+	if opts.SkipRequest != nil && opts.SkipRequest(req) {
+		return true
+	}
+	return opts.Probability != nil && !randChance(opts.Rand, *opts.Probability)
+}
+
+// DefaultSkipMethods 是默认不为其响应添加 padding 的请求方法, 可通过 PadAllResponses 关闭
+// HEAD 响应没有响应体, 携带 padding 头部只会形成与 GET 不一致的可识别特征
+var DefaultSkipMethods = []string{http.MethodHead}
+
+// DefaultSkipStatusCodes 是默认不添加 padding 的响应状态码, 可通过 PadAllResponses 关闭
+// 这些响应没有响应体, 其中 304 的头部还会被缓存合并到已存储的响应上
+var DefaultSkipStatusCodes = []int{
+	http.StatusSwitchingProtocols,
+	http.StatusNoContent,
+	http.StatusNotModified,
+}
+
+// skipResponse 报告是否应跳过对给定状态码与头部 h 的响应的 padding
+// 除 SkipStatusCodes 与 SkipWithHeaders 外, 未设置 PadAllResponses 时还会应用 DefaultSkipMethods 与 DefaultSkipStatusCodes;
+// 设置了 Preflight 时 OPTIONS 响应只按其 Skip 决定
+func skipResponse(req *http.Request, statusCode int, h http.Header, opts *PaddingOptions) bool {
+	if slices.Contains(opts.SkipStatusCodes, statusCode) || skipWithHeader(h, opts) {
+		return true
+	}
+	if preflight(req, opts) {
+		return opts.Preflight.Skip
+	}
+	if opts.PadAllResponses || entityRevalidation(req, statusCode, h, opts) {
+		return false
+	}
+	if req != nil && slices.Contains(DefaultSkipMethods, req.Method) {
+		return true
+	}
+	return slices.Contains(DefaultSkipStatusCodes, statusCode)
+}
+
+// informational 报告 statusCode 是否为 1xx 临时响应 (101 除外)
+// 临时响应之后还会有最终响应, 不应占用唯一一次的 padding 机会
+func informational(statusCode int) bool {
+	return statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols
+}
+
+// overrideMode 是 OverrideHeader 指定的按请求覆盖方式
+type overrideMode int
+
+const (
+	overrideNone overrideMode = iota
+	overrideOn
+	overrideOff
+)
+
+// override 读取并移除 req 中的覆盖请求头, 仅在请求可信时返回其指定的覆盖方式
+func override(req *http.Request, opts *PaddingOptions) overrideMode {
+	if opts.OverrideHeader == "" || req.Header == nil {
+		return overrideNone
+	}
+	value := req.Header.Get(opts.OverrideHeader)
+	if value == "" {
+		return overrideNone
+	}
+	req.Header.Del(opts.OverrideHeader)
+	if opts.TrustOverride == nil || !opts.TrustOverride(req) {
+		return overrideNone
+	}
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "on":
+		return overrideOn
+	case "off":
+		return overrideOff
+	}
+	return overrideNone
+}
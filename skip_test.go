@@ -0,0 +1,48 @@
+package padding
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSkipRequestProbability(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	for _, c := range []struct {
+		name        string
+		probability *float64
+		wantSkip    int // 100 次请求中应跳过的次数
+	}{
+		{"nil", nil, 0},
+		{"zero", new(float64), 100},
+		{"one", floatPtr(1), 0},
+		{"negative", floatPtr(-0.5), 100},
+		{"above one", floatPtr(2), 0},
+	} {
+		opts := normalizeOptions(PaddingOptions{Probability: c.probability}, "test")
+		skipped := 0
+		for range 100 {
+			if skipRequest(req, &opts) {
+				skipped++
+			}
+		}
+		if skipped != c.wantSkip {
+			t.Errorf("%s: skipped %d of 100 requests, want %d", c.name, skipped, c.wantSkip)
+		}
+	}
+}
+
+func TestNormalizeOptionsCopiesProbability(t *testing.T) {
+	p := 0.5
+	opts := normalizeOptions(PaddingOptions{Probability: &p}, "test")
+	p = 0
+	if opts.Probability == nil || *opts.Probability != 0.5 {
+		t.Fatalf("Probability = %v after the caller changed its value, want 0.5", opts.Probability)
+	}
+	// 规范化应当幂等
+	again := normalizeOptions(opts, "test")
+	if *again.Probability != 0.5 {
+		t.Fatalf("Probability = %g after normalizing twice, want 0.5", *again.Probability)
+	}
+}
+
+func floatPtr(v float64) *float64 { return &v }
@@ -0,0 +1,75 @@
+package padding
+
+import "net/http"
+
+// sniffLen 是 http.DetectContentType 检查的最大字节数, SniffBytes 超过它时按它计算
+const sniffLen = 512
+
+// 处理函数没有设置 Content-Type 时, net/http 会在第一次 Write 时按响应体的开头嗅探内容类型,
+// 而 padding 的决定 (ProfileByContentType、响应体 padding、StreamPadding 等) 在 WriteHeader 中就已做出,
+// 此时看到的内容类型为空; 设置 SniffBytes 后, 这类响应的头部推迟到缓冲了足够的响应体之后才写出,
+// 先按 http.DetectContentType 设置 Content-Type, 再照常做出 padding 决定
+
+// sniffing 在 WriteHeader 中调用, 报告是否应推迟写出头部以等待嗅探内容类型
+// 已在等待时, 之后的 WriteHeader (包括 Write 隐式的 200) 与 net/http 一样被忽略
+func (prw *paddingResponseWriter) sniffing(statusCode int) bool {
+	if prw.sniffStatus != 0 {
+		return true
+	}
+	if prw.opts.SniffBytes <= 0 || prw.sniffed || prw.wroteHeader || !bodyAllowed(prw.req.Method, statusCode) {
+		return false
+	}
+	h := prw.Header()
+	if _, ok := h["Content-Type"]; ok || encoded(h) {
+		// 显式设置 (包括设置为空以禁止嗅探) 或已编码的响应不需要嗅探
+		return false
+	}
+	prw.sniffed = true
+	prw.sniffStatus = statusCode
+	return true
+}
+
+// bufferSniff 缓冲等待嗅探期间写入的响应体, 缓冲达到 SniffBytes 时写出头部与缓冲的数据
+func (prw *paddingResponseWriter) bufferSniff(data []byte) (int, error) {
+	prw.sniffBuf = append(prw.sniffBuf, data...)
+	if len(prw.sniffBuf) < min(prw.opts.SniffBytes, sniffLen) {
+		return len(data), nil
+	}
+	if err := prw.releaseSniff(); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// releaseSniff 结束等待: 按缓冲的数据设置 Content-Type, 以推迟的状态码写出头部, 再写出缓冲的数据
+// 在缓冲已满、处理函数 Flush 以及处理链结束时调用; 不在等待时什么也不做
+func (prw *paddingResponseWriter) releaseSniff() error {
+	status := prw.sniffStatus
+	if status == 0 {
+		return nil
+	}
+	buf := prw.sniffBuf
+	prw.sniffStatus, prw.sniffBuf = 0, nil
+	if h := prw.Header(); len(buf) > 0 && h.Get("Content-Type") == "" {
+		h.Set("Content-Type", http.DetectContentType(buf))
+	}
+	prw.WriteHeader(status)
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := prw.Write(buf)
+	return err
+}
+
+// Status 在等待嗅探期间返回推迟的状态码
+func (prw *paddingResponseWriter) Status() int {
+	if prw.sniffStatus != 0 {
+		return prw.sniffStatus
+	}
+	return prw.ResponseWriter.Status()
+}
+
+// Written 在等待嗅探期间报告头部已经写出, 处理链不会再写出默认的响应
+func (prw *paddingResponseWriter) Written() bool {
+	return prw.sniffStatus != 0 || prw.ResponseWriter.Written()
+}
@@ -0,0 +1,130 @@
+package padding
+
+import (
+	"time"
+)
+
+// SSEKeepAliveOptions 配置 text/event-stream 响应的保活 padding 注释
+// 注释行 (": <padding>") 会被 SSE 客户端忽略, 可以在连接空闲时提供掩护流量而不影响事件解析
+type SSEKeepAliveOptions struct {
+	MinInterval time.Duration // 两次注释之间的最小间隔, 小于等于 0 时为 5 秒
+	MaxInterval time.Duration // 两次注释之间的最大间隔, 小于等于 0 时为 30 秒
+	// Profile 决定每条注释中 padding 的长度, 为 nil 时使用 ProfileShort
+	Profile *PaddingProfile
+}
+
+// normalizeSSEKeepAlive 返回补全默认值后的 SSEKeepAliveOptions 副本
+func normalizeSSEKeepAlive(s SSEKeepAliveOptions, logPrefix string) *SSEKeepAliveOptions {
+	if s.MinInterval <= 0 {
+		s.MinInterval = 5 * time.Second
+	}
+	if s.MaxInterval <= 0 {
+		s.MaxInterval = 30 * time.Second
+	}
+	if s.MinInterval > s.MaxInterval {
+		s.MinInterval = s.MaxInterval
+	}
+	if s.Profile == nil {
+		s.Profile = &ProfileShort
+	}
+	s.Profile = normalizeProfile(s.Profile, logPrefix)
+	return &s
+}
+
+// sseKeepAlive 在后台为一个 SSE 响应定期写入 padding 注释
+type sseKeepAlive struct {
+	prw  *paddingResponseWriter
+	opts *SSEKeepAliveOptions
+	stop chan struct{}
+	done chan struct{}
+	// newlines 记录处理函数已写入数据末尾连续换行符的数量
+	// 2 表示处于事件边界, 1 表示处于行边界, 0 表示位于一行中间, 此时不能插入注释
+	newlines int
+}
+
+// startSSEKeepAlive 为 prw 启动保活注释任务, 由 WriteHeader 在识别到 text/event-stream 时调用
+func (prw *paddingResponseWriter) startSSEKeepAlive() {
+	ka := &sseKeepAlive{
+		prw:      prw,
+		opts:     prw.opts.SSEKeepAlive,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		newlines: 2,
+	}
+	prw.sse = ka
+	go ka.run()
+}
+
+// observe 根据处理函数写入的数据更新行/事件边界状态, 调用方需持有 writeMu
+func (ka *sseKeepAlive) observe(data []byte) {
+	count := 0
+	for i := len(data) - 1; i >= 0; i-- {
+		switch data[i] {
+		case '\n':
+			count++
+		case '\r':
+		default:
+			ka.newlines = min(count, 2)
+			return
+		}
+	}
+	ka.newlines = min(ka.newlines+count, 2)
+}
+
+// run 以随机间隔写入 padding 注释, 直到响应结束或请求被取消
+func (ka *sseKeepAlive) run() {
+	defer close(ka.done)
+	done := ka.prw.req.Context().Done()
+	for {
+		interval, err := randInt(ka.prw.opts.Rand, int(ka.opts.MinInterval), int(ka.opts.MaxInterval))
+		if err != nil {
+			interval = int(ka.opts.MaxInterval)
+		}
+		timer := time.NewTimer(time.Duration(interval))
+		select {
+		case <-ka.stop:
+			timer.Stop()
+			return
+		case <-done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if err := ka.emit(); err != nil {
+			return
+		}
+	}
+}
+
+// shutdown 停止保活任务并等待其退出, 保证处理函数返回后不会再有写入
+func (ka *sseKeepAlive) shutdown() {
+	close(ka.stop)
+	<-ka.done
+}
+
+// emit 在当前边界允许的情况下写入一条 padding 注释并立即 Flush
+func (ka *sseKeepAlive) emit() error {
+	length, err := ka.opts.Profile.sample(ka.prw.opts.Rand)
+	if err != nil {
+		return nil
+	}
+
+	ka.prw.writeMu.Lock()
+	defer ka.prw.writeMu.Unlock()
+	if ka.newlines == 0 {
+		return nil
+	}
+	comment := make([]byte, 0, length+4)
+	comment = append(comment, ':', ' ')
+	comment = append(comment, getPaddingSlice(ka.prw.opts.Rand, length)...)
+	comment = append(comment, '\n')
+	if ka.newlines == 2 {
+		// 事件边界: 以空行结束, 不会与后续事件合并
+		comment = append(comment, '\n')
+	}
+	if _, err := ka.prw.ResponseWriter.Write(comment); err != nil {
+		return err
+	}
+	ka.prw.ResponseWriter.Flush()
+	return nil
+}
@@ -0,0 +1,119 @@
+package padding
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// 静态文件 (http.ServeFile、http.ServeContent 与 http.FileServer) 的响应有三个特点:
+//   - 设置了准确的 Content-Length, 并支持 Range 请求 (206)
+//   - 通过 io.Copy 写出响应体, 底层 ResponseWriter 实现了 io.ReaderFrom 时会使用 sendfile
+//   - 大小就是文件的大小, 同一个文件的每次响应都完全相同
+//
+// 头部 padding 对这类响应照常生效; paddingResponseWriter 实现了 io.ReaderFrom,
+// 没有启用改写响应体的特性时直接交给底层 ResponseWriter, 经 Padder.Handler 挂载时仍然使用 sendfile
+// 需要隐藏文件的确切大小时设置 ContentLengthBucket, 完整响应的 Content-Length 会被对齐到桶的整数倍;
+// 默认只作用于 text/html 与 JSON, 以 SRI 引用的脚本与样式表追加空白字符后哈希不再匹配, 见 ContentLengthBucketTypes
+
+// prepareBucket 在 WriteHeader 中于 prepareBodyPadding 之后调用,
+// 为未添加其他响应体 padding 的 200 响应准备空白字符, 使 Content-Length 对齐到 ContentLengthBucket 的整数倍
+// Range 请求的 206 响应、已编码的响应与 ContentLengthBucketTypes 之外的响应保持原样, 客户端拼接分段或解码时不会遇到多余的数据
+func (prw *paddingResponseWriter) prepareBucket(statusCode int) {
+	bucket := prw.opts.ContentLengthBucket
+	if bucket <= 0 || statusCode != http.StatusOK || !bodyAllowed(prw.req.Method, statusCode) {
+		return
+	}
+	if prw.bodyLength > 0 || prw.json != nil {
+		return
+	}
+	h := prw.Header()
+	cl := responseContentLength(h)
+	if cl < 0 || encoded(h) || !bucketFillable(prw.opts.ContentLengthBucketTypes, mediaType(h)) {
+		return
+	}
+	pad := int(roundUp64(cl, int64(bucket)) - cl)
+	if pad <= 0 {
+		return
+	}
+	prw.stats.recordBody(pad)
+	if prw.opts.Metrics != nil {
+		prw.opts.Metrics.RecordBodyPadding(pad)
+	}
+	if prw.opts.DryRun {
+		return
+	}
+	prw.bodyLength = pad
+	prw.bodyPadding = whitespaceFiller(pad)
+	h.Set("Content-Length", strconv.FormatInt(cl+int64(pad), 10))
+}
+
+// bucketFillable 报告媒体类型为 mt 的响应能否按 ContentLengthBucket 追加空白字符:
+// types 为空时只接受 text/html 与 JSON, 否则按精确类型或 "text/*" 这样的主类型通配匹配 types
+func bucketFillable(types []string, mt string) bool {
+	if !uniformFillable(mt) {
+		return false
+	}
+	if len(types) == 0 {
+		return mt == "text/html" || isJSONMediaType(mt)
+	}
+	major, _, _ := strings.Cut(mt, "/")
+	for _, t := range types {
+		if t == mt || t == major+"/*" || t == "*/*" {
+			return true
+		}
+	}
+	return false
+}
+
+// roundUp64 是 int64 版本的 roundUp
+func roundUp64(n, multiple int64) int64 {
+	if multiple <= 0 {
+		return n
+	}
+	return (n + multiple - 1) / multiple * multiple
+}
+
+// writerOnly 隐藏被包装者的 ReadFrom, 使 io.Copy 逐块调用 Write 而不是递归回到 ReadFrom
+type writerOnly struct {
+	io.Writer
+}
+
+// ReadFrom 使 http.ServeContent 等通过 io.Copy 写出的响应体在没有启用改写响应体的特性
+// (JSON 注入、StreamPadding、ConstantRate、Rechunk 与 SSE 保活) 时直接交给底层 ResponseWriter,
+// 底层支持时 (如 Padder.Handler 包装的 net/http 连接) 可以使用 sendfile; 否则逐块经过 Write
+func (prw *paddingResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	prw.ensureHeader()
+	if prw.failed {
+		return 0, ErrFailClosed
+	}
+	rf, ok := prw.ResponseWriter.(io.ReaderFrom)
+	if !ok || prw.sniffStatus != 0 || prw.json != nil || prw.stream != nil || prw.shaper != nil || prw.opts.Rechunk != nil || prw.sse != nil {
+		return io.Copy(writerOnly{prw}, r)
+	}
+	prw.writeMu.Lock()
+	defer prw.writeMu.Unlock()
+	n, err := rf.ReadFrom(r)
+	prw.written += n
+	return n, err
+}
+
+// ReadFrom 将响应体交给底层 http.ResponseWriter 的 ReadFrom, 保留 net/http 对文件的 sendfile 优化
+func (w *httpResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	rf, ok := w.ResponseWriter.(io.ReaderFrom)
+	if !ok {
+		return io.Copy(writerOnly{w}, r)
+	}
+	n, err := rf.ReadFrom(r)
+	w.size += int(n)
+	return n, err
+}
+
+var (
+	_ io.ReaderFrom = &paddingResponseWriter{}
+	_ io.ReaderFrom = &httpResponseWriter{}
+)
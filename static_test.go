@@ -0,0 +1,143 @@
+package padding_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/fenthope/padding"
+	"github.com/fenthope/padding/paddingtest"
+	"github.com/infinite-iroha/touka"
+)
+
+// staticFile 在临时目录中写入一个文本文件, 返回其路径与内容; 长度不是 512 的整数倍, 使分桶必然生效
+func staticFile(t *testing.T) (string, []byte) {
+	t.Helper()
+	content := []byte(strings.Repeat("static file line\n", 70))
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path, content
+}
+
+// staticOptions 是静态文件测试使用的配置, 总是添加头部 padding; 分桶默认不作用于 text/plain, 需要显式列出
+var staticOptions = padding.PaddingOptions{
+	Profile:                  &padding.PaddingProfile{MinLength: 16, MaxLength: 64},
+	ContentLengthBucket:      512,
+	ContentLengthBucketTypes: []string{"text/plain"},
+}
+
+func TestStaticFileTouka(t *testing.T) {
+	path, content := staticFile(t)
+	r := touka.New()
+	r.Use(padding.ToukaPaddingS(staticOptions))
+	r.GET("/file.txt", func(c *touka.Context) {
+		http.ServeFile(c.Writer, c.Request, path)
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	paddingtest.AssertStaticFile(t, srv.URL+"/file.txt", content, staticOptions)
+}
+
+func TestStaticFileHandler(t *testing.T) {
+	path, content := staticFile(t)
+	p := padding.NewPadder(staticOptions)
+	srv := httptest.NewServer(p.Handler(http.FileServer(http.Dir(filepath.Dir(path)))))
+	defer srv.Close()
+
+	paddingtest.AssertStaticFile(t, srv.URL+"/file.txt", content, staticOptions)
+}
+
+// readerFromRecorder 是实现了 io.ReaderFrom 的 ResponseRecorder, 记录 ReadFrom 被调用的次数
+// net/http 的连接以 ReadFrom 实现 sendfile, 调用次数大于 0 说明中间件没有阻断这条路径
+type readerFromRecorder struct {
+	*httptest.ResponseRecorder
+	readFrom int
+}
+
+func (w *readerFromRecorder) ReadFrom(r io.Reader) (int64, error) {
+	w.readFrom++
+	return io.Copy(w.ResponseRecorder, r)
+}
+
+func TestStaticFileReaderFrom(t *testing.T) {
+	path, content := staticFile(t)
+	for _, c := range []struct {
+		name string
+		opts padding.PaddingOptions
+	}{
+		{"header-only", padding.PaddingOptions{Profile: staticOptions.Profile}},
+		// 分桶的空白字符在响应体之后写出, 不影响文件内容本身经由 ReadFrom
+		{"bucket", staticOptions},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			h := padding.NewPadder(c.opts).Handler(http.FileServer(http.Dir(filepath.Dir(path))))
+			w := &readerFromRecorder{ResponseRecorder: httptest.NewRecorder()}
+			req := httptest.NewRequest(http.MethodGet, "/file.txt", nil)
+			h.ServeHTTP(w, req)
+
+			if w.readFrom == 0 {
+				t.Errorf("file body bypassed the underlying ReadFrom")
+			}
+			if w.Code != http.StatusOK || !bytes.HasPrefix(w.Body.Bytes(), content) {
+				t.Fatalf("status %d, body does not start with the file content", w.Code)
+			}
+			if b := c.opts.ContentLengthBucket; b > 0 && w.Body.Len()%b != 0 {
+				t.Errorf("body length %d is not a multiple of ContentLengthBucket (%d)", w.Body.Len(), b)
+			}
+			if len(paddingtest.PaddingHeaders(w.Header(), c.opts)) == 0 {
+				t.Errorf("response has no padding headers")
+			}
+		})
+	}
+}
+
+func TestStaticBucketMediaTypes(t *testing.T) {
+	content := strings.Repeat("a", 1000)
+	for _, c := range []struct {
+		contentType string
+		types       []string
+		wantPadded  bool
+	}{
+		{"text/html; charset=utf-8", nil, true},
+		{"application/json", nil, true},
+		{"application/problem+json", nil, true},
+		// 可能被 Subresource Integrity 引用, 默认保持原样
+		{"text/javascript", nil, false},
+		{"text/css", nil, false},
+		{"text/plain; charset=utf-8", nil, false},
+		{"text/css", []string{"text/css"}, true},
+		{"text/plain", []string{"Text/*"}, true},
+		{"text/html", []string{"text/plain"}, false},
+		// 即使列出, 二进制类型也不能追加空白字符
+		{"image/png", []string{"*/*"}, false},
+	} {
+		opts := padding.PaddingOptions{
+			Profile:                  staticOptions.Profile,
+			ContentLengthBucket:      512,
+			ContentLengthBucketTypes: c.types,
+		}
+		h := padding.NewPadder(opts).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", c.contentType)
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			io.WriteString(w, content)
+		}))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if padded := w.Body.Len() != len(content); padded != c.wantPadded {
+			t.Errorf("%s with types %q: body length %d, want padded %v", c.contentType, c.types, w.Body.Len(), c.wantPadded)
+		}
+		if got := w.Header().Get("Content-Length"); got != strconv.Itoa(w.Body.Len()) {
+			t.Errorf("%s with types %q: Content-Length %s, body length %d", c.contentType, c.types, got, w.Body.Len())
+		}
+	}
+}
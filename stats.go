@@ -0,0 +1,78 @@
+package padding
+
+import (
+	"sync/atomic"
+)
+
+// histogramBounds 是 padding 长度直方图各个桶的上界 (含), 超过最后一个上界的长度计入溢出桶
+var histogramBounds = [...]int{64, 128, 256, 512, 1024, 2048, 4096}
+
+// HistogramBucket 是 padding 长度直方图中的一个桶
+// UpperBound 为 -1 表示溢出桶 (长度大于所有上界)
+type HistogramBucket struct {
+	UpperBound int    `json:"upper_bound"`
+	Count      uint64 `json:"count"`
+}
+
+// Stats 是 Padder 运行时统计信息的快照
+type Stats struct {
+	Padded      uint64            `json:"padded"`       // 添加了 padding 头部的请求/响应数
+	Skipped     uint64            `json:"skipped"`      // 因 SkipPaths、Probability 或停用而跳过的请求数
+	HeaderBytes uint64            `json:"header_bytes"` // padding 头部值的累计字节数
+	BodyBytes   uint64            `json:"body_bytes"`   // 响应体 padding 的累计字节数
+	Histogram   []HistogramBucket `json:"histogram"`    // padding 头部长度的分布
+}
+
+// paddingStats 以原子计数器记录 padding 统计信息, 可被多个请求并发更新
+type paddingStats struct {
+	padded      atomic.Uint64
+	skipped     atomic.Uint64
+	headerBytes atomic.Uint64
+	bodyBytes   atomic.Uint64
+	buckets     [len(histogramBounds) + 1]atomic.Uint64
+}
+
+// recordHeader 记录一次长度为 n 的 padding 头部, n <= 0 时不记录
+func (s *paddingStats) recordHeader(n int) {
+	if n <= 0 {
+		return
+	}
+	s.padded.Add(1)
+	s.headerBytes.Add(uint64(n))
+	i := 0
+	for i < len(histogramBounds) && n > histogramBounds[i] {
+		i++
+	}
+	s.buckets[i].Add(1)
+}
+
+// recordBody 记录一次长度为 n 的响应体 padding
+func (s *paddingStats) recordBody(n int) {
+	if n > 0 {
+		s.bodyBytes.Add(uint64(n))
+	}
+}
+
+// snapshot 返回当前统计信息的快照
+func (s *paddingStats) snapshot() Stats {
+	st := Stats{
+		Padded:      s.padded.Load(),
+		Skipped:     s.skipped.Load(),
+		HeaderBytes: s.headerBytes.Load(),
+		BodyBytes:   s.bodyBytes.Load(),
+		Histogram:   make([]HistogramBucket, len(s.buckets)),
+	}
+	for i := range s.buckets {
+		bound := -1
+		if i < len(histogramBounds) {
+			bound = histogramBounds[i]
+		}
+		st.Histogram[i] = HistogramBucket{UpperBound: bound, Count: s.buckets[i].Load()}
+	}
+	return st
+}
+
+// Stats 返回 p 自创建以来的统计信息快照
+func (p *Padder) Stats() Stats {
+	return p.stats.snapshot()
+}
@@ -0,0 +1,160 @@
+package padding
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// RequestInfo 是 Strategy 做出 padding 决定时可用的信息
+type RequestInfo struct {
+	// Direction 是 padding 所在消息的方向
+	Direction Direction
+	// Request 是出站请求, 或响应对应的入站请求 (反向代理的下游响应可能为 nil)
+	Request *http.Request
+	// StatusCode 是响应状态码, 请求方向为 0
+	StatusCode int
+	// Protocol 是消息所在连接的协议: 响应取自入站请求的协议版本;
+	// 出站请求只有经 ContextWithProtocol 给出提示时才已知, 否则为 ProtocolUnknown
+	Protocol Protocol
+	// ContentLength 是消息体长度, 小于 0 表示未知
+	ContentLength int64
+	// Header 是即将发送的头部 (只读), 可用于按已有头部大小决定长度
+	Header http.Header
+	// Profile 是内置规则 (ProfileByStatus、ProfileByContentType、ProfileByHost、Schedule 等) 为本次消息选出的 Profile
+	Profile *PaddingProfile
+	// Rand 是本次决定应使用的随机数源
+	Rand RandSource
+	// Prior 是 Chain 中前一阶段的决定, 不在 Chain 中或为第一个阶段时为 nil
+	Prior *Decision
+	// Attempt 是出站请求的第几次重试 (仅客户端), 第一次尝试为 0; Retry 为 RetryPin 时重试不再调用 Strategy
+	Attempt int
+	// ConnResponses 是同一个连接上此前已添加 padding 的响应数 (仅服务端, 需要 ConnContext 或 ConnPadding),
+	// 可据此让同一个 keep-alive 连接上的响应保持相关或刻意错开
+	ConnResponses int
+	// PreviousLength 是同一个连接上上一个响应的 padding 头部长度, 仅在 ConnResponses 大于 0 时有意义
+	PreviousLength int
+}
+
+// HeaderPadding 是 Decision 中的一个 padding 头部
+type HeaderPadding struct {
+	// Name 是头部名称, 为空时使用 HeaderName (或 RotateHeader 当前的名称、按权重选出的 HeaderCandidates 之一)
+	Name string
+	// Length 是 padding 值的长度, 超过数据池大小 (4096 字节) 时会被截断
+	Length int
+}
+
+// Decision 是 Strategy 对一条消息做出的 padding 决定
+type Decision struct {
+	// Headers 是要设置的 padding 头部, 为空时不设置
+	Headers []HeaderPadding
+	// Body 是响应体 padding 的长度 (仅服务端), 仅在响应的内容类型已启用 HTMLBodyPadding 或 JSONBodyPadding 时生效,
+	// 代替对应 Profile 的采样结果
+	Body int
+	// Delay 大于 0 时, 在发送该消息 (请求或响应头部) 之前等待该时长; 请求被取消时提前结束
+	Delay time.Duration
+}
+
+// Strategy 决定每条消息的 padding, 用于实现完全自定义的 padding 方案
+// 设置 PaddingOptions.Strategy 后, 它代替 Profile 采样、固定头部大小、诱饵头部与 cookie 载体等内置的头部 padding 逻辑;
+// SkipPaths、Probability、Budget 等跳过与限额规则仍然生效; Decide 可能被并发调用
+type Strategy interface {
+	Decide(info RequestInfo) Decision
+}
+
+// StrategyFunc 将普通函数适配为 Strategy
+type StrategyFunc func(info RequestInfo) Decision
+
+// Decide 调用 f(info)
+func (f StrategyFunc) Decide(info RequestInfo) Decision {
+	return f(info)
+}
+
+// Decide 使 PaddingProfile 成为一个 Strategy: 按 Profile 为默认头部采样一个长度,
+// 已知消息体长度且启用了块长度填充时按块对齐; 随机数生成失败时不添加 padding
+func (p *PaddingProfile) Decide(info RequestInfo) Decision {
+	src := info.Rand
+	if src == nil {
+		src = defaultRandSource
+	}
+	length, err := p.sampleFor(src, int(info.ContentLength))
+	if err != nil || length <= 0 {
+		return Decision{}
+	}
+	return Decision{Headers: []HeaderPadding{{Length: length}}}
+}
+
+// decidePadding 为一条消息设置 padding: 设置了 Strategy 时按其决定写入头部, 否则使用内置逻辑 (setPadding)
+// 返回写入的 padding 总长度与 Strategy 的决定 (未设置 Strategy 时为零值); 仅在随机数生成失败时返回错误
+func decidePadding(h http.Header, info RequestInfo, opts *PaddingOptions) (int, Decision, error) {
+	level, probe := opts.Breaker.admit()
+	switch level {
+	case BreakerOpen:
+		if opts.FailClosed {
+			return 0, Decision{}, ErrBreakerOpen
+		}
+		return 0, Decision{}, nil
+	case BreakerDegraded:
+		info.Profile = opts.Breaker.opts.Fallback
+	}
+	var (
+		n   int
+		d   Decision
+		err error
+	)
+	diagnose(info, "padding.decide", opts, func() { n, d, err = decide(h, info, opts) })
+	opts.Breaker.report(err, probe)
+	return n, d, err
+}
+
+// decide 是 decidePadding 在断路器放行后的实际逻辑
+func decide(h http.Header, info RequestInfo, opts *PaddingOptions) (int, Decision, error) {
+	if opts.Strategy == nil {
+		n, err := setPadding(h, info.Direction, info.ContentLength, info.Profile, opts)
+		return n, Decision{}, err
+	}
+	info.Header = h
+	info.Rand = opts.Rand
+	d := opts.Strategy.Decide(info)
+	defaultName := opts.pickHeaderName(opts.now())
+	total := 0
+	for _, hp := range d.Headers {
+		name := hp.Name
+		if name == "" {
+			name = defaultName
+		}
+		length := opts.Budget.take(capHeaderPadding(h, name, min(hp.Length, maxPaddingSize), opts))
+		if length <= 0 {
+			continue
+		}
+		h.Set(name, paddingValue(length, opts))
+		total += length
+	}
+	return total, d, nil
+}
+
+// sleepContext 等待 d 或直到 ctx 结束, ctx 先结束时返回其错误
+// ctx 带有截止时间时, 等待时长会被 clampDelay 截断, 不会把请求推过截止时间
+func sleepContext(ctx context.Context, d time.Duration, opts *PaddingOptions) error {
+	if d <= 0 {
+		return nil
+	}
+	requested := d
+	d = clampDelay(ctx, d, opts.DeadlineMargin)
+	if dm, ok := opts.Metrics.(DelayMetrics); ok {
+		dm.RecordDelay(requested, d)
+	}
+	if d <= 0 {
+		return nil
+	}
+	return opts.sleep(ctx, d)
+}
+
+// clampDelay 返回不会越过 ctx 截止时间的延迟: ctx 带有截止时间时, 延迟最多持续到截止时间之前 margin 处
+func clampDelay(ctx context.Context, d, margin time.Duration) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return d
+	}
+	return max(min(d, time.Until(deadline)-margin), 0)
+}
@@ -0,0 +1,155 @@
+package padding
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// 帧格式: 1 字节类型 + 2 字节大端长度 + 载荷
+const (
+	frameHeaderSize = 3
+	maxFramePayload = 1<<16 - 1
+
+	frameData    = 0x00 // 载荷是上层数据
+	framePadding = 0x01 // 载荷是 padding, 接收方直接丢弃
+)
+
+// ErrInvalidFrame 表示 PadReader 读到了无法识别的帧
+var ErrInvalidFrame = errors.New("padding: invalid frame")
+
+// PadWriter 将写入的数据封装为长度前缀帧, 并在每次写入后追加一个随机长度的 padding 帧
+// 输出只能由 PadReader 解析, 适用于自定义隧道、文件上传等任意字节流
+type PadWriter struct {
+	w        io.Writer
+	strategy Strategy
+	profile  *PaddingProfile
+	src      RandSource
+	buf      []byte
+}
+
+// NewPadWriter 创建一个 PadWriter, 每个 padding 帧的载荷长度由 strategy 决定, 为 nil 时使用 ProfileDefault
+// *PaddingProfile 本身实现了 Strategy, 可以直接传入; 也可以传入 Chain 等组合出的策略
+// 传给 Decide 的 RequestInfo 中只有 ContentLength (本次写入的字节数, WritePadding 时为 -1)、Profile 与 Rand 有意义,
+// 其中 Profile 为传入的 *PaddingProfile (其他策略时为 ProfileDefault); 载荷长度为 Decision.Headers 中各长度之和,
+// 超过数据池大小 (4096 字节) 时被截断, Body 与 Delay 被忽略
+func NewPadWriter(w io.Writer, strategy Strategy) *PadWriter {
+	profile, _ := strategy.(*PaddingProfile)
+	if profile == nil {
+		profile = &ProfileDefault
+	}
+	profile = normalizeProfile(profile, "padding.PadWriter")
+	if _, ok := strategy.(*PaddingProfile); ok || strategy == nil {
+		strategy = profile
+	}
+	return &PadWriter{w: w, strategy: strategy, profile: profile, src: defaultRandSource}
+}
+
+// paddingLength 以本次写入的字节数 n (小于 0 表示只写出 padding) 调用 Strategy, 返回 padding 帧的载荷长度
+func (pw *PadWriter) paddingLength(n int) int {
+	d := pw.strategy.Decide(RequestInfo{ContentLength: int64(n), Profile: pw.profile, Rand: pw.src})
+	length := 0
+	for _, h := range d.Headers {
+		length += max(h.Length, 0)
+	}
+	return min(length, maxPaddingSize)
+}
+
+// Write 将 p 作为一个或多个数据帧写出, 随后写出一个 padding 帧, 所有帧通过一次底层写入发送
+// 返回值 n 为 p 中已被写出的字节数
+func (pw *PadWriter) Write(p []byte) (int, error) {
+	length := pw.paddingLength(len(p))
+	pw.buf = pw.buf[:0]
+	for rest := p; len(rest) > 0; {
+		chunk := rest[:min(len(rest), maxFramePayload)]
+		pw.buf = appendFrame(pw.buf, frameData, chunk)
+		rest = rest[len(chunk):]
+	}
+	if length > 0 {
+		pw.buf = appendFrame(pw.buf, framePadding, getPaddingSlice(pw.src, length))
+	}
+	if _, err := pw.w.Write(pw.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WritePadding 只写出一个随机长度的 padding 帧, 可用于在空闲时发送掩护流量
+func (pw *PadWriter) WritePadding() error {
+	length := pw.paddingLength(-1)
+	pw.buf = appendFrame(pw.buf[:0], framePadding, getPaddingSlice(pw.src, length))
+	_, err := pw.w.Write(pw.buf)
+	return err
+}
+
+// appendFrame 将一个帧追加到 buf, payload 长度不能超过 maxFramePayload
+func appendFrame(buf []byte, typ byte, payload []byte) []byte {
+	buf = append(buf, typ)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(payload)))
+	return append(buf, payload...)
+}
+
+// PadReader 解析 PadWriter 产生的帧流, 丢弃 padding 帧并返回原始数据
+type PadReader struct {
+	r         io.Reader
+	remaining int // 当前数据帧中尚未读取的字节数
+	discard   []byte
+}
+
+// NewPadReader 创建一个从 r 读取帧流的 PadReader
+func NewPadReader(r io.Reader) *PadReader {
+	return &PadReader{r: r}
+}
+
+// Read 读取原始数据, 帧流在帧边界处结束时返回 io.EOF, 在帧中间结束时返回 io.ErrUnexpectedEOF
+func (pr *PadReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for pr.remaining == 0 {
+		typ, length, err := pr.readHeader()
+		if err != nil {
+			return 0, err
+		}
+		switch typ {
+		case frameData:
+			pr.remaining = length
+		case framePadding:
+			if err := pr.skip(length); err != nil {
+				return 0, err
+			}
+		default:
+			return 0, fmt.Errorf("%w: unknown frame type %#x", ErrInvalidFrame, typ)
+		}
+	}
+	n, err := pr.r.Read(p[:min(len(p), pr.remaining)])
+	pr.remaining -= n
+	if err == io.EOF && pr.remaining > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// readHeader 读取下一个帧头
+func (pr *PadReader) readHeader() (byte, int, error) {
+	var hdr [frameHeaderSize]byte
+	if _, err := io.ReadFull(pr.r, hdr[:]); err != nil {
+		return 0, 0, err
+	}
+	return hdr[0], int(binary.BigEndian.Uint16(hdr[1:])), nil
+}
+
+// skip 读取并丢弃 n 字节的 padding 载荷
+func (pr *PadReader) skip(n int) error {
+	if cap(pr.discard) < n {
+		pr.discard = make([]byte, n)
+	}
+	if _, err := io.ReadFull(pr.r, pr.discard[:n]); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package padding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// paddingFrames 返回 PadWriter 输出 b 中各 padding 帧的载荷长度
+func paddingFrames(t *testing.T, b []byte) []int {
+	t.Helper()
+	var lengths []int
+	for len(b) > 0 {
+		if len(b) < frameHeaderSize {
+			t.Fatalf("truncated frame header")
+		}
+		typ, n := b[0], int(binary.BigEndian.Uint16(b[1:frameHeaderSize]))
+		if len(b) < frameHeaderSize+n {
+			t.Fatalf("truncated frame payload")
+		}
+		if typ == framePadding {
+			lengths = append(lengths, n)
+		}
+		b = b[frameHeaderSize+n:]
+	}
+	return lengths
+}
+
+func TestPadWriterStrategy(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		strategy Strategy
+		check    func(n int) bool
+	}{
+		{"nil", nil, func(n int) bool { return n >= ProfileDefault.MinLength && n <= ProfileDefault.MaxLength }},
+		{"profile", &PaddingProfile{MinLength: 100, MaxLength: 200}, func(n int) bool { return n >= 100 && n <= 200 }},
+		{"nil profile", (*PaddingProfile)(nil), func(n int) bool { return n >= ProfileDefault.MinLength && n <= ProfileDefault.MaxLength }},
+		{"chain", Chain(SampleProfile, RoundTo(64)), func(n int) bool { return n > 0 && n%64 == 0 }},
+		{"func", StrategyFunc(func(info RequestInfo) Decision {
+			return Decision{Headers: []HeaderPadding{{Length: 10}, {Length: 20}}}
+		}), func(n int) bool { return n == 30 }},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var out bytes.Buffer
+			pw := NewPadWriter(&out, c.strategy)
+			want := []byte("hello, padded stream")
+			for range 20 {
+				if _, err := pw.Write(want); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := pw.WritePadding(); err != nil {
+				t.Fatal(err)
+			}
+			for _, n := range paddingFrames(t, out.Bytes()) {
+				if !c.check(n) {
+					t.Fatalf("padding frame of %d bytes", n)
+				}
+			}
+			got, err := io.ReadAll(NewPadReader(&out))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, bytes.Repeat(want, 20)) {
+				t.Fatalf("PadReader returned %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package padding
+
+import (
+	"bytes"
+	"strings"
+)
+
+// StreamFiller 生成在流式响应中间插入的 padding
+// last 是处理函数最近一次写入的数据, n 是期望的 filler 长度; 当前位置不适合插入时返回 nil, 等待下一次写入后再尝试
+type StreamFiller func(last []byte, n int, src RandSource) []byte
+
+// DefaultStreamFillers 是内置的按媒体类型选择的 StreamFiller
+//   - text/event-stream: 在行边界插入 SSE 注释行 (": <padding>"), 客户端会忽略它
+//   - text/html: 在以 '>' 结尾的写入之后插入 HTML 注释, 适用于按完整元素流式输出的页面
+var DefaultStreamFillers = map[string]StreamFiller{
+	"text/event-stream": sseCommentFiller,
+	"text/html":         htmlStreamFiller,
+}
+
+// sseCommentFiller 在行边界生成恰好 n 字节的 SSE 注释行
+func sseCommentFiller(last []byte, n int, src RandSource) []byte {
+	if len(last) == 0 || last[len(last)-1] != '\n' || n < 4 {
+		return nil
+	}
+	buf := make([]byte, 0, n)
+	buf = append(buf, ':', ' ')
+	buf = append(buf, getPaddingSlice(src, n-3)...)
+	return append(buf, '\n')
+}
+
+// htmlStreamFiller 在标签结束之后生成恰好 n 字节的 HTML 注释
+func htmlStreamFiller(last []byte, n int, src RandSource) []byte {
+	if !bytes.HasSuffix(bytes.TrimRight(last, " \t\r\n"), []byte(">")) {
+		return nil
+	}
+	return htmlCommentFiller(src, n)
+}
+
+// StreamPaddingOptions 配置流式响应中间插入的响应体 padding
+// 与只在末尾追加的 HTMLBodyPadding 不同, filler 穿插在处理函数的写入之间, 使传输过程中的流量大小同样被混淆
+type StreamPaddingOptions struct {
+	// MinGap 与 MaxGap 决定两次插入之间处理函数至少写出的字节数, 每次插入后在 [MinGap, MaxGap] 内重新随机选取;
+	// 小于等于 0 时分别为 4 KiB 与 16 KiB
+	MinGap int
+	MaxGap int
+	// Profile 决定每次插入的 filler 长度, 为 nil 时使用 ProfileShort
+	Profile *PaddingProfile
+	// Fillers 按媒体类型 (小写, 不含参数) 覆盖或扩充 DefaultStreamFillers, 值为 nil 时关闭对应类型
+	Fillers map[string]StreamFiller `json:"-"`
+}
+
+// normalizeStreamPadding 返回补全默认值后的 StreamPaddingOptions 副本
+func normalizeStreamPadding(s StreamPaddingOptions, logPrefix string) *StreamPaddingOptions {
+	if s.MinGap <= 0 {
+		s.MinGap = 4 << 10
+	}
+	if s.MaxGap <= 0 {
+		s.MaxGap = 16 << 10
+	}
+	s.MinGap = min(s.MinGap, s.MaxGap)
+	if s.Profile == nil {
+		s.Profile = &ProfileShort
+	}
+	s.Profile = normalizeProfile(s.Profile, logPrefix)
+	fillers := make(map[string]StreamFiller, len(DefaultStreamFillers)+len(s.Fillers))
+	for mt, f := range DefaultStreamFillers {
+		fillers[mt] = f
+	}
+	for mt, f := range s.Fillers {
+		mt = strings.ToLower(strings.TrimSpace(mt))
+		if f == nil {
+			delete(fillers, mt)
+		} else {
+			fillers[mt] = f
+		}
+	}
+	s.Fillers = fillers
+	return &s
+}
+
+// streamPadder 是一个响应的流式 padding 状态
+type streamPadder struct {
+	opts   *StreamPaddingOptions
+	filler StreamFiller
+	gap    int // 距离下一次插入还需写出的字节数
+}
+
+// startStreamPadding 在 WriteHeader 中调用, 响应的媒体类型有对应的 StreamFiller 时启用流式 padding
+// filler 会使 Content-Length 失效, 因此启用时将其移除
+func (prw *paddingResponseWriter) startStreamPadding(statusCode int) {
+	sp := prw.opts.StreamPadding
+	if sp == nil || !bodyAllowed(prw.req.Method, statusCode) || partialContent(statusCode) || encoded(prw.Header()) {
+		return
+	}
+	filler, ok := sp.Fillers[mediaType(prw.Header())]
+	if !ok {
+		return
+	}
+	prw.stream = &streamPadder{opts: sp, filler: filler}
+	prw.stream.gap = prw.stream.nextGap(prw.opts.Rand)
+	prw.Header().Del("Content-Length")
+}
+
+// nextGap 随机选取下一次插入前需要写出的字节数
+func (s *streamPadder) nextGap(src RandSource) int {
+	gap, err := randInt(src, s.opts.MinGap, s.opts.MaxGap)
+	if err != nil {
+		return s.opts.MaxGap
+	}
+	return gap
+}
+
+// writeStreamFiller 在处理函数写出 data 之后调用, 累计写出量达到间隔且当前位置适合插入时写出一段 filler,
+// 调用方需持有 writeMu
+func (prw *paddingResponseWriter) writeStreamFiller(data []byte) error {
+	s := prw.stream
+	s.gap -= len(data)
+	if s.gap > 0 {
+		return nil
+	}
+	length, err := s.opts.Profile.sample(prw.opts.Rand)
+	if err != nil || length <= 0 {
+		return nil
+	}
+	filler := s.filler(data, length, prw.opts.Rand)
+	if filler == nil {
+		return nil
+	}
+	if granted := prw.opts.Budget.take(len(filler)); granted < len(filler) {
+		// 额度不足, 按获得的额度重新生成较短的 filler
+		if filler = s.filler(data, granted, prw.opts.Rand); filler == nil {
+			return nil
+		}
+	}
+	s.gap = s.nextGap(prw.opts.Rand)
+	prw.stats.recordBody(len(filler))
+	if prw.opts.Metrics != nil {
+		prw.opts.Metrics.RecordBodyPadding(len(filler))
+	}
+	_, err = prw.writeBody(filler)
+	return err
+}
@@ -0,0 +1,33 @@
+package padding
+
+import (
+	"net/http"
+
+	"github.com/WJQSERVER-STUDIO/httpc"
+	"github.com/infinite-iroha/touka"
+)
+
+// StripPaddingS 返回一个服务端中间件, 在交给后续处理函数之前移除入站请求中的 padding 头部
+// opts 应与对端 ToukaPadding 使用的配置一致; 启用 RotateHeader 时可以识别所有当前有效的名称
+func StripPaddingS(opts PaddingOptions) touka.HandlerFunc {
+	opts = normalizeOptions(opts, "toukaPadding.Strip")
+	return func(c *touka.Context) {
+		stripPaddingHeaders(c.Request.Header, &opts)
+		c.Next()
+	}
+}
+
+// StripPadding 返回一个 httpc 客户端中间件, 在将响应交给调用方之前移除其中的 padding 头部
+// opts 应与对端 ToukaPaddingS 使用的配置一致; 启用 RotateHeader 时可以识别所有当前有效的名称
+func StripPadding(opts PaddingOptions) httpc.MiddlewareFunc {
+	opts = normalizeOptions(opts, "httpc.StripPadding")
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpc.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if resp != nil && resp.Header != nil {
+				stripPaddingHeaders(resp.Header, &opts)
+			}
+			return resp, err
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package padding
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/infinite-iroha/touka"
+)
+
+// Tenants 在一个服务端中间件实例内为多个租户 (或虚拟主机) 维护各自的 padding 配置
+// 每个租户对应一个独立的 Padder, 拥有自己的头部名称、Profile、启停状态与统计信息
+type Tenants struct {
+	resolver func(c *touka.Context) string
+
+	mu      sync.RWMutex
+	padders map[string]*Padder
+}
+
+// NewTenants 创建多租户配置
+// resolver 从请求中解析租户名, 可以使用 TenantByHost; opts 的键为租户名, 键 "*" 的配置用于未知租户,
+// 未配置 "*" 时未知租户的请求不添加 padding
+func NewTenants(resolver func(c *touka.Context) string, opts map[string]PaddingOptions) *Tenants {
+	t := &Tenants{resolver: resolver, padders: make(map[string]*Padder, len(opts))}
+	for name, o := range opts {
+		t.padders[name] = newPadder(o, "padding.Tenants["+name+"]")
+	}
+	return t
+}
+
+// TenantByHost 是按请求的 Host (小写, 不含端口) 区分租户的解析函数
+func TenantByHost(c *touka.Context) string {
+	host := c.Request.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// Reload 原子地替换所有租户的配置
+// 已存在的租户原地 Reload, 保留其启停状态与统计信息; 新租户会被创建, 不再出现的租户会被移除
+func (t *Tenants) Reload(opts map[string]PaddingOptions) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	padders := make(map[string]*Padder, len(opts))
+	for name, o := range opts {
+		if p, ok := t.padders[name]; ok {
+			p.Reload(o)
+			padders[name] = p
+		} else {
+			padders[name] = newPadder(o, "padding.Tenants["+name+"]")
+		}
+	}
+	t.padders = padders
+}
+
+// Padder 返回租户 name 的 Padder, 可用于单独启停、Reload 或读取统计信息
+func (t *Tenants) Padder(name string) (*Padder, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	p, ok := t.padders[name]
+	return p, ok
+}
+
+// Names 返回所有已配置的租户名 (顺序不定)
+func (t *Tenants) Names() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	names := make([]string, 0, len(t.padders))
+	for name := range t.padders {
+		names = append(names, name)
+	}
+	return names
+}
+
+// lookup 返回请求所属租户的 Padder, 未知租户时退回到 "*"
+func (t *Tenants) lookup(c *touka.Context) *Padder {
+	name := t.resolver(c)
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if p, ok := t.padders[name]; ok {
+		return p
+	}
+	return t.padders["*"]
+}
+
+// Server 返回按租户选择配置的服务端中间件
+func (t *Tenants) Server() touka.HandlerFunc {
+	return func(c *touka.Context) {
+		p := t.lookup(c)
+		if p == nil {
+			c.Next()
+			return
+		}
+		p.serve(c)
+	}
+}
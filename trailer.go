@@ -0,0 +1,62 @@
+package padding
+
+import (
+	"net/http"
+)
+
+// TrailerOptions 配置在 HTTP Trailer 中发送的 padding
+// 流式响应开始写入后头部已无法修改, Trailer 则可以在响应体结束后根据最终大小决定 padding 长度
+// 仅对分块传输 (HTTP/1.1 chunked) 与 HTTP/2 响应生效, 设置了 Content-Length 的响应会丢弃 Trailer
+type TrailerOptions struct {
+	// Name 是承载 padding 的 Trailer 名称, 默认为 "T-Padding-Trailer"
+	Name string
+	// Profile 决定 padding 长度, 仅在 BucketSize 为 0 时使用; 为 nil 时使用 ProfileShort
+	Profile *PaddingProfile
+	// BucketSize 大于 0 时, padding 长度会使 (响应体 + Trailer) 的大小向上对齐到该值的整数倍
+	BucketSize int
+}
+
+// normalizeTrailer 返回补全默认值后的 TrailerOptions 副本
+func normalizeTrailer(t TrailerOptions, logPrefix string) *TrailerOptions {
+	if t.Name == "" {
+		t.Name = "T-Padding-Trailer"
+	}
+	t.Name = http.CanonicalHeaderKey(t.Name)
+	if t.Profile == nil {
+		t.Profile = &ProfileShort
+	}
+	t.Profile = normalizeProfile(t.Profile, logPrefix)
+	return &t
+}
+
+// declareTrailer 在 WriteHeader 中调用, 通过 Trailer 头部预先声明 padding Trailer
+func (prw *paddingResponseWriter) declareTrailer(statusCode int) {
+	if prw.opts.Trailer == nil || !bodyAllowed(prw.req.Method, statusCode) {
+		return
+	}
+	prw.Header().Add("Trailer", prw.opts.Trailer.Name)
+	prw.trailerDeclared = true
+}
+
+// writeTrailer 在响应体写完后根据已写入的字节数设置 padding Trailer
+func (prw *paddingResponseWriter) writeTrailer() error {
+	if !prw.trailerDeclared {
+		return nil
+	}
+	t := prw.opts.Trailer
+	var length int
+	if t.BucketSize > 0 {
+		// Trailer 行自身的开销: 名称、": " 与 "\r\n"
+		used := int(prw.written) + len(t.Name) + 4
+		length = min(roundUp(used, t.BucketSize)-used, maxPaddingSize)
+	} else {
+		var err error
+		if length, err = t.Profile.sample(prw.opts.Rand); err != nil {
+			return err
+		}
+	}
+	if length > 0 {
+		prw.Header().Set(t.Name, paddingString(prw.opts.Rand, length, prw.opts.Pool, prw.opts.ValueCache))
+	}
+	return nil
+}
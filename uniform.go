@@ -0,0 +1,117 @@
+package padding
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// UniformErrorOptions 配置 UniformErrors
+type UniformErrorOptions struct {
+	// Window 是作为参考分布的最近成功响应 (2xx) 数, 小于等于 0 时为 256
+	Window int
+	// MinSamples 是开始补齐错误响应前至少需要的成功响应样本数, 小于等于 0 时为 16
+	MinSamples int
+	// MaxFill 是单个错误响应最多补充的字节数, 小于等于 0 时为 64 KiB
+	MaxFill int
+}
+
+// UniformErrors 记录最近成功响应的总大小 (头部 + 响应体, 含 padding), 并将错误响应 (4xx/5xx)
+// 的响应体补齐到从中随机抽取的总大小, 使攻击者无法仅凭响应大小区分存在与不存在的路径
+// 通过 PaddingOptions.UniformErrors 安装 (仅服务端), 可在多个实例之间共享
+// 补充的内容是空白字符, 只作用于 text/* 与 JSON 类型且未经内容编码的错误响应; 目标总大小小于错误响应本身时不做处理
+type UniformErrors struct {
+	opts UniformErrorOptions
+
+	mu    sync.Mutex
+	sizes []int64 // 环形缓冲区
+	next  int
+	full  bool
+}
+
+// NewUniformErrors 创建一个 UniformErrors
+func NewUniformErrors(opts UniformErrorOptions) *UniformErrors {
+	if opts.Window <= 0 {
+		opts.Window = 256
+	}
+	if opts.MinSamples <= 0 {
+		opts.MinSamples = 16
+	}
+	opts.MinSamples = min(opts.MinSamples, opts.Window)
+	if opts.MaxFill <= 0 {
+		opts.MaxFill = 64 << 10
+	}
+	return &UniformErrors{opts: opts, sizes: make([]int64, opts.Window)}
+}
+
+// record 记录一次成功响应的总大小; u 为 nil 时不做任何事
+func (u *UniformErrors) record(size int64) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	u.sizes[u.next] = size
+	u.next++
+	if u.next == len(u.sizes) {
+		u.next, u.full = 0, true
+	}
+	u.mu.Unlock()
+}
+
+// target 从最近的成功响应中随机抽取一个总大小, 样本不足或随机数生成失败时返回 false
+func (u *UniformErrors) target(src RandSource) (int64, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	n := u.next
+	if u.full {
+		n = len(u.sizes)
+	}
+	if n < u.opts.MinSamples {
+		return 0, false
+	}
+	i, err := randInt(src, 0, n-1)
+	if err != nil {
+		return 0, false
+	}
+	return u.sizes[i], true
+}
+
+// uniformFillable 报告媒体类型为 mt 的响应体能否在末尾追加空白字符而不改变其含义
+func uniformFillable(mt string) bool {
+	return strings.HasPrefix(mt, "text/") || isJSONMediaType(mt)
+}
+
+// prepareUniformError 在 WriteHeader 中调用, 为错误响应抽取目标总大小
+// 补充的长度要到响应结束时才能确定, 因此会移除 Content-Length
+func (prw *paddingResponseWriter) prepareUniformError(statusCode int) {
+	u := prw.opts.UniformErrors
+	if u == nil || statusCode < http.StatusBadRequest || !bodyAllowed(prw.req.Method, statusCode) ||
+		encoded(prw.Header()) || !uniformFillable(mediaType(prw.Header())) {
+		return
+	}
+	if target, ok := u.target(prw.opts.Rand); ok {
+		prw.fillTarget = target
+		prw.fillMax = u.opts.MaxFill
+		prw.Header().Del("Content-Length")
+	}
+}
+
+// writeFill 在响应结束时以空白字符将响应体补齐到 fillTarget 指定的总大小, 调用方需持有 writeMu
+func (prw *paddingResponseWriter) writeFill() {
+	if prw.fillTarget <= 0 {
+		return
+	}
+	fill := prw.fillTarget - int64(headerSize(prw.Header(), "")) - prw.written
+	fill = min(fill, int64(prw.fillMax))
+	if fill <= 0 {
+		return
+	}
+	prw.stats.recordBody(int(fill))
+	if prw.opts.Metrics != nil {
+		prw.opts.Metrics.RecordBodyPadding(int(fill))
+	}
+	if _, err := prw.writeBody(whitespaceFiller(int(fill))); err != nil {
+		log.Printf("toukaPadding: failed to write body fill: %v", err)
+	}
+}
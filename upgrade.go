@@ -0,0 +1,52 @@
+package padding
+
+import (
+	"net/http"
+	"strings"
+)
+
+// isUpgradeRequest 报告请求是否为 Upgrade 握手 (如 WebSocket), 即 Connection 头部含 "upgrade" 且设置了 Upgrade 头部
+func isUpgradeRequest(req *http.Request) bool {
+	if req.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, v := range req.Header["Connection"] {
+		for token := range strings.SplitSeq(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handshakeHeader 报告 name 是否为 Upgrade 握手依赖的头部, padding 不得新增或修改这些头部
+func handshakeHeader(name string) bool {
+	return strings.EqualFold(name, "Connection") ||
+		strings.EqualFold(name, "Upgrade") ||
+		len(name) >= len("Sec-WebSocket-") && strings.EqualFold(name[:len("Sec-WebSocket-")], "Sec-WebSocket-")
+}
+
+// saveHandshakeHeaders 返回 h 中握手头部的副本
+func saveHandshakeHeaders(h http.Header) http.Header {
+	saved := make(http.Header)
+	for name, values := range h {
+		if handshakeHeader(name) {
+			saved[name] = append([]string(nil), values...)
+		}
+	}
+	return saved
+}
+
+// restoreHandshakeHeaders 将 h 中的握手头部恢复为 saved 中的状态
+// 只在配置的 padding 头部名称与握手头部冲突 (如 HeaderNames 含 "Sec-WebSocket-Extensions") 时才有实际作用
+func restoreHandshakeHeaders(h, saved http.Header) {
+	for name := range h {
+		if _, ok := saved[name]; !ok && handshakeHeader(name) {
+			delete(h, name)
+		}
+	}
+	for name, values := range saved {
+		h[name] = values
+	}
+}
@@ -0,0 +1,107 @@
+package padding
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/infinite-iroha/touka"
+)
+
+// inboundKey 是服务端中间件在 Context 中记录入站请求 padding 长度 (int) 所用的键
+const inboundKey = "padding.inbound"
+
+// upstreamContextKey 是 UpstreamContext 在 context 中记录入站 padding 长度所用的键
+type upstreamContextKey struct{}
+
+// UpstreamOptions 配置网关转发上游请求时, 出站请求的 padding 与入站请求 padding 的关系
+type UpstreamOptions struct {
+	// Correlate 为 true 时, 出站请求使用与入站请求相同的 padding 长度, 经过网关前后的请求保持同样的大小特征;
+	// 否则出站长度按 Profile 独立采样, 并保证与入站长度至少相差 MinDelta, 观察者无法按大小将两段请求对应起来
+	Correlate bool
+	// MinDelta 是去相关时出站与入站 padding 长度的最小差值, 小于等于 0 时为 32
+	MinDelta int
+}
+
+// normalizeUpstream 返回补全默认值后的 UpstreamOptions 副本
+func normalizeUpstream(u UpstreamOptions) *UpstreamOptions {
+	if u.MinDelta <= 0 {
+		u.MinDelta = 32
+	}
+	return &u
+}
+
+// recordInbound 在 Context 中记录入站请求携带的 padding 头部的总长度
+func recordInbound(c *touka.Context, opts *PaddingOptions) {
+	n := 0
+	for _, name := range opts.headerNames(opts.now()) {
+		n += len(c.Request.Header.Get(name))
+	}
+	c.Set(inboundKey, n)
+}
+
+// UpstreamContext 返回 c.Request.Context() 的一个派生, 其中携带服务端中间件记录的入站请求 padding 长度
+// 网关使用自己的客户端转发请求时, 以它作为出站请求的 context; 客户端中间件设置了 Upstream 时据此决定出站的 padding
+// 入站请求未经过服务端中间件时返回原 context, 出站请求照常独立采样
+func UpstreamContext(c *touka.Context) context.Context {
+	ctx := c.Request.Context()
+	v, ok := c.Get(inboundKey)
+	if !ok {
+		return ctx
+	}
+	n, _ := v.(int)
+	return context.WithValue(ctx, upstreamContextKey{}, n)
+}
+
+// upstreamProfile 按 Upstream 的设置与 context 中的入站 padding 长度调整出站请求的 Profile
+// context 中没有入站长度时返回 base
+func upstreamProfile(req *http.Request, base *PaddingProfile, opts *PaddingOptions) *PaddingProfile {
+	inbound, ok := req.Context().Value(upstreamContextKey{}).(int)
+	if !ok || opts.Upstream == nil {
+		return base
+	}
+	if opts.Upstream.Correlate {
+		n := min(inbound, maxPaddingSize)
+		return &PaddingProfile{MinLength: n, MaxLength: n}
+	}
+	return apartProfile(base, inbound, opts.Upstream.MinDelta, opts)
+}
+
+// apartProfile 返回一个长度固定的 Profile, 长度按 base 采样, 并保证与 ref 至少相差 delta
+// 采样失败时返回 base
+func apartProfile(base *PaddingProfile, ref, delta int, opts *PaddingOptions) *PaddingProfile {
+	n, err := base.sample(opts.Rand)
+	if err != nil {
+		return base
+	}
+	if n-ref >= delta || ref-n >= delta {
+		return &PaddingProfile{MinLength: n, MaxLength: n}
+	}
+	// 采样值离参照长度太近, 改为在 Profile 范围内距离参照长度至少 delta 的区间中均匀选取
+	lo, hi := base.MinLength, base.MaxLength
+	if hi <= 0 {
+		// 混合 Profile 没有整体的范围
+		lo, hi = 0, maxPaddingSize
+	}
+	below := max(min(hi, ref-delta)-lo+1, 0) // [lo, ref-delta] 中的取值个数
+	aboveLo := max(lo, ref+delta)
+	above := max(min(hi, maxPaddingSize)-aboveLo+1, 0) // [ref+delta, hi] 中的取值个数
+	if below+above == 0 {
+		// Profile 的范围内没有足够远的长度, 退到范围之外最近的一侧
+		if ref+delta <= maxPaddingSize {
+			n = ref + delta
+		} else {
+			n = max(ref-delta, 0)
+		}
+		return &PaddingProfile{MinLength: n, MaxLength: n}
+	}
+	i, err := randInt(opts.Rand, 0, below+above-1)
+	if err != nil {
+		return base
+	}
+	if i < below {
+		n = lo + i
+	} else {
+		n = aboveLo + i - below
+	}
+	return &PaddingProfile{MinLength: n, MaxLength: n}
+}
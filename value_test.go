@@ -0,0 +1,23 @@
+package padding
+
+import "testing"
+
+func TestValueNormalizesProfile(t *testing.T) {
+	for _, c := range []struct {
+		profile  *PaddingProfile
+		min, max int
+	}{
+		{nil, ProfileDefault.MinLength, ProfileDefault.MaxLength},
+		{&PaddingProfile{MinLength: 300, MaxLength: 100}, 100, 100},
+		{&PaddingProfile{MinLength: 5000, MaxLength: 9000}, maxPaddingSize, maxPaddingSize},
+		{&PaddingProfile{MinLength: -10, MaxLength: 10}, 0, 10},
+	} {
+		v, err := Value(c.profile)
+		if err != nil {
+			t.Fatalf("Value(%+v): %v", c.profile, err)
+		}
+		if len(v) < c.min || len(v) > c.max {
+			t.Errorf("Value(%+v) returned %d bytes, want [%d, %d]", c.profile, len(v), c.min, c.max)
+		}
+	}
+}
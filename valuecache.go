@@ -0,0 +1,102 @@
+package padding
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ValueCacheStats 是 ValueCache 计数器的快照
+type ValueCacheStats struct {
+	Hits    uint64 `json:"hits"`    // 命中缓存的次数
+	Misses  uint64 `json:"misses"`  // 未命中 (新转换或超出容量) 的次数
+	Entries int    `json:"entries"` // 已缓存的值的个数
+	Bytes   int    `json:"bytes"`   // 已缓存的值的总字节数
+}
+
+// ValueCache 缓存按 (长度, 数据池偏移) 生成的 padding 字符串, 使 Header().Set 复用已有的字符串,
+// 而不是每个请求都将最长 4KB 的切片转换为新字符串; 长度范围较小的 Profile 收益最明显
+// 达到容量上限后不再加入新值; 通过 PaddingOptions.ValueCache 安装, 可在使用同一个 Pool 的多个实例之间共享
+// 缓存只保存当前数据池数据的值, 数据池重新生成或换用另一个 Pool 时清空, 因此使用不同 Pool 的实例应各用一个 ValueCache
+type ValueCache struct {
+	maxBytes int
+	offsets  int
+
+	mu      sync.RWMutex
+	data    *poolData      // entries 所属的数据池数据
+	entries map[int]string // 键为 start*(maxPaddingSize+1) + length
+	bytes   int
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// NewValueCache 创建一个最多缓存 maxBytes 字节字符串的 ValueCache, maxBytes 小于等于 0 时为 4 MiB
+// offsets 大于 0 时, 每个长度只从 offsets 个均匀分布的数据池偏移中随机选取, 使缓存能够覆盖全部取值;
+// 这是以 padding 值的多样性换取命中率的权衡, 值过少时相同的值更容易在 HPACK/QPACK 动态表中被索引, 建议不小于 64
+// offsets 小于等于 0 时偏移的选取方式不变, 只有长度范围很小且 maxBytes 足够大时才能命中
+func NewValueCache(maxBytes, offsets int) *ValueCache {
+	if maxBytes <= 0 {
+		maxBytes = 4 << 20
+	}
+	return &ValueCache{maxBytes: maxBytes, offsets: max(offsets, 0), entries: make(map[int]string)}
+}
+
+// Stats 返回缓存计数器的快照
+func (c *ValueCache) Stats() ValueCacheStats {
+	c.mu.RLock()
+	entries, bytes := len(c.entries), c.bytes
+	c.mu.RUnlock()
+	return ValueCacheStats{Hits: c.hits.Load(), Misses: c.misses.Load(), Entries: entries, Bytes: bytes}
+}
+
+// value 返回数据池数据 d 的 [start, start+length) 对应的字符串
+func (c *ValueCache) value(d *poolData, start, length int) string {
+	key := start*(maxPaddingSize+1) + length
+	c.mu.RLock()
+	v, ok := c.entries[key]
+	ok = ok && c.data == d
+	c.mu.RUnlock()
+	if ok {
+		c.hits.Add(1)
+		return v
+	}
+	c.misses.Add(1)
+	v = string(d.text(start, length))
+	c.mu.Lock()
+	if c.data != d {
+		clear(c.entries)
+		c.data, c.bytes = d, 0
+	}
+	if _, ok := c.entries[key]; !ok && c.bytes+length <= c.maxBytes {
+		c.entries[key] = v
+		c.bytes += length
+	}
+	c.mu.Unlock()
+	return v
+}
+
+// paddingString 从数据池 pool (nil 时为默认数据池) 中取一个长度为 length 的随机值, 设置了 cache 时复用缓存的字符串
+func paddingString(src RandSource, length int, pool *Pool, cache *ValueCache) string {
+	d := pool.load()
+	if cache == nil {
+		return string(d.slice(src, length))
+	}
+	if length <= 0 {
+		return ""
+	}
+	length = min(length, maxPaddingSize)
+	return cache.value(d, cache.offset(d, src, length), length)
+}
+
+// offset 为长度为 length 的值选取数据池偏移, 设置了 offsets 时只在均匀分布的 offsets 个偏移中选取
+func (c *ValueCache) offset(d *poolData, src RandSource, length int) int {
+	span := len(d.b) - length + 1 // 可选的偏移个数
+	if c.offsets == 0 || c.offsets >= span {
+		return d.offset(src, length)
+	}
+	i, err := randInt(src, 0, c.offsets-1)
+	if err != nil {
+		return 0
+	}
+	return i * (span / c.offsets)
+}
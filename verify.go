@@ -0,0 +1,132 @@
+package padding
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/infinite-iroha/touka"
+)
+
+// padMACSize 是附加在 padding 值末尾的 HMAC 的长度 (SHA-256 的无填充 base64url 编码)
+const padMACSize = 43
+
+// VerifiedKey 是 VerifyPaddingS 在 Context 中记录校验结果 (bool) 所用的键
+const VerifiedKey = "padding.verified"
+
+// padMAC 计算 padding 值前缀 body 在给定请求上的 HMAC
+// 方法与路径参与计算, 使 padding 值不能被挪用到其它请求上
+func padMAC(key []byte, req *http.Request, body string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(req.Method))
+	mac.Write([]byte{'\n'})
+	if req.URL != nil {
+		mac.Write([]byte(req.URL.EscapedPath()))
+	}
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signPaddingHeaders 用 AuthKey 为出站请求中的 padding 头部签名: 值的末尾 43 个字符替换为
+// 对其余部分、请求方法与路径计算的 HMAC, 不足 43 个字符的值会被加长
+// SelfDescribing 时改为以签名段结尾的自描述值, 长度不变
+// ReplayWindow 大于 0 时, 签名的内容以一个带时间戳的 nonce 开头 (自描述值中为 nonce 段)
+func signPaddingHeaders(req *http.Request, opts *PaddingOptions) {
+	if len(opts.AuthKey) == 0 {
+		return
+	}
+	now := opts.now()
+	for _, name := range opts.headerNames(now) {
+		for key, values := range req.Header {
+			if len(values) == 0 || http.CanonicalHeaderKey(key) != http.CanonicalHeaderKey(name) {
+				continue
+			}
+			if opts.SelfDescribing && IsPaddingValue(values[0]) {
+				values[0] = signDescribedValue(req, values[0], now, opts)
+				continue
+			}
+			body := values[0][:max(len(values[0])-padMACSize, 0)]
+			if opts.ReplayWindow > 0 {
+				body = newNonce(now, opts) + body[min(len(body), nonceSize):]
+			}
+			values[0] = body + padMAC(opts.AuthKey, req, body)
+		}
+	}
+}
+
+// verifyPaddingHeaders 报告请求是否携带了合法的 padding 头部
+// 未设置 AuthKey 时只要求任一可识别的 padding 头部存在且非空, 否则还要求其 HMAC 正确
+// SelfDescribing 时不论头部名称, 任一自描述的 padding 值 (设置了 AuthKey 时须带有正确的签名段) 都被接受
+// replay 不为 nil 时, HMAC 正确的值还须带有时间窗口内且未被使用过的 nonce
+func verifyPaddingHeaders(req *http.Request, opts *PaddingOptions, replay *replayCache) bool {
+	if opts.SelfDescribing {
+		for _, values := range req.Header {
+			for _, v := range values {
+				if !IsPaddingValue(v) {
+					continue
+				}
+				if len(opts.AuthKey) == 0 {
+					return true
+				}
+				if nonce, ok := verifyDescribedValue(req, v, opts); ok && (replay == nil || replay.accept(nonce, opts.now())) {
+					return true
+				}
+			}
+		}
+	}
+	for _, name := range opts.headerNames(opts.now()) {
+		value := req.Header.Get(name)
+		if value == "" {
+			continue
+		}
+		if len(opts.AuthKey) == 0 {
+			return true
+		}
+		if len(value) < padMACSize {
+			continue
+		}
+		body, sig := value[:len(value)-padMACSize], value[len(value)-padMACSize:]
+		if !hmac.Equal([]byte(sig), []byte(padMAC(opts.AuthKey, req, body))) {
+			continue
+		}
+		if replay == nil || replay.accept(body[:min(len(body), nonceSize)], opts.now()) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyOptions 配置 VerifyPaddingS
+type VerifyOptions struct {
+	// Padding 应与对端 ToukaPadding 使用的配置一致, 用于识别 padding 头部名称与 HMAC 密钥 (AuthKey)
+	Padding PaddingOptions
+	// FlagOnly 为 true 时不拒绝请求, 只在 Context 中以 VerifiedKey 记录校验结果
+	FlagOnly bool
+	// StatusCode 是拒绝请求时的响应状态码, 默认为 403
+	StatusCode int
+}
+
+// VerifyPaddingS 返回一个服务端中间件, 要求入站请求携带合法的 padding 头部
+// 在封闭的生态中, 设置 AuthKey 后它同时是一个轻量的客户端真实性信号; 校验结果总会以 VerifiedKey 记录在 Context 中
+// 同时设置 ReplayWindow 时还会拒绝重放的请求, 已使用的 nonce 记录在返回的中间件内, 多个实例之间不共享
+func VerifyPaddingS(opts VerifyOptions) touka.HandlerFunc {
+	padding := normalizeOptions(opts.Padding, "toukaPadding.Verify")
+	if opts.StatusCode == 0 {
+		opts.StatusCode = http.StatusForbidden
+	}
+	var replay *replayCache
+	if len(padding.AuthKey) > 0 && padding.ReplayWindow > 0 {
+		replay = newReplayCache(padding.ReplayWindow)
+	}
+	return func(c *touka.Context) {
+		ok := verifyPaddingHeaders(c.Request, &padding, replay)
+		c.Set(VerifiedKey, ok)
+		if !ok && !opts.FlagOnly {
+			c.AbortWithStatus(opts.StatusCode)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,252 @@
+package padding
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxControlPayload 是 WebSocket 控制帧负载的最大长度 (RFC 6455 5.5)
+const maxControlPayload = 125
+
+// WebSocketPaddingOptions 配置 WebSocket 连接上的 padding 帧注入
+// 注入的是未经请求的 Pong 帧 (RFC 6455 5.5.3), 对端会将其视为单向心跳而忽略, 不需要应答
+type WebSocketPaddingOptions struct {
+	MinInterval time.Duration // 两次注入之间的最小间隔, 小于等于 0 时为 10 秒
+	MaxInterval time.Duration // 两次注入之间的最大间隔, 小于等于 0 时为 60 秒
+	// Profile 决定每个 Pong 帧的负载长度, 上限为 125 字节; 为 nil 时为 16 到 125 字节
+	Profile *PaddingProfile
+	// Client 为 true 时按客户端角色发送带掩码的帧; 服务端发送的帧不得带掩码
+	Client bool
+}
+
+// normalizeWebSocketPadding 返回补全默认值后的 WebSocketPaddingOptions 副本
+func normalizeWebSocketPadding(w WebSocketPaddingOptions, logPrefix string) *WebSocketPaddingOptions {
+	if w.MinInterval <= 0 {
+		w.MinInterval = 10 * time.Second
+	}
+	if w.MaxInterval <= 0 {
+		w.MaxInterval = 60 * time.Second
+	}
+	if w.MinInterval > w.MaxInterval {
+		w.MinInterval = w.MaxInterval
+	}
+	if w.Profile == nil {
+		w.Profile = &PaddingProfile{MinLength: 16, MaxLength: maxControlPayload}
+	}
+	w.Profile = normalizeProfile(w.Profile, logPrefix)
+	w.Profile.MaxLength = min(w.Profile.MaxLength, maxControlPayload)
+	w.Profile.MinLength = min(w.Profile.MinLength, w.Profile.MaxLength)
+	return &w
+}
+
+// isWebSocketUpgrade 报告请求是否为 WebSocket 升级握手
+func isWebSocketUpgrade(req *http.Request) bool {
+	return strings.EqualFold(req.Header.Get("Upgrade"), "websocket")
+}
+
+// WebSocketConn 包装一个已完成 WebSocket 握手的连接, 在随机间隔向对端注入随机长度的 Pong 帧
+// 注入只发生在出站帧的边界上, 不会打断上层库正在写入的帧; 关闭返回的连接即停止注入
+func WebSocketConn(conn net.Conn, opts WebSocketPaddingOptions) net.Conn {
+	return newWSPaddingConn(conn, normalizeWebSocketPadding(opts, "padding.WebSocketConn"), defaultRandSource, false)
+}
+
+// newWSPaddingConn 创建包装器并启动注入任务, opts 需已完成校验
+// handshake 为 true 时连接上还将写出握手响应, 帧的跟踪 (以及注入) 从握手响应结束之后开始
+func newWSPaddingConn(conn net.Conn, opts *WebSocketPaddingOptions, src RandSource, handshake bool) *wsPaddingConn {
+	wc := &wsPaddingConn{
+		Conn:    conn,
+		opts:    opts,
+		src:     src,
+		tracker: wsFrameTracker{handshake: handshake},
+		stop:    make(chan struct{}),
+	}
+	go wc.run()
+	return wc
+}
+
+// wsPaddingConn 是 WebSocketConn 返回的连接包装器
+type wsPaddingConn struct {
+	net.Conn
+	opts *WebSocketPaddingOptions
+	src  RandSource
+
+	mu      sync.Mutex // 串行化上层写入与注入的 padding 帧
+	tracker wsFrameTracker
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// Write 写入上层数据并跟踪出站帧边界
+func (wc *wsPaddingConn) Write(p []byte) (int, error) {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	n, err := wc.Conn.Write(p)
+	wc.tracker.feed(p[:n])
+	return n, err
+}
+
+// Close 停止注入并关闭底层连接
+func (wc *wsPaddingConn) Close() error {
+	wc.closeOnce.Do(func() { close(wc.stop) })
+	return wc.Conn.Close()
+}
+
+// run 以随机间隔注入 padding 帧, 直到连接关闭或写入失败
+func (wc *wsPaddingConn) run() {
+	for {
+		interval, err := randInt(wc.src, int(wc.opts.MinInterval), int(wc.opts.MaxInterval))
+		if err != nil {
+			interval = int(wc.opts.MaxInterval)
+		}
+		timer := time.NewTimer(time.Duration(interval))
+		select {
+		case <-wc.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if err := wc.inject(); err != nil {
+			return
+		}
+	}
+}
+
+// inject 在出站帧边界处写入一个 padding Pong 帧; 不在边界时跳过本次注入
+func (wc *wsPaddingConn) inject() error {
+	length, err := wc.opts.Profile.sample(wc.src)
+	if err != nil {
+		return nil
+	}
+	frame := make([]byte, 0, 2+4+length)
+	frame = append(frame, 0x8A) // FIN + Pong
+	if wc.opts.Client {
+		frame = append(frame, 0x80|byte(length))
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return nil
+		}
+		frame = append(frame, key[:]...)
+		start := len(frame)
+		frame = append(frame, getPaddingSlice(wc.src, length)...)
+		for i := start; i < len(frame); i++ {
+			frame[i] ^= key[(i-start)%4]
+		}
+	} else {
+		frame = append(frame, byte(length))
+		frame = append(frame, getPaddingSlice(wc.src, length)...)
+	}
+
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	if !wc.tracker.atBoundary() {
+		return nil
+	}
+	_, err = wc.Conn.Write(frame)
+	return err
+}
+
+// handshakeEnd 是 HTTP 握手响应头部的结束标记
+const handshakeEnd = "\r\n\r\n"
+
+// wsFrameTracker 解析出站字节流中的 WebSocket 帧头, 用于判断当前是否处于帧边界
+type wsFrameTracker struct {
+	// handshake 为 true 时仍在跳过劫持后由上层库自行写出的 101 握手响应 (如 gorilla/websocket),
+	// 直到遇到结束头部的空行; matched 是已匹配的 handshakeEnd 前缀长度
+	handshake bool
+	matched   int
+
+	header    [14]byte // 当前正在累积的帧头 (最长 2 + 8 + 4 字节)
+	headerLen int
+	remaining uint64 // 当前帧尚未写出的负载字节数
+}
+
+// atBoundary 报告已写出的数据是否恰好结束于一个完整帧之后; 握手响应写完之前总是返回 false
+func (t *wsFrameTracker) atBoundary() bool {
+	return !t.handshake && t.headerLen == 0 && t.remaining == 0
+}
+
+// feed 消费一段已写出的数据, 更新帧解析状态
+func (t *wsFrameTracker) feed(p []byte) {
+	for len(p) > 0 {
+		if t.handshake {
+			switch {
+			case p[0] == handshakeEnd[t.matched]:
+				t.matched++
+			case p[0] == handshakeEnd[0]:
+				t.matched = 1
+			default:
+				t.matched = 0
+			}
+			p = p[1:]
+			if t.matched == len(handshakeEnd) {
+				t.handshake = false
+			}
+			continue
+		}
+		if t.remaining > 0 {
+			n := min(uint64(len(p)), t.remaining)
+			t.remaining -= n
+			p = p[n:]
+			continue
+		}
+		t.header[t.headerLen] = p[0]
+		t.headerLen++
+		p = p[1:]
+		if need := t.headerSize(); need > 0 && t.headerLen == need {
+			t.remaining = t.payloadLen()
+			t.headerLen = 0
+		}
+	}
+}
+
+// headerSize 返回当前帧头的完整长度, 前两个字节尚未到齐时返回 0
+func (t *wsFrameTracker) headerSize() int {
+	if t.headerLen < 2 {
+		return 0
+	}
+	size := 2
+	switch t.header[1] & 0x7F {
+	case 126:
+		size += 2
+	case 127:
+		size += 8
+	}
+	if t.header[1]&0x80 != 0 {
+		size += 4
+	}
+	return size
+}
+
+// payloadLen 从完整的帧头中解析负载长度
+func (t *wsFrameTracker) payloadLen() uint64 {
+	switch l := t.header[1] & 0x7F; l {
+	case 126:
+		return uint64(binary.BigEndian.Uint16(t.header[2:4]))
+	case 127:
+		return binary.BigEndian.Uint64(t.header[2:10])
+	default:
+		return uint64(l)
+	}
+}
+
+// Hijack 在启用 WebSocket padding 且请求为 WebSocket 升级时, 返回注入 padding 帧的连接
+func (prw *paddingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := prw.ResponseWriter.Hijack()
+	if err != nil || prw.opts.WebSocket == nil || prw.opts.DryRun || !isWebSocketUpgrade(prw.req) {
+		return conn, brw, err
+	}
+	// 处理函数先以 WriteHeader(101) 写出握手响应时, 劫持后的连接上只有帧; 否则上层库会在劫持后自行写出握手响应
+	wc := newWSPaddingConn(conn, prw.opts.WebSocket, prw.opts.Rand, !prw.switched)
+	// 劫持时写缓冲区为空, 重定向到包装后的连接, 使经由 brw 的写入同样被跟踪
+	if brw != nil {
+		brw.Writer.Reset(wc)
+	}
+	return wc, brw, nil
+}
@@ -0,0 +1,133 @@
+package padding
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/infinite-iroha/touka"
+)
+
+// wsFrame 按服务端角色 (不带掩码) 编码一个 FIN 文本帧
+func wsFrame(payload []byte) []byte {
+	frame := []byte{0x81}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	return append(frame, payload...)
+}
+
+// readWSFrame 读取一个不带掩码的帧, 返回其首字节与负载
+func readWSFrame(r io.Reader) (byte, []byte, error) {
+	var h [2]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return 0, nil, err
+	}
+	n := uint64(h[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	payload := make([]byte, n)
+	_, err := io.ReadFull(r, payload)
+	return h[0], payload, err
+}
+
+// TestWebSocketHandshakeThenFrames 模拟 gorilla/websocket 的做法: 劫持连接后经 brw 自行写出 101 握手响应,
+// 再逐段写出数据帧; 注入的 Pong 帧只能出现在帧边界上, 客户端应能完整解析出所有数据帧
+func TestWebSocketHandshakeThenFrames(t *testing.T) {
+	const frames = 30
+	opts := PaddingOptions{WebSocket: &WebSocketPaddingOptions{MinInterval: time.Millisecond, MaxInterval: 2 * time.Millisecond}}
+	r := touka.New()
+	r.Use(ToukaPaddingS(opts))
+	r.GET("/ws", func(c *touka.Context) {
+		conn, brw, err := c.Writer.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: s3pPLMBiTxaQ9kYGzzhZRbK+xOo=\r\n\r\n")
+		_ = brw.Flush()
+		time.Sleep(5 * time.Millisecond)
+		for i := range frames {
+			frame := wsFrame(bytes.Repeat([]byte{byte('a' + i%26)}, 50+i*10))
+			// 分两次写出同一个帧, 中间留出注入的机会
+			half := len(frame) / 2
+			_, _ = brw.Write(frame[:half])
+			_ = brw.Flush()
+			time.Sleep(3 * time.Millisecond)
+			_, _ = brw.Write(frame[half:])
+			_ = brw.Flush()
+			time.Sleep(3 * time.Millisecond)
+		}
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, _ = io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status %d, want 101", resp.StatusCode)
+	}
+
+	data, pongs := 0, 0
+	for data < frames {
+		first, payload, err := readWSFrame(br)
+		if err != nil {
+			t.Fatalf("after %d data frames: %v", data, err)
+		}
+		switch first {
+		case 0x81:
+			want := bytes.Repeat([]byte{byte('a' + data%26)}, 50+data*10)
+			if !bytes.Equal(payload, want) {
+				t.Fatalf("data frame %d corrupted", data)
+			}
+			data++
+		case 0x8A:
+			if len(payload) > maxControlPayload {
+				t.Fatalf("pong payload of %d bytes", len(payload))
+			}
+			pongs++
+		default:
+			t.Fatalf("unexpected frame byte %#x after %d data frames", first, data)
+		}
+	}
+	if pongs == 0 {
+		t.Errorf("no padding Pong frames were injected")
+	}
+}